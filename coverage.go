@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"practice/examples/coverage"
+)
+
+// runCoverage implements `practice coverage [flags] [packages]` and returns
+// the process exit code
+func runCoverage(args []string) int {
+	fs := flag.NewFlagSet("coverage", flag.ContinueOnError)
+	outDir := fs.String("out", "coverage", "Directory for the profiles and the HTML report")
+	configPath := fs.String("config", "coverage.json", "JSON file with the minimum coverage per package")
+	minimum := fs.Float64("min", -1, "Minimum coverage for packages not listed in the config (overrides its default)")
+	parallel := fs.Int("p", 0, "Number of packages to test at once (default GOMAXPROCS)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: practice coverage [flags] [packages]")
+		fmt.Fprintln(fs.Output(), "Runs the package tests with coverage, prints per-package and per-function")
+		fmt.Fprintln(fs.Output(), "coverage, writes an HTML report and fails below the configured minimum.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	thresholds, err := coverage.LoadThresholds(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading coverage thresholds: %v\n", err)
+		return 1
+	}
+	if *minimum >= 0 {
+		thresholds.Default = *minimum
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = coverage.Run(ctx, os.Stdout, coverage.Config{
+		Dir:        ".",
+		Patterns:   fs.Args(),
+		OutDir:     *outDir,
+		Thresholds: thresholds,
+		Parallel:   *parallel,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nCoverage check failed:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
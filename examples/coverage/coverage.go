@@ -0,0 +1,378 @@
+// Package coverage runs the module's package tests with coverage profiles,
+// merges the profiles, reports per-package and per-function coverage, renders
+// an HTML report and checks every package against a minimum.
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Common errors
+var (
+	ErrBelowThreshold = errors.New("coverage below threshold")
+	ErrTestsFailed    = errors.New("tests failed")
+	ErrInvalidProfile = errors.New("invalid coverage profile")
+)
+
+// Block is one line of a coverage profile: a span of statements and how
+// often it ran
+type Block struct {
+	File      string
+	StartLine int
+	StartCol  int
+	EndLine   int
+	EndCol    int
+	NumStmt   int
+	Count     int
+}
+
+func (b Block) key() string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d", b.File, b.StartLine, b.StartCol, b.EndLine, b.EndCol)
+}
+
+// Profile is a parsed coverage profile as written by go test -coverprofile
+type Profile struct {
+	Mode   string
+	Blocks []Block
+}
+
+// ParseProfile reads a coverage profile
+func ParseProfile(r io.Reader) (*Profile, error) {
+	p := &Profile{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if line == 1 {
+			mode, ok := strings.CutPrefix(text, "mode: ")
+			if !ok {
+				return nil, fmt.Errorf("%w: line 1: missing mode", ErrInvalidProfile)
+			}
+			p.Mode = mode
+			continue
+		}
+
+		i := strings.LastIndex(text, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("%w: line %d: %q", ErrInvalidProfile, line, text)
+		}
+		b := Block{File: text[:i]}
+		if _, err := fmt.Sscanf(text[i+1:], "%d.%d,%d.%d %d %d",
+			&b.StartLine, &b.StartCol, &b.EndLine, &b.EndCol, &b.NumStmt, &b.Count); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidProfile, line, err)
+		}
+		p.Blocks = append(p.Blocks, b)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if p.Mode == "" {
+		return nil, fmt.Errorf("%w: empty profile", ErrInvalidProfile)
+	}
+	return p, nil
+}
+
+// Merge combines profiles of the same mode. A block that appears in more
+// than one profile is counted once: in set mode it is covered if any
+// profile covered it, otherwise the counts are added.
+func Merge(profiles ...*Profile) (*Profile, error) {
+	merged := &Profile{}
+	index := make(map[string]int)
+	for _, p := range profiles {
+		if merged.Mode == "" {
+			merged.Mode = p.Mode
+		} else if p.Mode != merged.Mode {
+			return nil, fmt.Errorf("%w: cannot merge mode %q into %q", ErrInvalidProfile, p.Mode, merged.Mode)
+		}
+
+		for _, b := range p.Blocks {
+			i, seen := index[b.key()]
+			if !seen {
+				index[b.key()] = len(merged.Blocks)
+				merged.Blocks = append(merged.Blocks, b)
+				continue
+			}
+			if merged.Mode == "set" {
+				merged.Blocks[i].Count = max(merged.Blocks[i].Count, b.Count)
+			} else {
+				merged.Blocks[i].Count += b.Count
+			}
+		}
+	}
+
+	sort.SliceStable(merged.Blocks, func(i, j int) bool {
+		a, b := merged.Blocks[i], merged.Blocks[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return a.StartCol < b.StartCol
+	})
+	return merged, nil
+}
+
+// WriteTo writes the profile in the format go tool cover reads
+func (p *Profile) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "mode: %s\n", p.Mode)
+	for _, b := range p.Blocks {
+		fmt.Fprintf(&buf, "%s %d %d\n", b.key(), b.NumStmt, b.Count)
+	}
+	return buf.WriteTo(w)
+}
+
+// PackageCoverage is the statement coverage of one package
+type PackageCoverage struct {
+	Package    string
+	Statements int
+	Covered    int
+}
+
+// Percent returns the share of statements covered. A package without
+// statements counts as fully covered.
+func (c PackageCoverage) Percent() float64 {
+	if c.Statements == 0 {
+		return 100
+	}
+	return 100 * float64(c.Covered) / float64(c.Statements)
+}
+
+// Packages returns the coverage of every package in the profile, sorted by
+// import path
+func (p *Profile) Packages() []PackageCoverage {
+	byPkg := make(map[string]*PackageCoverage)
+	for _, b := range p.Blocks {
+		pkg := path.Dir(b.File)
+		c, ok := byPkg[pkg]
+		if !ok {
+			c = &PackageCoverage{Package: pkg}
+			byPkg[pkg] = c
+		}
+		c.Statements += b.NumStmt
+		if b.Count > 0 {
+			c.Covered += b.NumStmt
+		}
+	}
+
+	result := make([]PackageCoverage, 0, len(byPkg))
+	for _, c := range byPkg {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Package < result[j].Package
+	})
+	return result
+}
+
+// Thresholds is the minimum coverage, in percent, each package must reach
+type Thresholds struct {
+	// Default applies to packages not listed in Packages
+	Default  float64            `json:"default"`
+	Packages map[string]float64 `json:"packages"`
+}
+
+// LoadThresholds reads thresholds from a JSON file. A missing file means
+// no minimum at all.
+func LoadThresholds(path string) (Thresholds, error) {
+	var t Thresholds
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return t, fmt.Errorf("failed to read thresholds: %w", err)
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("failed to parse thresholds %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// For returns the minimum coverage of pkg
+func (t Thresholds) For(pkg string) float64 {
+	if minimum, ok := t.Packages[pkg]; ok {
+		return minimum
+	}
+	return t.Default
+}
+
+// Check returns an error wrapping ErrBelowThreshold for every package that
+// does not reach its minimum
+func Check(pkgs []PackageCoverage, t Thresholds) error {
+	var errs []error
+	for _, c := range pkgs {
+		if minimum := t.For(c.Package); c.Percent() < minimum {
+			errs = append(errs, fmt.Errorf("%w: %s has %.1f%%, needs %.1f%%", ErrBelowThreshold, c.Package, c.Percent(), minimum))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Config controls a coverage run
+type Config struct {
+	// Dir is the module directory the go commands run in
+	Dir string
+
+	// Patterns selects the packages to test; default ./...
+	Patterns []string
+
+	// OutDir receives the per-package profiles, the merged profile
+	// coverage.out and the HTML report index.html
+	OutDir string
+
+	Thresholds Thresholds
+
+	// Parallel is how many packages are tested at once; default GOMAXPROCS
+	Parallel int
+}
+
+// Run tests every package with a coverage profile, merges the profiles
+// into cfg.OutDir, prints per-package and per-function coverage to w,
+// renders the HTML report and finally checks the thresholds
+func Run(ctx context.Context, w io.Writer, cfg Config) error {
+	if len(cfg.Patterns) == 0 {
+		cfg.Patterns = []string{"./..."}
+	}
+	if cfg.Parallel <= 0 {
+		cfg.Parallel = runtime.GOMAXPROCS(0)
+	}
+	outDir, err := filepath.Abs(cfg.OutDir)
+	if err != nil {
+		return err
+	}
+	profileDir := filepath.Join(outDir, "profiles")
+	if err := os.MkdirAll(profileDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	listed, err := goCmd(ctx, cfg.Dir, append([]string{"list"}, cfg.Patterns...)...)
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+	pkgs := strings.Fields(listed)
+
+	// Every test binary instruments all selected packages, so code the
+	// golden tests in demo run through counts for the example it belongs to
+	profiles, err := testPackages(ctx, cfg.Dir, profileDir, pkgs, cfg.Parallel)
+	if err != nil {
+		return err
+	}
+	merged, err := Merge(profiles...)
+	if err != nil {
+		return err
+	}
+
+	mergedPath := filepath.Join(outDir, "coverage.out")
+	f, err := os.Create(mergedPath)
+	if err != nil {
+		return fmt.Errorf("failed to write merged profile: %w", err)
+	}
+	if _, err := merged.WriteTo(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write merged profile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write merged profile: %w", err)
+	}
+
+	packages := merged.Packages()
+	fmt.Fprintln(w, "Package coverage:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range packages {
+		status := "ok"
+		if c.Percent() < cfg.Thresholds.For(c.Package) {
+			status = "FAIL"
+		}
+		fmt.Fprintf(tw, "  %s\t%.1f%%\t(min %.1f%%)\t%s\n", c.Package, c.Percent(), cfg.Thresholds.For(c.Package), status)
+	}
+	tw.Flush()
+
+	funcs, err := goCmd(ctx, cfg.Dir, "tool", "cover", "-func="+mergedPath)
+	if err != nil {
+		return fmt.Errorf("failed to report function coverage: %w", err)
+	}
+	fmt.Fprintln(w, "\nFunction coverage:")
+	fmt.Fprint(w, funcs)
+
+	htmlPath := filepath.Join(outDir, "index.html")
+	if _, err := goCmd(ctx, cfg.Dir, "tool", "cover", "-html="+mergedPath, "-o", htmlPath); err != nil {
+		return fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	fmt.Fprintf(w, "\nHTML report: %s\n", htmlPath)
+
+	return Check(packages, cfg.Thresholds)
+}
+
+// testPackages runs go test with a coverage profile for each package, at
+// most parallel at a time, and returns the profiles in package order. Each
+// profile covers all of pkgs, not only the package under test.
+func testPackages(ctx context.Context, dir, profileDir string, pkgs []string, parallel int) ([]*Profile, error) {
+	coverPkg := strings.Join(pkgs, ",")
+	profiles := make([]*Profile, len(pkgs))
+	errs := make([]error, len(pkgs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, pkg := range pkgs {
+		wg.Add(1)
+		go func(i int, pkg string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			profilePath := filepath.Join(profileDir, strings.ReplaceAll(pkg, "/", "_")+".out")
+			if _, err := goCmd(ctx, dir, "test", "-covermode=atomic", "-coverpkg="+coverPkg, "-coverprofile="+profilePath, pkg); err != nil {
+				errs[i] = fmt.Errorf("%w: %s: %v", ErrTestsFailed, pkg, err)
+				return
+			}
+
+			f, err := os.Open(profilePath)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to read profile of %s: %w", pkg, err)
+				return
+			}
+			defer f.Close()
+			profiles[i], errs[i] = ParseProfile(f)
+		}(i, pkg)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// goCmd runs the go command in dir and returns its standard output.
+// Standard error, where the go command writes warnings, is only reported
+// when the command fails.
+func goCmd(ctx context.Context, dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("go %s: %w\n%s%s", args[0], err, stdout.Bytes(), stderr.Bytes())
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,184 @@
+package coverage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profileA = `mode: atomic
+practice/a/a.go:3.20,5.2 2 1
+practice/a/a.go:7.20,9.2 2 0
+practice/b/b.go:3.20,4.2 1 0
+`
+
+const profileB = `mode: atomic
+practice/b/b.go:3.20,4.2 1 3
+practice/a/a.go:3.20,5.2 2 2
+`
+
+func mustParse(t *testing.T, s string) *Profile {
+	t.Helper()
+	p, err := ParseProfile(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("ParseProfile: %v", err)
+	}
+	return p
+}
+
+func TestParseProfile(t *testing.T) {
+	p := mustParse(t, profileA)
+	if p.Mode != "atomic" {
+		t.Errorf("Mode = %q, want atomic", p.Mode)
+	}
+	want := Block{File: "practice/a/a.go", StartLine: 7, StartCol: 20, EndLine: 9, EndCol: 2, NumStmt: 2}
+	if len(p.Blocks) != 3 || p.Blocks[1] != want {
+		t.Errorf("Blocks = %+v, want second block %+v", p.Blocks, want)
+	}
+
+	for _, bad := range []string{"", "practice/a/a.go:3.20,5.2 2 1\n", "mode: set\nnot a block\n"} {
+		if _, err := ParseProfile(strings.NewReader(bad)); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("ParseProfile(%q) error = %v, want ErrInvalidProfile", bad, err)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	merged, err := Merge(mustParse(t, profileA), mustParse(t, profileB))
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := merged.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	want := `mode: atomic
+practice/a/a.go:3.20,5.2 2 3
+practice/a/a.go:7.20,9.2 2 0
+practice/b/b.go:3.20,4.2 1 3
+`
+	if buf.String() != want {
+		t.Errorf("merged profile:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	// Round trip through the parser
+	if again := mustParse(t, buf.String()); len(again.Blocks) != 3 {
+		t.Errorf("reparsed %d blocks, want 3", len(again.Blocks))
+	}
+}
+
+func TestMergeSetMode(t *testing.T) {
+	a := mustParse(t, "mode: set\np/x.go:1.1,2.2 1 1\n")
+	b := mustParse(t, "mode: set\np/x.go:1.1,2.2 1 1\n")
+	merged, err := Merge(a, b)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if got := merged.Blocks[0].Count; got != 1 {
+		t.Errorf("set mode count = %d, want 1", got)
+	}
+
+	if _, err := Merge(a, mustParse(t, profileA)); !errors.Is(err, ErrInvalidProfile) {
+		t.Errorf("Merge of mixed modes error = %v, want ErrInvalidProfile", err)
+	}
+}
+
+func TestPackagesAndCheck(t *testing.T) {
+	merged, err := Merge(mustParse(t, profileA))
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	pkgs := merged.Packages()
+	want := []PackageCoverage{
+		{Package: "practice/a", Statements: 4, Covered: 2},
+		{Package: "practice/b", Statements: 1, Covered: 0},
+	}
+	if len(pkgs) != len(want) || pkgs[0] != want[0] || pkgs[1] != want[1] {
+		t.Fatalf("Packages() = %+v, want %+v", pkgs, want)
+	}
+	if got := pkgs[0].Percent(); got != 50 {
+		t.Errorf("Percent() = %v, want 50", got)
+	}
+	if got := (PackageCoverage{}).Percent(); got != 100 {
+		t.Errorf("Percent() of empty package = %v, want 100", got)
+	}
+
+	tests := []struct {
+		name       string
+		thresholds Thresholds
+		failing    []string
+	}{
+		{"no minimum", Thresholds{}, nil},
+		{"default", Thresholds{Default: 40}, []string{"practice/b"}},
+		{"per package", Thresholds{Default: 40, Packages: map[string]float64{"practice/a": 60, "practice/b": 0}}, []string{"practice/a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(pkgs, tt.thresholds)
+			if len(tt.failing) == 0 {
+				if err != nil {
+					t.Fatalf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrBelowThreshold) {
+				t.Fatalf("Check() = %v, want ErrBelowThreshold", err)
+			}
+			for _, pkg := range tt.failing {
+				if !strings.Contains(err.Error(), pkg+" has") {
+					t.Errorf("Check() = %v, want %s reported", err, pkg)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadThresholds(t *testing.T) {
+	dir := t.TempDir()
+
+	missing, err := LoadThresholds(filepath.Join(dir, "missing.json"))
+	if err != nil || missing.Default != 0 || missing.Packages != nil {
+		t.Errorf("LoadThresholds(missing) = %+v, %v; want zero thresholds", missing, err)
+	}
+
+	path := filepath.Join(dir, "coverage.json")
+	if err := os.WriteFile(path, []byte(`{"default": 10, "packages": {"practice/a": 75}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadThresholds(path)
+	if err != nil {
+		t.Fatalf("LoadThresholds: %v", err)
+	}
+	if got.For("practice/a") != 75 || got.For("practice/b") != 10 {
+		t.Errorf("thresholds = %+v", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadThresholds(path); err == nil {
+		t.Error("LoadThresholds of malformed JSON succeeded")
+	}
+}
+
+func TestGoCmdIgnoresWarnings(t *testing.T) {
+	// go list warns on stderr that ./... matched nothing and still succeeds
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module empty\n\ngo 1.23\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := goCmd(context.Background(), dir, "list", "./...")
+	if err != nil || out != "" {
+		t.Errorf("goCmd(list) = %q, %v; want no packages and no error", out, err)
+	}
+
+	_, err = goCmd(context.Background(), dir, "list", "./missing")
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("goCmd(list ./missing) error = %v, want it to include the go command's message", err)
+	}
+}
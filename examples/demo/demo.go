@@ -0,0 +1,137 @@
+// Package demo is the environment the examples' Run functions work in:
+// where output goes, what time it is, where new IDs come from and how long
+// a simulated step takes. Tests swap in a deterministic environment so the
+// printed output can be compared against golden files.
+package demo
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Epoch is the first reading of the deterministic clock
+var Epoch = time.Date(2024, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+// Env is what a Run function needs from the outside world. Writing to an
+// Env writes to Out.
+type Env struct {
+	// Out is safe for concurrent use
+	Out   io.Writer
+	Now   func() time.Time
+	NewID func() string
+	Sleep func(time.Duration)
+
+	// Listen opens the listeners of servers a Run function starts
+	Listen func(network, address string) (net.Listener, error)
+
+	// Deterministic is set by the Deterministic option, for the rare
+	// output that cannot be made stable through the fields above
+	Deterministic bool
+}
+
+// Option configures an Env
+type Option func(*Env)
+
+// WithClock replaces time.Now
+func WithClock(now func() time.Time) Option {
+	return func(e *Env) {
+		e.Now = now
+	}
+}
+
+// WithIDs replaces the random UUID generator
+func WithIDs(newID func() string) Option {
+	return func(e *Env) {
+		e.NewID = newID
+	}
+}
+
+// WithListen replaces net.Listen, e.g. to keep a demo server off the
+// fixed port it normally uses
+func WithListen(listen func(network, address string) (net.Listener, error)) Option {
+	return func(e *Env) {
+		e.Listen = listen
+	}
+}
+
+// Deterministic makes every run print the same output: the clock starts
+// at Epoch and advances a millisecond per reading, IDs are sequential and
+// simulated work does not sleep.
+func Deterministic() Option {
+	return func(e *Env) {
+		e.Now = StepClock(Epoch, time.Millisecond)
+		e.NewID = SequentialIDs()
+		e.Sleep = func(time.Duration) {}
+		e.Deterministic = true
+	}
+}
+
+// New creates an Env writing to w, using the real clock and random UUIDs
+// unless opts say otherwise
+func New(w io.Writer, opts ...Option) *Env {
+	e := &Env{
+		Out:    &syncWriter{w: w},
+		Now:    time.Now,
+		NewID:  uuid.NewString,
+		Sleep:  time.Sleep,
+		Listen: net.Listen,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Write writes to Out
+func (e *Env) Write(p []byte) (int, error) {
+	return e.Out.Write(p)
+}
+
+// Since is time.Since on the environment's clock
+func (e *Env) Since(start time.Time) time.Duration {
+	return e.Now().Sub(start)
+}
+
+// StepClock returns a clock that reads start, then advances by step on
+// every reading. It is safe for concurrent use.
+func StepClock(start time.Time, step time.Duration) func() time.Time {
+	var mu sync.Mutex
+	next := start
+	return func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now := next
+		next = next.Add(step)
+		return now
+	}
+}
+
+// SequentialIDs returns a generator of UUID-shaped IDs counting up from
+// 00000000-0000-0000-0000-000000000001. It is safe for concurrent use.
+func SequentialIDs() func() string {
+	var mu sync.Mutex
+	var n uint64
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return fmt.Sprintf("00000000-0000-0000-0000-%012x", n)
+	}
+}
+
+// syncWriter serializes writes so goroutines can share an output
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,135 @@
+package demo_test
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"practice/examples/demo"
+	"practice/examples/example1"
+	"practice/examples/example10"
+	"practice/examples/example2"
+	"practice/examples/example3"
+	"practice/examples/example4"
+	"practice/examples/example5"
+	"practice/examples/example6"
+	"practice/examples/example7"
+	"practice/examples/example8"
+	"practice/examples/example9"
+	"practice/examples/tabletest"
+	"practice/examples/tabletest/tabletesttest"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// example is a Run function and how to make its output comparable
+type example struct {
+	run func(w io.Writer, opts ...demo.Option) error
+
+	// normalize rewrites output that stays nondeterministic even with a
+	// fixed clock, such as the interleaving of goroutines
+	normalize func(string) string
+}
+
+var workerID = regexp.MustCompile(`Worker \d+`)
+
+// unordered sorts the lines of output produced by concurrent goroutines
+// and hides which worker picked up which job
+func unordered(out string) string {
+	lines := strings.Split(strings.TrimSuffix(workerID.ReplaceAllString(out, "Worker N"), "\n"), "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func goldenTable(cases []tabletest.Case[example, string]) tabletest.Table[example, string] {
+	return tabletest.Table[example, string]{
+		Name:  "examples",
+		Cases: cases,
+		Run: func(ex example) (string, error) {
+			var buf bytes.Buffer
+			if err := ex.run(&buf, demo.Deterministic(), demo.WithListen(loopback)); err != nil {
+				return buf.String(), err
+			}
+			if ex.normalize != nil {
+				return ex.normalize(buf.String()), nil
+			}
+			return buf.String(), nil
+		},
+		Golden: func(got string, err error) []byte {
+			return []byte(got)
+		},
+		GoldenDir:    "testdata",
+		UpdateGolden: *update,
+	}
+}
+
+// loopback listens on a free port of the loopback interface instead of
+// the fixed port a demo server asks for
+func loopback(network, _ string) (net.Listener, error) {
+	return net.Listen(network, "127.0.0.1:0")
+}
+
+// TestGolden runs every example that needs nothing from outside the
+// process in deterministic mode and compares its output with
+// testdata/<example>.golden. Run with -update to accept intended changes.
+func TestGolden(t *testing.T) {
+	tabletesttest.Run(t, goldenTable([]tabletest.Case[example, string]{
+		{Name: "example1", Input: example{run: example1.Run}},
+		{Name: "example2", Input: example{run: example2.Run, normalize: unordered}},
+		{Name: "example2_pipeline", Input: example{run: example2.RunPipeline, normalize: unordered}},
+		{Name: "example3", Input: example{run: example3.Run}},
+		{Name: "example3_wrapping", Input: example{run: example3.RunErrorWrapping}},
+		{Name: "example3_concurrency", Input: example{run: example3.RunConcurrency}},
+		{Name: "example3_resilience", Input: example{run: example3.RunResilience}},
+		{Name: "example3_panic", Input: example{run: example3.RunPanicRecovery}},
+		{Name: "example4", Input: example{run: example4.Run}},
+		{Name: "example4_integration", Input: example{run: example4.RunIntegration}},
+		{Name: "example5", Input: example{run: example5.Run}},
+		{Name: "example5_integration", Input: example{run: example5.RunIntegration}},
+		{Name: "example5_sqlite", Input: example{run: example5.RunPersistence}},
+		{Name: "example6", Input: example{run: example6.Run}},
+		{Name: "example8", Input: example{run: example8.Run}},
+		{Name: "example10", Input: example{run: example10.Run}},
+	}))
+}
+
+// TestGoldenGoCommand covers the examples that run the go command: example7
+// fetches modules, so it needs the network or a warm module cache and its
+// output follows the module versions, and example9 builds a binary. -short
+// skips them.
+func TestGoldenGoCommand(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	tabletesttest.Run(t, goldenTable([]tabletest.Case[example, string]{
+		{Name: "example7", Input: example{run: example7.Run}},
+		{Name: "example9", Input: example{run: example9.Run}},
+	}))
+}
+
+func TestDeterministicRunsMatch(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := example5.RunIntegration(&first, demo.Deterministic()); err != nil {
+		t.Fatal(err)
+	}
+	if err := example5.RunIntegration(&second, demo.Deterministic()); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("two deterministic runs differ:\n%s\n---\n%s", first.String(), second.String())
+	}
+}
+
+func TestSequentialIDs(t *testing.T) {
+	next := demo.SequentialIDs()
+	for _, want := range []string{"00000000-0000-0000-0000-000000000001", "00000000-0000-0000-0000-000000000002"} {
+		if got := next(); got != want {
+			t.Errorf("SequentialIDs() = %q, want %q", got, want)
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:generate go run practice/examples/mock/mockgen -out mocks/mocks.go
+
 package example1
 
 import (
@@ -6,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"practice/examples/demo"
 )
 
 // Bad interface design (what AI might suggest)
@@ -52,13 +56,29 @@ func (v *JSONValidator) Validate(data []byte) error {
 type DataProcessorImpl struct {
 	validator Validator
 	storage   Storage
+	now       func() time.Time
+}
+
+// Option configures a DataProcessorImpl
+type Option func(*DataProcessorImpl)
+
+// WithClock replaces time.Now for processing timestamps
+func WithClock(now func() time.Time) Option {
+	return func(p *DataProcessorImpl) {
+		p.now = now
+	}
 }
 
-func NewDataProcessor(validator Validator, storage Storage) *DataProcessorImpl {
-	return &DataProcessorImpl{
+func NewDataProcessor(validator Validator, storage Storage, opts ...Option) *DataProcessorImpl {
+	p := &DataProcessorImpl{
 		validator: validator,
 		storage:   storage,
+		now:       time.Now,
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *DataProcessorImpl) Process(data []byte) ([]byte, error) {
@@ -72,7 +92,7 @@ func (p *DataProcessorImpl) Process(data []byte) ([]byte, error) {
 		Timestamp time.Time       `json:"timestamp"`
 	}{
 		Data:      data,
-		Timestamp: time.Now(),
+		Timestamp: p.now(),
 	}
 
 	return json.Marshal(processed)
@@ -105,11 +125,13 @@ func (s *InMemoryStorage) Retrieve(id string) ([]byte, error) {
 }
 
 // Run executes the example
-func Run() error {
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+
 	// Create components
 	validator := &JSONValidator{}
 	storage := &InMemoryStorage{}
-	processor := NewDataProcessor(validator, storage)
+	processor := NewDataProcessor(validator, storage, WithClock(env.Now))
 
 	// Example usage
 	data := []byte(`{"name": "test", "value": 123}`)
@@ -120,6 +142,6 @@ func Run() error {
 		return fmt.Errorf("failed to process data: %w", err)
 	}
 
-	fmt.Printf("Processed data: %s\n", processed)
+	fmt.Fprintf(env, "Processed data: %s\n", processed)
 	return nil
 }
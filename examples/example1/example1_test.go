@@ -0,0 +1,72 @@
+package example1_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"practice/examples/example1"
+	"practice/examples/example1/mocks"
+	"practice/examples/mock"
+)
+
+func TestDataProcessorValidatesFirst(t *testing.T) {
+	validator := mocks.NewValidator(t)
+	storage := mocks.NewStorage(t)
+
+	data := []byte(`{"name":"test"}`)
+	validator.ExpectValidate(data).Return(nil)
+
+	processed, err := example1.NewDataProcessor(validator, storage).Process(data)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var out struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(processed, &out); err != nil {
+		t.Fatalf("Process() returned invalid JSON: %v", err)
+	}
+	if string(out.Data) != string(data) {
+		t.Errorf("Process() data = %s, want %s", out.Data, data)
+	}
+}
+
+func TestDataProcessorRejectsInvalidData(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	validator := mocks.NewValidator(t)
+	validator.ExpectValidate(mock.Any()).Return(errInvalid)
+
+	// A strict storage fails the test if the processor touches it
+	storage := mocks.NewStorage(t)
+
+	_, err := example1.NewDataProcessor(validator, storage).Process([]byte("nope"))
+	if !errors.Is(err, errInvalid) {
+		t.Errorf("Process() error = %v, want %v", err, errInvalid)
+	}
+}
+
+func TestDataProcessorWithFakeValidator(t *testing.T) {
+	// A fake and a spy: the zero value delegates to ValidateFunc and
+	// records every call
+	validator := &mocks.Validator{
+		ValidateFunc: (&example1.JSONValidator{}).Validate,
+	}
+	processor := example1.NewDataProcessor(validator, &mocks.Storage{})
+
+	inputs := [][]byte{[]byte(`{}`), []byte(`{`), []byte(`[1]`)}
+	for _, input := range inputs {
+		processor.Process(input)
+	}
+
+	calls := validator.ValidateCalls()
+	if len(calls) != len(inputs) {
+		t.Fatalf("Validate called %d times, want %d", len(calls), len(inputs))
+	}
+	for i, call := range calls {
+		if string(call.Data) != string(inputs[i]) {
+			t.Errorf("Validate call %d data = %s, want %s", i, call.Data, inputs[i])
+		}
+	}
+}
@@ -0,0 +1,1242 @@
+// Code generated by mockgen from practice/examples/example1. DO NOT EDIT.
+
+// Package mocks holds test doubles for the interfaces of practice/examples/example1.
+package mocks
+
+import (
+	"context"
+	"io"
+
+	"practice/examples/example1"
+	"practice/examples/mock"
+)
+
+// DataProcessor is a test double for example1.DataProcessor
+type DataProcessor struct {
+	recorder mock.Recorder
+
+	// DeleteFunc, if set, handles Delete calls that match no expectation
+	DeleteFunc func(id string) error
+
+	// ProcessFunc, if set, handles Process calls that match no expectation
+	ProcessFunc func(data []byte) ([]byte, error)
+
+	// RetrieveFunc, if set, handles Retrieve calls that match no expectation
+	RetrieveFunc func(id string) ([]byte, error)
+
+	// StoreFunc, if set, handles Store calls that match no expectation
+	StoreFunc func(data []byte) error
+
+	// UpdateFunc, if set, handles Update calls that match no expectation
+	UpdateFunc func(id string, data []byte) error
+
+	// ValidateFunc, if set, handles Validate calls that match no expectation
+	ValidateFunc func(data []byte) error
+}
+
+var _ example1.DataProcessor = (*DataProcessor)(nil)
+
+// NewDataProcessor returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewDataProcessor(t mock.TB) *DataProcessor {
+	m := &DataProcessor{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *DataProcessor) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Delete implements example1.DataProcessor
+func (m *DataProcessor) Delete(id string) error {
+	if results, ok := m.recorder.Called("Delete", id); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	m.recorder.Unexpected("Delete", id)
+	var r0 error
+	return r0
+}
+
+// DataProcessorDeleteCall is an expectation on DataProcessor.Delete
+type DataProcessorDeleteCall struct {
+	e *mock.Expectation
+}
+
+// ExpectDelete expects a call to Delete whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectDelete(id interface{}) DataProcessorDeleteCall {
+	return DataProcessorDeleteCall{m.recorder.Expect("Delete", 1, id)}
+}
+
+// Return sets the values Delete returns
+func (c DataProcessorDeleteCall) Return(r0 error) DataProcessorDeleteCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Delete returns from its arguments
+func (c DataProcessorDeleteCall) Do(fn func(id string) error) DataProcessorDeleteCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(string)
+		r0 := fn(id)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorDeleteCall) Times(n int) DataProcessorDeleteCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorDeleteCall) AnyTimes() DataProcessorDeleteCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorDeleteArgs holds the arguments of one Delete call
+type DataProcessorDeleteArgs struct {
+	ID string
+}
+
+// DeleteCalls returns the arguments of every Delete call in order
+func (m *DataProcessor) DeleteCalls() []DataProcessorDeleteArgs {
+	var out []DataProcessorDeleteArgs
+	for _, call := range m.recorder.Calls("Delete") {
+		var args DataProcessorDeleteArgs
+		args.ID, _ = call.Args[0].(string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Process implements example1.DataProcessor
+func (m *DataProcessor) Process(data []byte) ([]byte, error) {
+	if results, ok := m.recorder.Called("Process", data); ok {
+		r0, _ := results[0].([]byte)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ProcessFunc != nil {
+		return m.ProcessFunc(data)
+	}
+	m.recorder.Unexpected("Process", data)
+	var r0 []byte
+	var r1 error
+	return r0, r1
+}
+
+// DataProcessorProcessCall is an expectation on DataProcessor.Process
+type DataProcessorProcessCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcess expects a call to Process whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectProcess(data interface{}) DataProcessorProcessCall {
+	return DataProcessorProcessCall{m.recorder.Expect("Process", 2, data)}
+}
+
+// Return sets the values Process returns
+func (c DataProcessorProcessCall) Return(r0 []byte, r1 error) DataProcessorProcessCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Process returns from its arguments
+func (c DataProcessorProcessCall) Do(fn func(data []byte) ([]byte, error)) DataProcessorProcessCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0, r1 := fn(data)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorProcessCall) Times(n int) DataProcessorProcessCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorProcessCall) AnyTimes() DataProcessorProcessCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorProcessArgs holds the arguments of one Process call
+type DataProcessorProcessArgs struct {
+	Data []byte
+}
+
+// ProcessCalls returns the arguments of every Process call in order
+func (m *DataProcessor) ProcessCalls() []DataProcessorProcessArgs {
+	var out []DataProcessorProcessArgs
+	for _, call := range m.recorder.Calls("Process") {
+		var args DataProcessorProcessArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Retrieve implements example1.DataProcessor
+func (m *DataProcessor) Retrieve(id string) ([]byte, error) {
+	if results, ok := m.recorder.Called("Retrieve", id); ok {
+		r0, _ := results[0].([]byte)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.RetrieveFunc != nil {
+		return m.RetrieveFunc(id)
+	}
+	m.recorder.Unexpected("Retrieve", id)
+	var r0 []byte
+	var r1 error
+	return r0, r1
+}
+
+// DataProcessorRetrieveCall is an expectation on DataProcessor.Retrieve
+type DataProcessorRetrieveCall struct {
+	e *mock.Expectation
+}
+
+// ExpectRetrieve expects a call to Retrieve whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectRetrieve(id interface{}) DataProcessorRetrieveCall {
+	return DataProcessorRetrieveCall{m.recorder.Expect("Retrieve", 2, id)}
+}
+
+// Return sets the values Retrieve returns
+func (c DataProcessorRetrieveCall) Return(r0 []byte, r1 error) DataProcessorRetrieveCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Retrieve returns from its arguments
+func (c DataProcessorRetrieveCall) Do(fn func(id string) ([]byte, error)) DataProcessorRetrieveCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(string)
+		r0, r1 := fn(id)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorRetrieveCall) Times(n int) DataProcessorRetrieveCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorRetrieveCall) AnyTimes() DataProcessorRetrieveCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorRetrieveArgs holds the arguments of one Retrieve call
+type DataProcessorRetrieveArgs struct {
+	ID string
+}
+
+// RetrieveCalls returns the arguments of every Retrieve call in order
+func (m *DataProcessor) RetrieveCalls() []DataProcessorRetrieveArgs {
+	var out []DataProcessorRetrieveArgs
+	for _, call := range m.recorder.Calls("Retrieve") {
+		var args DataProcessorRetrieveArgs
+		args.ID, _ = call.Args[0].(string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Store implements example1.DataProcessor
+func (m *DataProcessor) Store(data []byte) error {
+	if results, ok := m.recorder.Called("Store", data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.StoreFunc != nil {
+		return m.StoreFunc(data)
+	}
+	m.recorder.Unexpected("Store", data)
+	var r0 error
+	return r0
+}
+
+// DataProcessorStoreCall is an expectation on DataProcessor.Store
+type DataProcessorStoreCall struct {
+	e *mock.Expectation
+}
+
+// ExpectStore expects a call to Store whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectStore(data interface{}) DataProcessorStoreCall {
+	return DataProcessorStoreCall{m.recorder.Expect("Store", 1, data)}
+}
+
+// Return sets the values Store returns
+func (c DataProcessorStoreCall) Return(r0 error) DataProcessorStoreCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Store returns from its arguments
+func (c DataProcessorStoreCall) Do(fn func(data []byte) error) DataProcessorStoreCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0 := fn(data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorStoreCall) Times(n int) DataProcessorStoreCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorStoreCall) AnyTimes() DataProcessorStoreCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorStoreArgs holds the arguments of one Store call
+type DataProcessorStoreArgs struct {
+	Data []byte
+}
+
+// StoreCalls returns the arguments of every Store call in order
+func (m *DataProcessor) StoreCalls() []DataProcessorStoreArgs {
+	var out []DataProcessorStoreArgs
+	for _, call := range m.recorder.Calls("Store") {
+		var args DataProcessorStoreArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Update implements example1.DataProcessor
+func (m *DataProcessor) Update(id string, data []byte) error {
+	if results, ok := m.recorder.Called("Update", id, data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(id, data)
+	}
+	m.recorder.Unexpected("Update", id, data)
+	var r0 error
+	return r0
+}
+
+// DataProcessorUpdateCall is an expectation on DataProcessor.Update
+type DataProcessorUpdateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectUpdate expects a call to Update whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectUpdate(id, data interface{}) DataProcessorUpdateCall {
+	return DataProcessorUpdateCall{m.recorder.Expect("Update", 1, id, data)}
+}
+
+// Return sets the values Update returns
+func (c DataProcessorUpdateCall) Return(r0 error) DataProcessorUpdateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Update returns from its arguments
+func (c DataProcessorUpdateCall) Do(fn func(id string, data []byte) error) DataProcessorUpdateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(string)
+		data, _ := args[1].([]byte)
+		r0 := fn(id, data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorUpdateCall) Times(n int) DataProcessorUpdateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorUpdateCall) AnyTimes() DataProcessorUpdateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorUpdateArgs holds the arguments of one Update call
+type DataProcessorUpdateArgs struct {
+	ID   string
+	Data []byte
+}
+
+// UpdateCalls returns the arguments of every Update call in order
+func (m *DataProcessor) UpdateCalls() []DataProcessorUpdateArgs {
+	var out []DataProcessorUpdateArgs
+	for _, call := range m.recorder.Calls("Update") {
+		var args DataProcessorUpdateArgs
+		args.ID, _ = call.Args[0].(string)
+		args.Data, _ = call.Args[1].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Validate implements example1.DataProcessor
+func (m *DataProcessor) Validate(data []byte) error {
+	if results, ok := m.recorder.Called("Validate", data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.ValidateFunc != nil {
+		return m.ValidateFunc(data)
+	}
+	m.recorder.Unexpected("Validate", data)
+	var r0 error
+	return r0
+}
+
+// DataProcessorValidateCall is an expectation on DataProcessor.Validate
+type DataProcessorValidateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectValidate expects a call to Validate whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectValidate(data interface{}) DataProcessorValidateCall {
+	return DataProcessorValidateCall{m.recorder.Expect("Validate", 1, data)}
+}
+
+// Return sets the values Validate returns
+func (c DataProcessorValidateCall) Return(r0 error) DataProcessorValidateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Validate returns from its arguments
+func (c DataProcessorValidateCall) Do(fn func(data []byte) error) DataProcessorValidateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0 := fn(data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorValidateCall) Times(n int) DataProcessorValidateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorValidateCall) AnyTimes() DataProcessorValidateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorValidateArgs holds the arguments of one Validate call
+type DataProcessorValidateArgs struct {
+	Data []byte
+}
+
+// ValidateCalls returns the arguments of every Validate call in order
+func (m *DataProcessor) ValidateCalls() []DataProcessorValidateArgs {
+	var out []DataProcessorValidateArgs
+	for _, call := range m.recorder.Calls("Validate") {
+		var args DataProcessorValidateArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// DataService is a test double for example1.DataService
+type DataService struct {
+	recorder mock.Recorder
+
+	// ProcessFunc, if set, handles Process calls that match no expectation
+	ProcessFunc func(data []byte) ([]byte, error)
+
+	// RetrieveFunc, if set, handles Retrieve calls that match no expectation
+	RetrieveFunc func(id string) ([]byte, error)
+
+	// StoreFunc, if set, handles Store calls that match no expectation
+	StoreFunc func(data []byte) error
+
+	// ValidateFunc, if set, handles Validate calls that match no expectation
+	ValidateFunc func(data []byte) error
+}
+
+var _ example1.DataService = (*DataService)(nil)
+
+// NewDataService returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewDataService(t mock.TB) *DataService {
+	m := &DataService{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *DataService) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Process implements example1.DataService
+func (m *DataService) Process(data []byte) ([]byte, error) {
+	if results, ok := m.recorder.Called("Process", data); ok {
+		r0, _ := results[0].([]byte)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ProcessFunc != nil {
+		return m.ProcessFunc(data)
+	}
+	m.recorder.Unexpected("Process", data)
+	var r0 []byte
+	var r1 error
+	return r0, r1
+}
+
+// DataServiceProcessCall is an expectation on DataService.Process
+type DataServiceProcessCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcess expects a call to Process whose arguments match the given
+// matchers or equal the given values
+func (m *DataService) ExpectProcess(data interface{}) DataServiceProcessCall {
+	return DataServiceProcessCall{m.recorder.Expect("Process", 2, data)}
+}
+
+// Return sets the values Process returns
+func (c DataServiceProcessCall) Return(r0 []byte, r1 error) DataServiceProcessCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Process returns from its arguments
+func (c DataServiceProcessCall) Do(fn func(data []byte) ([]byte, error)) DataServiceProcessCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0, r1 := fn(data)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataServiceProcessCall) Times(n int) DataServiceProcessCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataServiceProcessCall) AnyTimes() DataServiceProcessCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataServiceProcessArgs holds the arguments of one Process call
+type DataServiceProcessArgs struct {
+	Data []byte
+}
+
+// ProcessCalls returns the arguments of every Process call in order
+func (m *DataService) ProcessCalls() []DataServiceProcessArgs {
+	var out []DataServiceProcessArgs
+	for _, call := range m.recorder.Calls("Process") {
+		var args DataServiceProcessArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Retrieve implements example1.DataService
+func (m *DataService) Retrieve(id string) ([]byte, error) {
+	if results, ok := m.recorder.Called("Retrieve", id); ok {
+		r0, _ := results[0].([]byte)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.RetrieveFunc != nil {
+		return m.RetrieveFunc(id)
+	}
+	m.recorder.Unexpected("Retrieve", id)
+	var r0 []byte
+	var r1 error
+	return r0, r1
+}
+
+// DataServiceRetrieveCall is an expectation on DataService.Retrieve
+type DataServiceRetrieveCall struct {
+	e *mock.Expectation
+}
+
+// ExpectRetrieve expects a call to Retrieve whose arguments match the given
+// matchers or equal the given values
+func (m *DataService) ExpectRetrieve(id interface{}) DataServiceRetrieveCall {
+	return DataServiceRetrieveCall{m.recorder.Expect("Retrieve", 2, id)}
+}
+
+// Return sets the values Retrieve returns
+func (c DataServiceRetrieveCall) Return(r0 []byte, r1 error) DataServiceRetrieveCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Retrieve returns from its arguments
+func (c DataServiceRetrieveCall) Do(fn func(id string) ([]byte, error)) DataServiceRetrieveCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(string)
+		r0, r1 := fn(id)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataServiceRetrieveCall) Times(n int) DataServiceRetrieveCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataServiceRetrieveCall) AnyTimes() DataServiceRetrieveCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataServiceRetrieveArgs holds the arguments of one Retrieve call
+type DataServiceRetrieveArgs struct {
+	ID string
+}
+
+// RetrieveCalls returns the arguments of every Retrieve call in order
+func (m *DataService) RetrieveCalls() []DataServiceRetrieveArgs {
+	var out []DataServiceRetrieveArgs
+	for _, call := range m.recorder.Calls("Retrieve") {
+		var args DataServiceRetrieveArgs
+		args.ID, _ = call.Args[0].(string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Store implements example1.DataService
+func (m *DataService) Store(data []byte) error {
+	if results, ok := m.recorder.Called("Store", data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.StoreFunc != nil {
+		return m.StoreFunc(data)
+	}
+	m.recorder.Unexpected("Store", data)
+	var r0 error
+	return r0
+}
+
+// DataServiceStoreCall is an expectation on DataService.Store
+type DataServiceStoreCall struct {
+	e *mock.Expectation
+}
+
+// ExpectStore expects a call to Store whose arguments match the given
+// matchers or equal the given values
+func (m *DataService) ExpectStore(data interface{}) DataServiceStoreCall {
+	return DataServiceStoreCall{m.recorder.Expect("Store", 1, data)}
+}
+
+// Return sets the values Store returns
+func (c DataServiceStoreCall) Return(r0 error) DataServiceStoreCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Store returns from its arguments
+func (c DataServiceStoreCall) Do(fn func(data []byte) error) DataServiceStoreCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0 := fn(data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataServiceStoreCall) Times(n int) DataServiceStoreCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataServiceStoreCall) AnyTimes() DataServiceStoreCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataServiceStoreArgs holds the arguments of one Store call
+type DataServiceStoreArgs struct {
+	Data []byte
+}
+
+// StoreCalls returns the arguments of every Store call in order
+func (m *DataService) StoreCalls() []DataServiceStoreArgs {
+	var out []DataServiceStoreArgs
+	for _, call := range m.recorder.Calls("Store") {
+		var args DataServiceStoreArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Validate implements example1.DataService
+func (m *DataService) Validate(data []byte) error {
+	if results, ok := m.recorder.Called("Validate", data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.ValidateFunc != nil {
+		return m.ValidateFunc(data)
+	}
+	m.recorder.Unexpected("Validate", data)
+	var r0 error
+	return r0
+}
+
+// DataServiceValidateCall is an expectation on DataService.Validate
+type DataServiceValidateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectValidate expects a call to Validate whose arguments match the given
+// matchers or equal the given values
+func (m *DataService) ExpectValidate(data interface{}) DataServiceValidateCall {
+	return DataServiceValidateCall{m.recorder.Expect("Validate", 1, data)}
+}
+
+// Return sets the values Validate returns
+func (c DataServiceValidateCall) Return(r0 error) DataServiceValidateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Validate returns from its arguments
+func (c DataServiceValidateCall) Do(fn func(data []byte) error) DataServiceValidateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0 := fn(data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataServiceValidateCall) Times(n int) DataServiceValidateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataServiceValidateCall) AnyTimes() DataServiceValidateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataServiceValidateArgs holds the arguments of one Validate call
+type DataServiceValidateArgs struct {
+	Data []byte
+}
+
+// ValidateCalls returns the arguments of every Validate call in order
+func (m *DataService) ValidateCalls() []DataServiceValidateArgs {
+	var out []DataServiceValidateArgs
+	for _, call := range m.recorder.Calls("Validate") {
+		var args DataServiceValidateArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Processor is a test double for example1.Processor
+type Processor struct {
+	recorder mock.Recorder
+
+	// ProcessFunc, if set, handles Process calls that match no expectation
+	ProcessFunc func(data []byte) ([]byte, error)
+}
+
+var _ example1.Processor = (*Processor)(nil)
+
+// NewProcessor returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewProcessor(t mock.TB) *Processor {
+	m := &Processor{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *Processor) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Process implements example1.Processor
+func (m *Processor) Process(data []byte) ([]byte, error) {
+	if results, ok := m.recorder.Called("Process", data); ok {
+		r0, _ := results[0].([]byte)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ProcessFunc != nil {
+		return m.ProcessFunc(data)
+	}
+	m.recorder.Unexpected("Process", data)
+	var r0 []byte
+	var r1 error
+	return r0, r1
+}
+
+// ProcessorProcessCall is an expectation on Processor.Process
+type ProcessorProcessCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcess expects a call to Process whose arguments match the given
+// matchers or equal the given values
+func (m *Processor) ExpectProcess(data interface{}) ProcessorProcessCall {
+	return ProcessorProcessCall{m.recorder.Expect("Process", 2, data)}
+}
+
+// Return sets the values Process returns
+func (c ProcessorProcessCall) Return(r0 []byte, r1 error) ProcessorProcessCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Process returns from its arguments
+func (c ProcessorProcessCall) Do(fn func(data []byte) ([]byte, error)) ProcessorProcessCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0, r1 := fn(data)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c ProcessorProcessCall) Times(n int) ProcessorProcessCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c ProcessorProcessCall) AnyTimes() ProcessorProcessCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// ProcessorProcessArgs holds the arguments of one Process call
+type ProcessorProcessArgs struct {
+	Data []byte
+}
+
+// ProcessCalls returns the arguments of every Process call in order
+func (m *Processor) ProcessCalls() []ProcessorProcessArgs {
+	var out []ProcessorProcessArgs
+	for _, call := range m.recorder.Calls("Process") {
+		var args ProcessorProcessArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Storage is a test double for example1.Storage
+type Storage struct {
+	recorder mock.Recorder
+
+	// RetrieveFunc, if set, handles Retrieve calls that match no expectation
+	RetrieveFunc func(id string) ([]byte, error)
+
+	// StoreFunc, if set, handles Store calls that match no expectation
+	StoreFunc func(data []byte) error
+}
+
+var _ example1.Storage = (*Storage)(nil)
+
+// NewStorage returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewStorage(t mock.TB) *Storage {
+	m := &Storage{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *Storage) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Retrieve implements example1.Storage
+func (m *Storage) Retrieve(id string) ([]byte, error) {
+	if results, ok := m.recorder.Called("Retrieve", id); ok {
+		r0, _ := results[0].([]byte)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.RetrieveFunc != nil {
+		return m.RetrieveFunc(id)
+	}
+	m.recorder.Unexpected("Retrieve", id)
+	var r0 []byte
+	var r1 error
+	return r0, r1
+}
+
+// StorageRetrieveCall is an expectation on Storage.Retrieve
+type StorageRetrieveCall struct {
+	e *mock.Expectation
+}
+
+// ExpectRetrieve expects a call to Retrieve whose arguments match the given
+// matchers or equal the given values
+func (m *Storage) ExpectRetrieve(id interface{}) StorageRetrieveCall {
+	return StorageRetrieveCall{m.recorder.Expect("Retrieve", 2, id)}
+}
+
+// Return sets the values Retrieve returns
+func (c StorageRetrieveCall) Return(r0 []byte, r1 error) StorageRetrieveCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Retrieve returns from its arguments
+func (c StorageRetrieveCall) Do(fn func(id string) ([]byte, error)) StorageRetrieveCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(string)
+		r0, r1 := fn(id)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c StorageRetrieveCall) Times(n int) StorageRetrieveCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c StorageRetrieveCall) AnyTimes() StorageRetrieveCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// StorageRetrieveArgs holds the arguments of one Retrieve call
+type StorageRetrieveArgs struct {
+	ID string
+}
+
+// RetrieveCalls returns the arguments of every Retrieve call in order
+func (m *Storage) RetrieveCalls() []StorageRetrieveArgs {
+	var out []StorageRetrieveArgs
+	for _, call := range m.recorder.Calls("Retrieve") {
+		var args StorageRetrieveArgs
+		args.ID, _ = call.Args[0].(string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Store implements example1.Storage
+func (m *Storage) Store(data []byte) error {
+	if results, ok := m.recorder.Called("Store", data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.StoreFunc != nil {
+		return m.StoreFunc(data)
+	}
+	m.recorder.Unexpected("Store", data)
+	var r0 error
+	return r0
+}
+
+// StorageStoreCall is an expectation on Storage.Store
+type StorageStoreCall struct {
+	e *mock.Expectation
+}
+
+// ExpectStore expects a call to Store whose arguments match the given
+// matchers or equal the given values
+func (m *Storage) ExpectStore(data interface{}) StorageStoreCall {
+	return StorageStoreCall{m.recorder.Expect("Store", 1, data)}
+}
+
+// Return sets the values Store returns
+func (c StorageStoreCall) Return(r0 error) StorageStoreCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Store returns from its arguments
+func (c StorageStoreCall) Do(fn func(data []byte) error) StorageStoreCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0 := fn(data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c StorageStoreCall) Times(n int) StorageStoreCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c StorageStoreCall) AnyTimes() StorageStoreCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// StorageStoreArgs holds the arguments of one Store call
+type StorageStoreArgs struct {
+	Data []byte
+}
+
+// StoreCalls returns the arguments of every Store call in order
+func (m *Storage) StoreCalls() []StorageStoreArgs {
+	var out []StorageStoreArgs
+	for _, call := range m.recorder.Calls("Store") {
+		var args StorageStoreArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
+
+// StreamProcessor is a test double for example1.StreamProcessor
+type StreamProcessor struct {
+	recorder mock.Recorder
+
+	// ProcessStreamFunc, if set, handles ProcessStream calls that match no expectation
+	ProcessStreamFunc func(ctx context.Context, reader io.Reader) error
+}
+
+var _ example1.StreamProcessor = (*StreamProcessor)(nil)
+
+// NewStreamProcessor returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewStreamProcessor(t mock.TB) *StreamProcessor {
+	m := &StreamProcessor{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *StreamProcessor) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// ProcessStream implements example1.StreamProcessor
+func (m *StreamProcessor) ProcessStream(ctx context.Context, reader io.Reader) error {
+	if results, ok := m.recorder.Called("ProcessStream", ctx, reader); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.ProcessStreamFunc != nil {
+		return m.ProcessStreamFunc(ctx, reader)
+	}
+	m.recorder.Unexpected("ProcessStream", ctx, reader)
+	var r0 error
+	return r0
+}
+
+// StreamProcessorProcessStreamCall is an expectation on StreamProcessor.ProcessStream
+type StreamProcessorProcessStreamCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcessStream expects a call to ProcessStream whose arguments match the given
+// matchers or equal the given values
+func (m *StreamProcessor) ExpectProcessStream(ctx, reader interface{}) StreamProcessorProcessStreamCall {
+	return StreamProcessorProcessStreamCall{m.recorder.Expect("ProcessStream", 1, ctx, reader)}
+}
+
+// Return sets the values ProcessStream returns
+func (c StreamProcessorProcessStreamCall) Return(r0 error) StreamProcessorProcessStreamCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values ProcessStream returns from its arguments
+func (c StreamProcessorProcessStreamCall) Do(fn func(ctx context.Context, reader io.Reader) error) StreamProcessorProcessStreamCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		reader, _ := args[1].(io.Reader)
+		r0 := fn(ctx, reader)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c StreamProcessorProcessStreamCall) Times(n int) StreamProcessorProcessStreamCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c StreamProcessorProcessStreamCall) AnyTimes() StreamProcessorProcessStreamCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// StreamProcessorProcessStreamArgs holds the arguments of one ProcessStream call
+type StreamProcessorProcessStreamArgs struct {
+	Ctx    context.Context
+	Reader io.Reader
+}
+
+// ProcessStreamCalls returns the arguments of every ProcessStream call in order
+func (m *StreamProcessor) ProcessStreamCalls() []StreamProcessorProcessStreamArgs {
+	var out []StreamProcessorProcessStreamArgs
+	for _, call := range m.recorder.Calls("ProcessStream") {
+		var args StreamProcessorProcessStreamArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Reader, _ = call.Args[1].(io.Reader)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Validator is a test double for example1.Validator
+type Validator struct {
+	recorder mock.Recorder
+
+	// ValidateFunc, if set, handles Validate calls that match no expectation
+	ValidateFunc func(data []byte) error
+}
+
+var _ example1.Validator = (*Validator)(nil)
+
+// NewValidator returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewValidator(t mock.TB) *Validator {
+	m := &Validator{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *Validator) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Validate implements example1.Validator
+func (m *Validator) Validate(data []byte) error {
+	if results, ok := m.recorder.Called("Validate", data); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.ValidateFunc != nil {
+		return m.ValidateFunc(data)
+	}
+	m.recorder.Unexpected("Validate", data)
+	var r0 error
+	return r0
+}
+
+// ValidatorValidateCall is an expectation on Validator.Validate
+type ValidatorValidateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectValidate expects a call to Validate whose arguments match the given
+// matchers or equal the given values
+func (m *Validator) ExpectValidate(data interface{}) ValidatorValidateCall {
+	return ValidatorValidateCall{m.recorder.Expect("Validate", 1, data)}
+}
+
+// Return sets the values Validate returns
+func (c ValidatorValidateCall) Return(r0 error) ValidatorValidateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Validate returns from its arguments
+func (c ValidatorValidateCall) Do(fn func(data []byte) error) ValidatorValidateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		data, _ := args[0].([]byte)
+		r0 := fn(data)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c ValidatorValidateCall) Times(n int) ValidatorValidateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c ValidatorValidateCall) AnyTimes() ValidatorValidateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// ValidatorValidateArgs holds the arguments of one Validate call
+type ValidatorValidateArgs struct {
+	Data []byte
+}
+
+// ValidateCalls returns the arguments of every Validate call in order
+func (m *Validator) ValidateCalls() []ValidatorValidateArgs {
+	var out []ValidatorValidateArgs
+	for _, call := range m.recorder.Calls("Validate") {
+		var args ValidatorValidateArgs
+		args.Data, _ = call.Args[0].([]byte)
+		out = append(out, args)
+	}
+	return out
+}
@@ -3,7 +3,10 @@ package example10
 import (
 	"errors"
 	"fmt"
+	"io"
 	"time"
+
+	"practice/examples/demo"
 )
 
 // Custom error for demonstration
@@ -27,28 +30,29 @@ func ProcessUser(id int, name string) (*User, error) {
 }
 
 // Run demonstrates idiomatic Go practices
-func Run() error {
-	fmt.Println("--- Idiomatic Go Example ---")
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	fmt.Fprintln(env, "--- Idiomatic Go Example ---")
 
 	// 1. Error handling
 	user, err := ProcessUser(1, "Gopher")
 	if err != nil {
 		return fmt.Errorf("failed to process user: %w", err)
 	}
-	fmt.Printf("Processed user: %+v\n", user)
+	fmt.Fprintf(env, "Processed user: %+v\n", user)
 
 	// 2. Naming conventions and code organization
 	// This section demonstrates proper naming and organization
-	fmt.Println("Demonstrating naming conventions and code organization...")
-	time.Sleep(500 * time.Millisecond)
-	fmt.Println("Naming conventions and code organization demonstrated.")
+	fmt.Fprintln(env, "Demonstrating naming conventions and code organization...")
+	env.Sleep(500 * time.Millisecond)
+	fmt.Fprintln(env, "Naming conventions and code organization demonstrated.")
 
 	return nil
 }
 
 // RunBenchmark demonstrates benchmarking idiomatic Go practices
-func RunBenchmark() error {
-	fmt.Println("--- Idiomatic Go Benchmark ---")
+func RunBenchmark(w io.Writer) error {
+	fmt.Fprintln(w, "--- Idiomatic Go Benchmark ---")
 	start := time.Now()
 	iters := 1000
 	for i := 0; i < iters; i++ {
@@ -58,7 +62,7 @@ func RunBenchmark() error {
 		}
 	}
 	duration := time.Since(start)
-	fmt.Printf("Ran ProcessUser %d times in %v\n", iters, duration)
-	fmt.Printf("Average time per run: %v\n", duration/time.Duration(iters))
+	fmt.Fprintf(w, "Ran ProcessUser %d times in %v\n", iters, duration)
+	fmt.Fprintf(w, "Average time per run: %v\n", duration/time.Duration(iters))
 	return nil
 }
@@ -3,8 +3,12 @@ package example2
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
+
+	"practice/examples/demo"
+	"practice/examples/example3"
 )
 
 // Worker represents a worker that processes jobs
@@ -14,16 +18,18 @@ type Worker struct {
 	wg      *sync.WaitGroup
 	jobs    <-chan int
 	results chan<- int
+	env     *demo.Env
 }
 
-// NewWorker creates a new worker
-func NewWorker(id int, ctx context.Context, wg *sync.WaitGroup, jobs <-chan int, results chan<- int) *Worker {
+// NewWorker creates a new worker that reports its progress to env
+func NewWorker(id int, ctx context.Context, wg *sync.WaitGroup, jobs <-chan int, results chan<- int, env *demo.Env) *Worker {
 	return &Worker{
 		id:      id,
 		ctx:     ctx,
 		wg:      wg,
 		jobs:    jobs,
 		results: results,
+		env:     env,
 	}
 }
 
@@ -34,24 +40,33 @@ func (w *Worker) Start() {
 		for {
 			select {
 			case <-w.ctx.Done():
-				fmt.Printf("Worker %d shutting down\n", w.id)
+				fmt.Fprintf(w.env, "Worker %d shutting down\n", w.id)
 				return
 			case job, ok := <-w.jobs:
 				if !ok {
 					return
 				}
-				// Simulate work
-				time.Sleep(100 * time.Millisecond)
-				result := job * 2
-				w.results <- result
-				fmt.Printf("Worker %d processed job %d, result: %d\n", w.id, job, result)
+				// A panicking job is reported instead of killing the worker
+				err := example3.Safe(fmt.Sprintf("worker_%d", w.id), func() error {
+					// Simulate work
+					w.env.Sleep(100 * time.Millisecond)
+					result := job * 2
+					w.results <- result
+					fmt.Fprintf(w.env, "Worker %d processed job %d, result: %d\n", w.id, job, result)
+					return nil
+				})
+				if err != nil {
+					fmt.Fprintf(w.env, "Worker %d recovered from job %d: %v\n", w.id, job, err)
+				}
 			}
 		}
 	}()
 }
 
 // Run demonstrates various concurrency patterns
-func Run() error {
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -67,14 +82,16 @@ func Run() error {
 	numWorkers := 3
 	wg.Add(numWorkers)
 	for i := 1; i <= numWorkers; i++ {
-		worker := NewWorker(i, ctx, &wg, jobs, results)
+		worker := NewWorker(i, ctx, &wg, jobs, results, env)
 		worker.Start()
 	}
 
 	// Start result collector
+	collected := make(chan struct{})
 	go func() {
+		defer close(collected)
 		for result := range results {
-			fmt.Printf("Received result: %d\n", result)
+			fmt.Fprintf(env, "Received result: %d\n", result)
 		}
 	}()
 
@@ -86,7 +103,7 @@ func Run() error {
 			case <-ctx.Done():
 				return
 			case jobs <- i:
-				fmt.Printf("Sent job %d\n", i)
+				fmt.Fprintf(env, "Sent job %d\n", i)
 			}
 		}
 	}()
@@ -94,6 +111,7 @@ func Run() error {
 	// Wait for workers to finish
 	wg.Wait()
 	close(results)
+	<-collected
 
 	// Check if we timed out
 	if ctx.Err() == context.DeadlineExceeded {
@@ -104,7 +122,9 @@ func Run() error {
 }
 
 // RunPipeline demonstrates a pipeline pattern
-func RunPipeline() error {
+func RunPipeline(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -122,7 +142,7 @@ func RunPipeline() error {
 			case <-ctx.Done():
 				return
 			case numbers <- i:
-				fmt.Printf("Generated: %d\n", i)
+				fmt.Fprintf(env, "Generated: %d\n", i)
 			}
 		}
 	}()
@@ -134,7 +154,7 @@ func RunPipeline() error {
 			case <-ctx.Done():
 				return
 			case squares <- n * n:
-				fmt.Printf("Squared: %d\n", n*n)
+				fmt.Fprintf(env, "Squared: %d\n", n*n)
 			}
 		}
 	}()
@@ -146,14 +166,14 @@ func RunPipeline() error {
 			case <-ctx.Done():
 				return
 			case results <- s + 1:
-				fmt.Printf("Final result: %d\n", s+1)
+				fmt.Fprintf(env, "Final result: %d\n", s+1)
 			}
 		}
 	}()
 
 	// Collect results
 	for r := range results {
-		fmt.Printf("Pipeline output: %d\n", r)
+		fmt.Fprintf(env, "Pipeline output: %d\n", r)
 	}
 
 	return nil
@@ -0,0 +1,226 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Code is a stable, machine-readable identifier attached to an error.
+// Unlike the error message, a code never changes once published, so
+// clients can safely switch on it.
+type Code string
+
+// Known error codes
+const (
+	CodeUnknown             Code = "UNKNOWN"
+	CodeInvalidInput        Code = "INVALID_INPUT"
+	CodeNotFound            Code = "NOT_FOUND"
+	CodeUserNameEmpty       Code = "USER_NAME_EMPTY"
+	CodeUserAgeRange        Code = "USER_AGE_OUT_OF_RANGE"
+	CodeUserDuplicateID     Code = "USER_DUPLICATE_ID"
+	CodeUserNotFound        Code = "USER_NOT_FOUND"
+	CodeUserVersionConflict Code = "USER_VERSION_CONFLICT"
+	CodeInputNotNumeric     Code = "INPUT_NOT_NUMERIC"
+	CodeInputNotPositive    Code = "INPUT_NOT_POSITIVE"
+	CodeUnavailable         Code = "UNAVAILABLE"
+	CodeCircuitOpen         Code = "CIRCUIT_OPEN"
+	CodePanic               Code = "PANIC"
+)
+
+// GRPCStatus mirrors the canonical gRPC status codes without pulling in
+// the grpc module.
+type GRPCStatus int
+
+// Canonical gRPC status codes
+const (
+	GRPCOK                 GRPCStatus = 0
+	GRPCCanceled           GRPCStatus = 1
+	GRPCUnknown            GRPCStatus = 2
+	GRPCInvalidArgument    GRPCStatus = 3
+	GRPCDeadlineExceeded   GRPCStatus = 4
+	GRPCNotFound           GRPCStatus = 5
+	GRPCAlreadyExists      GRPCStatus = 6
+	GRPCPermissionDenied   GRPCStatus = 7
+	GRPCResourceExhausted  GRPCStatus = 8
+	GRPCFailedPrecondition GRPCStatus = 9
+	GRPCAborted            GRPCStatus = 10
+	GRPCInternal           GRPCStatus = 13
+	GRPCUnavailable        GRPCStatus = 14
+)
+
+var grpcStatusNames = map[GRPCStatus]string{
+	GRPCOK:                 "OK",
+	GRPCCanceled:           "CANCELED",
+	GRPCUnknown:            "UNKNOWN",
+	GRPCInvalidArgument:    "INVALID_ARGUMENT",
+	GRPCDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	GRPCNotFound:           "NOT_FOUND",
+	GRPCAlreadyExists:      "ALREADY_EXISTS",
+	GRPCPermissionDenied:   "PERMISSION_DENIED",
+	GRPCResourceExhausted:  "RESOURCE_EXHAUSTED",
+	GRPCFailedPrecondition: "FAILED_PRECONDITION",
+	GRPCAborted:            "ABORTED",
+	GRPCInternal:           "INTERNAL",
+	GRPCUnavailable:        "UNAVAILABLE",
+}
+
+func (s GRPCStatus) String() string {
+	if name, ok := grpcStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("GRPCStatus(%d)", int(s))
+}
+
+// CodeInfo describes how an error code should be surfaced to callers
+type CodeInfo struct {
+	HTTPStatus int
+	GRPCStatus GRPCStatus
+	Retryable  bool
+}
+
+// codeRegistry maps codes to their transport metadata
+type codeRegistry struct {
+	mu    sync.RWMutex
+	codes map[Code]CodeInfo
+}
+
+var registry = &codeRegistry{
+	codes: map[Code]CodeInfo{
+		CodeUnknown:             {HTTPStatus: http.StatusInternalServerError, GRPCStatus: GRPCUnknown},
+		CodeInvalidInput:        {HTTPStatus: http.StatusBadRequest, GRPCStatus: GRPCInvalidArgument},
+		CodeNotFound:            {HTTPStatus: http.StatusNotFound, GRPCStatus: GRPCNotFound},
+		CodeUserNameEmpty:       {HTTPStatus: http.StatusBadRequest, GRPCStatus: GRPCInvalidArgument},
+		CodeUserAgeRange:        {HTTPStatus: http.StatusBadRequest, GRPCStatus: GRPCInvalidArgument},
+		CodeUserDuplicateID:     {HTTPStatus: http.StatusConflict, GRPCStatus: GRPCAlreadyExists},
+		CodeUserNotFound:        {HTTPStatus: http.StatusNotFound, GRPCStatus: GRPCNotFound},
+		CodeUserVersionConflict: {HTTPStatus: http.StatusConflict, GRPCStatus: GRPCAborted},
+		CodeInputNotNumeric:     {HTTPStatus: http.StatusBadRequest, GRPCStatus: GRPCInvalidArgument},
+		CodeInputNotPositive:    {HTTPStatus: http.StatusBadRequest, GRPCStatus: GRPCInvalidArgument},
+		CodeUnavailable:         {HTTPStatus: http.StatusServiceUnavailable, GRPCStatus: GRPCUnavailable, Retryable: true},
+		CodeCircuitOpen:         {HTTPStatus: http.StatusServiceUnavailable, GRPCStatus: GRPCUnavailable, Retryable: true},
+		CodePanic:               {HTTPStatus: http.StatusInternalServerError, GRPCStatus: GRPCInternal},
+	},
+}
+
+// RegisterCode adds a new code to the registry. Codes are stable, so
+// registering the same code twice is an error.
+func RegisterCode(code Code, info CodeInfo) error {
+	if code == "" {
+		return fmt.Errorf("%w: code cannot be empty", ErrInvalidInput)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if _, exists := registry.codes[code]; exists {
+		return fmt.Errorf("code %s already registered", code)
+	}
+	registry.codes[code] = info
+	return nil
+}
+
+// LookupCode returns the registered metadata for a code
+func LookupCode(code Code) (CodeInfo, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	info, ok := registry.codes[code]
+	return info, ok
+}
+
+// coded is implemented by errors that carry a Code
+type coded interface {
+	error
+	ErrorCode() Code
+}
+
+// ErrorCode returns the code attached to the validation error
+func (e *ValidationError) ErrorCode() Code {
+	return e.Code
+}
+
+// ErrorCode returns the code attached to the processing error
+func (e *ProcessingError) ErrorCode() Code {
+	return e.Code
+}
+
+// CodeOf returns the outermost code in err's tree, searching depth first
+// as errors.As does, through the branches of joined errors too. Wrappers
+// without a code of their own are skipped, and errors that only wrap one
+// of the sentinel errors fall back to the matching generic code.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	if code := firstCode(err); code != "" {
+		return code
+	}
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrInvalidInput):
+		return CodeInvalidInput
+	default:
+		return CodeUnknown
+	}
+}
+
+// firstCode returns the first non-empty code in a depth-first walk of err's
+// tree, or "" if there is none
+func firstCode(err error) Code {
+	for err != nil {
+		if c, ok := err.(coded); ok && c.ErrorCode() != "" {
+			return c.ErrorCode()
+		}
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				if code := firstCode(inner); code != "" {
+					return code
+				}
+			}
+			return ""
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// infoOf returns the registered metadata for the code found in err
+func infoOf(err error) CodeInfo {
+	if info, ok := LookupCode(CodeOf(err)); ok {
+		return info
+	}
+	info, _ := LookupCode(CodeUnknown)
+	return info
+}
+
+// HTTPStatusOf maps err to an HTTP status code
+func HTTPStatusOf(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return infoOf(err).HTTPStatus
+}
+
+// GRPCStatusOf maps err to a gRPC-style status code
+func GRPCStatusOf(err error) GRPCStatus {
+	if err == nil {
+		return GRPCOK
+	}
+	return infoOf(err).GRPCStatus
+}
+
+// IsRetryable reports whether the code found in err is marked retryable
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return infoOf(err).Retryable
+}
@@ -0,0 +1,118 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	validation := ValidationErrors{
+		{Field: "name", Code: CodeUserNameEmpty, Err: ErrInvalidInput},
+		{Field: "age", Code: CodeUserAgeRange, Err: ErrInvalidInput},
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"nil", nil, ""},
+		{"plain error", errors.New("boom"), CodeUnknown},
+		{"processing error", &ProcessingError{Code: CodeUserNotFound, Err: ErrNotFound}, CodeUserNotFound},
+		{"wrapped processing error", fmt.Errorf("handler: %w", &ProcessingError{Code: CodeUnavailable}), CodeUnavailable},
+		{"validation error", &ValidationError{Code: CodeUserAgeRange, Err: ErrInvalidInput}, CodeUserAgeRange},
+		{"uncoded wrapper falls through to inner code", &ProcessingError{Err: &ValidationError{Code: CodeUserNameEmpty}}, CodeUserNameEmpty},
+		{"outermost code wins", &ProcessingError{Code: CodeInvalidInput, Err: &ValidationError{Code: CodeUserNameEmpty}}, CodeInvalidInput},
+		{"first validation error of many", &ProcessingError{Err: validation}, CodeUserNameEmpty},
+		{"uncoded not found", fmt.Errorf("lookup: %w", ErrNotFound), CodeNotFound},
+		{"uncoded invalid input", &ProcessingError{Err: ErrInvalidInput}, CodeInvalidInput},
+		{"uncoded errors fall back to sentinels", &ProcessingError{Err: &ValidationError{Err: ErrNotFound}}, CodeNotFound},
+		{"uncoded other error", &ProcessingError{Err: errors.New("boom")}, CodeUnknown},
+		{"joined branches are searched", errors.Join(&ValidationError{Err: errors.New("x")}, &ProcessingError{Code: CodeUnavailable}), CodeUnavailable},
+		{"first coded branch wins", fmt.Errorf("%w; %w", &ValidationError{Code: CodeUserAgeRange}, &ProcessingError{Code: CodeUnavailable}), CodeUserAgeRange},
+		{"join inside an uncoded wrapper", &ProcessingError{Err: errors.Join(errors.New("x"), &ValidationError{Code: CodeUserNameEmpty})}, CodeUserNameEmpty},
+		{"uncoded join falls back to sentinels", errors.Join(errors.New("x"), ErrNotFound), CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusMapping(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		http      int
+		grpc      GRPCStatus
+		retryable bool
+	}{
+		{"nil", nil, http.StatusOK, GRPCOK, false},
+		{"invalid input", ErrInvalidInput, http.StatusBadRequest, GRPCInvalidArgument, false},
+		{"not found", ErrNotFound, http.StatusNotFound, GRPCNotFound, false},
+		{"duplicate", &ProcessingError{Code: CodeUserDuplicateID}, http.StatusConflict, GRPCAlreadyExists, false},
+		{"version conflict", &ProcessingError{Code: CodeUserVersionConflict}, http.StatusConflict, GRPCAborted, false},
+		{"unavailable", &ProcessingError{Code: CodeUnavailable}, http.StatusServiceUnavailable, GRPCUnavailable, true},
+		{"circuit open", &ProcessingError{Code: CodeCircuitOpen}, http.StatusServiceUnavailable, GRPCUnavailable, true},
+		{"panic", &ProcessingError{Code: CodePanic}, http.StatusInternalServerError, GRPCInternal, false},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, GRPCUnknown, false},
+		{"unregistered code", &ProcessingError{Code: "NEVER_REGISTERED"}, http.StatusInternalServerError, GRPCUnknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTTPStatusOf(tt.err); got != tt.http {
+				t.Errorf("HTTPStatusOf() = %d, want %d", got, tt.http)
+			}
+			if got := GRPCStatusOf(tt.err); got != tt.grpc {
+				t.Errorf("GRPCStatusOf() = %s, want %s", got, tt.grpc)
+			}
+			if got := IsRetryable(tt.err); got != tt.retryable {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestRegisterCode(t *testing.T) {
+	code := Code("TEST_RATE_LIMITED")
+	info := CodeInfo{HTTPStatus: http.StatusTooManyRequests, GRPCStatus: GRPCResourceExhausted, Retryable: true}
+
+	if _, ok := LookupCode(code); ok {
+		t.Fatalf("LookupCode(%s) found a code that was never registered", code)
+	}
+	if err := RegisterCode(code, info); err != nil {
+		t.Fatalf("RegisterCode() error = %v", err)
+	}
+	if got, ok := LookupCode(code); !ok || got != info {
+		t.Errorf("LookupCode() = %+v, %v, want %+v", got, ok, info)
+	}
+
+	err := &ProcessingError{Code: code}
+	if HTTPStatusOf(err) != http.StatusTooManyRequests || GRPCStatusOf(err) != GRPCResourceExhausted || !IsRetryable(err) {
+		t.Errorf("registered code maps to %d, %s, retryable %v", HTTPStatusOf(err), GRPCStatusOf(err), IsRetryable(err))
+	}
+
+	if err := RegisterCode(code, CodeInfo{}); err == nil {
+		t.Error("RegisterCode() twice succeeded, want an error")
+	}
+	if err := RegisterCode(CodeUnknown, CodeInfo{}); err == nil {
+		t.Error("RegisterCode() of a built-in code succeeded, want an error")
+	}
+	if err := RegisterCode("", info); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("RegisterCode(\"\") error = %v, want %v", err, ErrInvalidInput)
+	}
+}
+
+func TestGRPCStatusString(t *testing.T) {
+	if got := GRPCUnavailable.String(); got != "UNAVAILABLE" {
+		t.Errorf("String() = %q, want UNAVAILABLE", got)
+	}
+	if got := GRPCStatus(99).String(); got != "GRPCStatus(99)" {
+		t.Errorf("String() = %q, want GRPCStatus(99)", got)
+	}
+}
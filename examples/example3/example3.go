@@ -4,20 +4,27 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"sync"
 	"time"
+
+	"practice/examples/demo"
+	"practice/examples/tabletest"
 )
 
 // Common errors
 var (
 	ErrInvalidInput = errors.New("invalid input")
 	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("version conflict")
 )
 
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field string
 	Value interface{}
+	Code  Code
 	Err   error
 }
 
@@ -29,11 +36,16 @@ func (e *ValidationError) Unwrap() error {
 	return e.Err
 }
 
-// ProcessingError represents a processing error
+// ProcessingError represents a processing error. Use NewProcessingError to
+// also record the caller's stack.
 type ProcessingError struct {
 	Operation string
+	Code      Code
 	Err       error
 	Timestamp time.Time
+	Fields    []Field
+
+	stack []uintptr
 }
 
 func (e *ProcessingError) Error() string {
@@ -47,182 +59,270 @@ func (e *ProcessingError) Unwrap() error {
 // User represents a user in the system
 type User struct {
 	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	Age       int       `json:"age"`
+	Name      string    `json:"name" validate:"required,len<=64" code:"required=USER_NAME_EMPTY"`
+	Age       int       `json:"age" validate:"min=0,max=150" code:"USER_AGE_OUT_OF_RANGE"`
 	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version"`
 }
 
-// UserService handles user operations
+// UserService handles user operations. It is safe for concurrent use and
+// stores copies, so callers may keep modifying the users they pass in.
 type UserService struct {
+	mu    sync.RWMutex
 	users map[int]*User
+	now   func() time.Time
 }
 
-// NewUserService creates a new user service
-func NewUserService() *UserService {
-	return &UserService{
-		users: make(map[int]*User),
+// Option configures a UserService
+type Option func(*UserService)
+
+// WithClock replaces time.Now for creation times and error timestamps
+func WithClock(now func() time.Time) Option {
+	return func(s *UserService) {
+		s.now = now
 	}
 }
 
-// ValidateUser validates a user
-func (s *UserService) ValidateUser(user *User) error {
-	if user.Name == "" {
-		return &ValidationError{
-			Field: "name",
-			Value: user.Name,
-			Err:   fmt.Errorf("name cannot be empty"),
-		}
+// NewUserService creates a new user service
+func NewUserService(opts ...Option) *UserService {
+	s := &UserService{
+		users: make(map[int]*User),
+		now:   time.Now,
 	}
-
-	if user.Age < 0 || user.Age > 150 {
-		return &ValidationError{
-			Field: "age",
-			Value: user.Age,
-			Err:   fmt.Errorf("age must be between 0 and 150"),
-		}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
 
-	return nil
+// stamp sets an error's timestamp from the service clock
+func (s *UserService) stamp(e *ProcessingError) *ProcessingError {
+	e.Timestamp = s.now()
+	return e
+}
+
+// ValidateUser validates a user against its validate tags, reporting every
+// invalid field as ValidationErrors
+func (s *UserService) ValidateUser(user *User) error {
+	return Validate(user)
 }
 
-// CreateUser creates a new user
+// CreateUser creates a new user. The check for an existing ID and the
+// insert happen atomically, so concurrent creators of the same ID see
+// exactly one success.
 func (s *UserService) CreateUser(user *User) error {
 	// Validate the user
+	if user == nil {
+		return s.stamp(NewProcessingError("create_user", CodeInvalidInput,
+			fmt.Errorf("%w: user cannot be nil", ErrInvalidInput)))
+	}
 	if err := s.ValidateUser(user); err != nil {
-		return &ProcessingError{
-			Operation: "create_user",
-			Err:       err,
-			Timestamp: time.Now(),
-		}
+		return s.stamp(NewProcessingError("create_user", "", err).With("user_id", user.ID))
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Check if user already exists
 	if _, exists := s.users[user.ID]; exists {
-		return &ProcessingError{
-			Operation: "create_user",
-			Err:       fmt.Errorf("user with ID %d already exists", user.ID),
-			Timestamp: time.Now(),
-		}
+		return s.stamp(NewProcessingError("create_user", CodeUserDuplicateID,
+			fmt.Errorf("user with ID %d already exists", user.ID)).With("user_id", user.ID))
 	}
 
 	// Create the user
-	user.CreatedAt = time.Now()
-	s.users[user.ID] = user
+	user.CreatedAt = s.now()
+	user.Version = 1
+	stored := *user
+	s.users[user.ID] = &stored
 	return nil
 }
 
 // GetUser retrieves a user by ID
 func (s *UserService) GetUser(id int) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	user, exists := s.users[id]
 	if !exists {
-		return nil, &ProcessingError{
-			Operation: "get_user",
-			Err:       fmt.Errorf("%w: user with ID %d", ErrNotFound, id),
-			Timestamp: time.Now(),
-		}
+		return nil, s.stamp(NewProcessingError("get_user", CodeUserNotFound,
+			fmt.Errorf("%w: user with ID %d", ErrNotFound, id)).With("user_id", id))
 	}
-	return user, nil
+	found := *user
+	return &found, nil
 }
 
-// Run demonstrates error handling patterns
-func Run() error {
-	service := NewUserService()
-
-	// Test cases
-	testCases := []struct {
-		name    string
-		user    *User
-		wantErr bool
-	}{
-		{
-			name: "valid user",
-			user: &User{
-				ID:   1,
-				Name: "John Doe",
-				Age:  30,
+// UpdateUser replaces a user if user.Version still matches the stored
+// version, then bumps the version. When another writer got there first it
+// returns a *VersionConflictError; re-read the user and try again.
+func (s *UserService) UpdateUser(user *User) error {
+	if user == nil {
+		return s.stamp(NewProcessingError("update_user", CodeInvalidInput,
+			fmt.Errorf("%w: user cannot be nil", ErrInvalidInput)))
+	}
+	if err := s.ValidateUser(user); err != nil {
+		return s.stamp(NewProcessingError("update_user", "", err).With("user_id", user.ID))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.users[user.ID]
+	if !exists {
+		return s.stamp(NewProcessingError("update_user", CodeUserNotFound,
+			fmt.Errorf("%w: user with ID %d", ErrNotFound, user.ID)).With("user_id", user.ID))
+	}
+	if stored.Version != user.Version {
+		return s.stamp(NewProcessingError("update_user", CodeUserVersionConflict, &VersionConflictError{
+			ID:       user.ID,
+			Expected: user.Version,
+			Actual:   stored.Version,
+		}).With("user_id", user.ID))
+	}
+
+	user.CreatedAt = stored.CreatedAt
+	user.Version = stored.Version + 1
+	updated := *user
+	s.users[user.ID] = &updated
+	return nil
+}
+
+// VersionConflictError reports a failed compare-and-swap update
+type VersionConflictError struct {
+	ID       int
+	Expected int64
+	Actual   int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%v: user %d is at version %d, update was based on version %d", ErrConflict, e.ID, e.Actual, e.Expected)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// createUserTable returns the user creation cases. Each case creates a
+// user on service and reads it back. Run prints it as a walkthrough and the
+// package tests run it under go test.
+func createUserTable(service *UserService) tabletest.Table[*User, *User] {
+	return tabletest.Table[*User, *User]{
+		Name: "create_user",
+		Cases: []tabletest.Case[*User, *User]{
+			{
+				Name:  "valid user",
+				Input: &User{ID: 1, Name: "John Doe", Age: 30},
+				Want:  &User{ID: 1, Name: "John Doe", Age: 30, Version: 1},
 			},
-			wantErr: false,
-		},
-		{
-			name: "invalid name",
-			user: &User{
-				ID:   2,
-				Name: "",
-				Age:  30,
+			{
+				Name:    "invalid name",
+				Input:   &User{ID: 2, Name: "", Age: 30},
+				WantErr: true,
 			},
-			wantErr: true,
-		},
-		{
-			name: "invalid age",
-			user: &User{
-				ID:   3,
-				Name: "Jane Doe",
-				Age:  -1,
+			{
+				Name:    "invalid age",
+				Input:   &User{ID: 3, Name: "Jane Doe", Age: -1},
+				WantErr: true,
+			},
+			{
+				Name:    "invalid name and age",
+				Input:   &User{ID: 4, Name: "", Age: 200},
+				WantErr: true,
 			},
-			wantErr: true,
 		},
-	}
-
-	// Run test cases
-	for _, tc := range testCases {
-		fmt.Printf("\nTesting: %s\n", tc.name)
-
-		// Create user
-		err := service.CreateUser(tc.user)
-		if err != nil {
-			if !tc.wantErr {
-				return fmt.Errorf("unexpected error: %w", err)
+		Run: func(user *User) (*User, error) {
+			if err := service.CreateUser(user); err != nil {
+				return nil, err
 			}
+			return service.GetUser(user.ID)
+		},
+		Equal: func(got, want *User) bool {
+			return got.ID == want.ID && got.Name == want.Name && got.Age == want.Age && got.Version == want.Version
+		},
+		Describe: describeCreateUser,
+	}
+}
 
-			// Handle different error types
-			var validationErr *ValidationError
-			var processingErr *ProcessingError
-
-			switch {
-			case errors.As(err, &validationErr):
-				fmt.Printf("Validation error: %v\n", validationErr)
-			case errors.As(err, &processingErr):
-				fmt.Printf("Processing error: %v\n", processingErr)
-			default:
-				fmt.Printf("Unknown error: %v\n", err)
-			}
-			continue
-		}
+// describeCreateUser prints the created user, or how to handle the error
+func describeCreateUser(w io.Writer, user *User, err error) {
+	if err == nil {
+		userJSON, _ := json.MarshalIndent(user, "", "  ")
+		fmt.Fprintf(w, "Created user: %s\n", userJSON)
+		return
+	}
 
-		// Try to get the user
-		user, err := service.GetUser(tc.user.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get user: %w", err)
-		}
+	// Handle different error types
+	var validationErr *ValidationError
+	var processingErr *ProcessingError
+
+	switch {
+	case errors.As(err, &validationErr):
+		fmt.Fprintf(w, "Validation error: %v\n", validationErr)
+	case errors.As(err, &processingErr):
+		fmt.Fprintf(w, "Processing error: %v\n", processingErr)
+	default:
+		fmt.Fprintf(w, "Unknown error: %v\n", err)
+	}
+	fmt.Fprintf(w, "Code: %s (HTTP %d, gRPC %s)\n", CodeOf(err), HTTPStatusOf(err), GRPCStatusOf(err))
+	fmt.Fprintf(w, "Log: %s\n", MarshalErrorLogfmt(err))
 
-		// Print user details
-		userJSON, _ := json.MarshalIndent(user, "", "  ")
-		fmt.Printf("Created user: %s\n", userJSON)
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		body, _ := json.Marshal(validationErrs)
+		fmt.Fprintf(w, "Invalid fields: %v\nResponse body: %s\n", validationErrs.Fields(), body)
 	}
+}
 
-	return nil
+// Run demonstrates error handling patterns
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	return createUserTable(NewUserService(WithClock(env.Now))).Print(env)
 }
 
 // RunErrorWrapping demonstrates error wrapping patterns
-func RunErrorWrapping() error {
+func RunErrorWrapping(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+
 	// Example of error wrapping
 	err := processData("invalid")
 	if err != nil {
 		// Unwrap the error chain
-		fmt.Printf("Error: %v\n", err)
-		fmt.Printf("Unwrapped: %v\n", errors.Unwrap(err))
+		fmt.Fprintf(env, "Error: %v\n", err)
+		fmt.Fprintf(env, "Unwrapped: %v\n", errors.Unwrap(err))
 
 		// Check for specific error types
 		if errors.Is(err, ErrInvalidInput) {
-			fmt.Println("Error is ErrInvalidInput")
+			fmt.Fprintln(env, "Error is ErrInvalidInput")
 		}
 
 		var validationErr *ValidationError
 		if errors.As(err, &validationErr) {
-			fmt.Printf("Error is ValidationError: %v\n", validationErr)
+			fmt.Fprintf(env, "Error is ValidationError: %v\n", validationErr)
 		}
+
+		fmt.Fprintf(env, "Error code: %s\n", CodeOf(err))
 	}
 
+	// Example of structured context and stack traces
+	service := NewUserService(WithClock(env.Now))
+	_, err = service.GetUser(42)
+	var processingErr *ProcessingError
+	if errors.As(err, &processingErr) {
+		processingErr.With("request_id", "req-1234")
+		fmt.Fprintf(env, "\nDetailed error:\n%s\n", detailed(env, err))
+	}
+
+	// Example of sending an error across a process boundary
+	encoded, marshalErr := MarshalErrorJSON(err)
+	if marshalErr != nil {
+		return fmt.Errorf("failed to encode error: %w", marshalErr)
+	}
+	decoded, decodeErr := UnmarshalErrorJSON(encoded)
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode error: %w", decodeErr)
+	}
+	fmt.Fprintf(env, "\nDecoded error: %v\n", decoded)
+	fmt.Fprintf(env, "Decoded error is ErrNotFound: %t, code: %s\n", errors.Is(decoded, ErrNotFound), CodeOf(decoded))
+
 	return nil
 }
 
@@ -233,6 +333,7 @@ func processData(input string) error {
 		return &ValidationError{
 			Field: "input",
 			Value: input,
+			Code:  CodeInputNotNumeric,
 			Err:   fmt.Errorf("%w: %v", ErrInvalidInput, err),
 		}
 	}
@@ -241,9 +342,80 @@ func processData(input string) error {
 		return &ValidationError{
 			Field: "input",
 			Value: num,
+			Code:  CodeInputNotPositive,
 			Err:   fmt.Errorf("%w: number must be positive", ErrInvalidInput),
 		}
 	}
 
 	return nil
 }
+
+// RunConcurrency demonstrates concurrent creates and optimistic updates
+func RunConcurrency(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	service := NewUserService(WithClock(env.Now))
+
+	// Many goroutines race to create the same user; exactly one wins
+	const creators = 10
+	var wg sync.WaitGroup
+	results := make(chan error, creators)
+	for i := 0; i < creators; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results <- service.CreateUser(&User{ID: 1, Name: fmt.Sprintf("Creator %d", i), Age: 30})
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	created, duplicates := 0, 0
+	for err := range results {
+		switch {
+		case err == nil:
+			created++
+		case CodeOf(err) == CodeUserDuplicateID:
+			duplicates++
+		default:
+			return fmt.Errorf("unexpected create error: %w", err)
+		}
+	}
+	fmt.Fprintf(env, "Concurrent creates: %d succeeded, %d rejected as duplicates\n", created, duplicates)
+
+	// Two clients read the same version and both try to update
+	first, err := service.GetUser(1)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	second, err := service.GetUser(1)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	first.Name = "First Writer"
+	if err := service.UpdateUser(first); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	fmt.Fprintf(env, "First update succeeded, now at version %d\n", first.Version)
+
+	second.Name = "Second Writer"
+	err = service.UpdateUser(second)
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) {
+		return fmt.Errorf("expected a version conflict, got: %v", err)
+	}
+	fmt.Fprintf(env, "Second update rejected: %v (code %s, HTTP %d)\n", conflict, CodeOf(err), HTTPStatusOf(err))
+
+	// The second client re-reads and retries
+	second, err = service.GetUser(1)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	second.Name = "Second Writer"
+	if err := service.UpdateUser(second); err != nil {
+		return fmt.Errorf("failed to retry update: %w", err)
+	}
+	fmt.Fprintf(env, "Retried update succeeded, now at version %d\n", second.Version)
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package example3
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"practice/examples/tabletest/tabletesttest"
+)
+
+func TestCreateUser(t *testing.T) {
+	table := createUserTable(NewUserService())
+	table.Parallel = true
+	tabletesttest.Run(t, table)
+}
+
+func TestUserServiceRejectsNilUser(t *testing.T) {
+	service := NewUserService()
+	for name, call := range map[string]func(*User) error{
+		"CreateUser": service.CreateUser,
+		"UpdateUser": service.UpdateUser,
+	} {
+		err := call(nil)
+		if !errors.Is(err, ErrInvalidInput) || CodeOf(err) != CodeInvalidInput {
+			t.Errorf("%s(nil) error = %v, want %v with code %s", name, err, ErrInvalidInput, CodeInvalidInput)
+		}
+	}
+}
+
+func TestUserServiceConcurrentWriters(t *testing.T) {
+	const writers = 20
+	service := NewUserService()
+
+	// Concurrent creators of the same ID: exactly one wins
+	var wg sync.WaitGroup
+	created := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created <- service.CreateUser(&User{ID: 1, Name: "Alice", Age: 30})
+		}()
+	}
+	wg.Wait()
+	close(created)
+	if wins, losses := countResults(created, func(err error) bool { return CodeOf(err) == CodeUserDuplicateID }); wins != 1 || losses != writers-1 {
+		t.Fatalf("concurrent CreateUser: %d succeeded and %d reported a duplicate, want 1 and %d", wins, losses, writers-1)
+	}
+
+	// Concurrent updates from the same stale read: exactly one wins and
+	// every other writer gets ErrConflict
+	read, err := service.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	updated := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(age int) {
+			defer wg.Done()
+			user := *read
+			user.Age = age
+			updated <- service.UpdateUser(&user)
+		}(31 + i)
+	}
+	wg.Wait()
+	close(updated)
+	if wins, losses := countResults(updated, func(err error) bool { return errors.Is(err, ErrConflict) }); wins != 1 || losses != writers-1 {
+		t.Fatalf("concurrent UpdateUser: %d succeeded and %d conflicted, want 1 and %d", wins, losses, writers-1)
+	}
+
+	final, _ := service.GetUser(1)
+	if final.Version != read.Version+1 {
+		t.Errorf("Version = %d, want %d", final.Version, read.Version+1)
+	}
+}
+
+// countResults counts nil errors as wins and errors matching lost as
+// losses; any other error counts as neither
+func countResults(results <-chan error, lost func(error) bool) (wins, losses int) {
+	for err := range results {
+		switch {
+		case err == nil:
+			wins++
+		case lost(err):
+			losses++
+		}
+	}
+	return wins, losses
+}
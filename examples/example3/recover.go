@@ -0,0 +1,193 @@
+package example3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"time"
+
+	"practice/examples/demo"
+)
+
+// PanicError holds the value passed to panic
+type PanicError struct {
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the panic value when it is itself an error, so errors.Is
+// and errors.As can see through the panic.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// RecoverOption configures Safe, Go and RecoverMiddleware
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	now func() time.Time
+}
+
+// RecoverClock replaces time.Now for the timestamps of recovered panics
+func RecoverClock(now func() time.Time) RecoverOption {
+	return func(c *recoverConfig) {
+		c.now = now
+	}
+}
+
+func newRecoverConfig(opts []RecoverOption) recoverConfig {
+	c := recoverConfig{now: time.Now}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// recovered converts a recovered panic value into a ProcessingError. The
+// stack is always captured, regardless of SetStackCapture, since it is
+// the only record of where the panic happened.
+func recovered(operation string, value interface{}, now func() time.Time) *ProcessingError {
+	e := &ProcessingError{
+		Operation: operation,
+		Code:      CodePanic,
+		Err:       &PanicError{Value: value},
+		Timestamp: now(),
+	}
+	var pcs [maxStackDepth]uintptr
+	// Skip runtime.Callers, recovered and the deferred function, starting
+	// at the runtime's panic frames so the panic site follows.
+	n := runtime.Callers(3, pcs[:])
+	e.stack = pcs[:n:n]
+	return e.With("panic", value)
+}
+
+// Safe calls fn, converting a panic into a ProcessingError with code PANIC
+// carrying the panic value and the stack at the point of the panic.
+func Safe(operation string, fn func() error, opts ...RecoverOption) (err error) {
+	cfg := newRecoverConfig(opts)
+	defer func() {
+		if r := recover(); r != nil {
+			err = recovered(operation, r, cfg.now)
+		}
+	}()
+	return fn()
+}
+
+// Go runs fn in a new goroutine under Safe. The returned channel receives
+// fn's error, or the recovered panic, and is then closed. It is buffered,
+// so the goroutine exits even if nobody reads the result.
+func Go(operation string, fn func() error, opts ...RecoverOption) <-chan error {
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		done <- Safe(operation, fn, opts...)
+	}()
+	return done
+}
+
+// RecoverMiddleware turns a panic in next into a 500 response with a JSON
+// error body instead of crashing the server. onPanic, if non-nil, receives
+// the recovered error for logging. If next had already started its
+// response, the error body cannot be sent, so the connection is aborted
+// instead, as net/http does for an unrecovered panic. http.ErrAbortHandler
+// is passed on as is.
+func RecoverMiddleware(next http.Handler, onPanic func(*http.Request, error), opts ...RecoverOption) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w}
+		err := Safe(r.Method+" "+r.URL.Path, func() error {
+			next.ServeHTTP(tw, r)
+			return nil
+		}, opts...)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, http.ErrAbortHandler) {
+			panic(http.ErrAbortHandler)
+		}
+		if onPanic != nil {
+			onPanic(r, err)
+		}
+		if tw.started {
+			panic(http.ErrAbortHandler)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(HTTPStatusOf(err))
+		json.NewEncoder(w).Encode(map[string]string{
+			"code":    string(CodeOf(err)),
+			"message": "internal error",
+		})
+	})
+}
+
+// trackingWriter records whether a handler has started its response
+type trackingWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *trackingWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *trackingWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming handlers working behind the middleware
+func (w *trackingWriter) Flush() {
+	w.started = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RunPanicRecovery demonstrates recovering panics into errors
+func RunPanicRecovery(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	clock := RecoverClock(env.Now)
+
+	// Scenario 1: a panic in a synchronous call
+	fmt.Fprintln(env, "\nScenario 1: Recover a panic with Safe")
+	err := Safe("parse_config", func() error {
+		var settings map[string]int
+		settings["retries"] = 3 // assignment to nil map panics
+		return nil
+	}, clock)
+	fmt.Fprintf(env, "Recovered: %v (code %s)\n", err, CodeOf(err))
+
+	// Scenario 2: a panic in a goroutine
+	fmt.Fprintln(env, "\nScenario 2: Recover a panic in a goroutine")
+	backendDown := NewProcessingError("job_3", CodeUnavailable, fmt.Errorf("backend down"))
+	backendDown.Timestamp = env.Now()
+	results := []<-chan error{
+		Go("job_1", func() error { return nil }, clock),
+		Go("job_2", func() error { panic("worker exploded") }, clock),
+		Go("job_3", func() error { return backendDown }, clock),
+	}
+	for i, result := range results {
+		fmt.Fprintf(env, "Job %d: %v\n", i+1, <-result)
+	}
+
+	// Scenario 3: the full chain including the panic site
+	fmt.Fprintln(env, "\nScenario 3: Detailed panic error")
+	fmt.Fprintln(env, detailed(env, Safe("detailed", func() error { panic(ErrInvalidInput) }, clock)))
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+)
+
+func TestSafe(t *testing.T) {
+	tests := []struct {
+		name      string
+		fn        func() error
+		wantErr   error
+		wantPanic bool
+	}{
+		{name: "no error", fn: func() error { return nil }},
+		{name: "returned error", fn: func() error { return ErrNotFound }, wantErr: ErrNotFound},
+		{name: "panic with string", fn: func() error { panic("boom") }, wantPanic: true},
+		{name: "panic with error", fn: func() error { panic(ErrInvalidInput) }, wantErr: ErrInvalidInput, wantPanic: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Safe("test", tt.fn)
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Safe() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var panicErr *PanicError
+			if got := errors.As(err, &panicErr); got != tt.wantPanic {
+				t.Fatalf("Safe() recovered panic = %v, want %v (err: %v)", got, tt.wantPanic, err)
+			}
+			if !tt.wantPanic {
+				return
+			}
+
+			if code := CodeOf(err); code != CodePanic {
+				t.Errorf("CodeOf() = %s, want %s", code, CodePanic)
+			}
+			var processingErr *ProcessingError
+			if !errors.As(err, &processingErr) || len(processingErr.StackTrace()) == 0 {
+				t.Errorf("recovered panic has no stack trace")
+			}
+		})
+	}
+}
+
+func TestGoDoesNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// Results are deliberately never read
+	for i := 0; i < 100; i++ {
+		Go("leak_check", func() error { panic("boom") })
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("goroutines leaked: %d before, %d after", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGoDeliversRecoveredPanic(t *testing.T) {
+	result, ok := <-Go("deliver", func() error { panic("boom") })
+	if !ok || CodeOf(result) != CodePanic {
+		t.Fatalf("Go() result = %v, want PANIC error", result)
+	}
+	if _, ok := <-Go("deliver", func() error { return nil }); !ok {
+		t.Fatalf("Go() channel closed before delivering a result")
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	var logged error
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler exploded")
+	}), func(r *http.Request, err error) { logged = err })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if !strings.Contains(rec.Body.String(), string(CodePanic)) {
+		t.Errorf("body = %q, want code %s", rec.Body.String(), CodePanic)
+	}
+	if CodeOf(logged) != CodePanic {
+		t.Errorf("onPanic received %v, want PANIC error", logged)
+	}
+}
+
+// serveAborted serves req and returns the value the handler panicked
+// with, if any
+func serveAborted(handler http.Handler, rec *httptest.ResponseRecorder, req *http.Request) (panicked interface{}) {
+	defer func() { panicked = recover() }()
+	handler.ServeHTTP(rec, req)
+	return nil
+}
+
+func TestRecoverMiddlewareAfterWrite(t *testing.T) {
+	var logged error
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, "partial")
+		panic("handler exploded mid-response")
+	}), func(r *http.Request, err error) { logged = err })
+
+	rec := httptest.NewRecorder()
+	if panicked := serveAborted(handler, rec, httptest.NewRequest(http.MethodGet, "/users", nil)); panicked != http.ErrAbortHandler {
+		t.Errorf("ServeHTTP panicked with %v, want %v to abort the connection", panicked, http.ErrAbortHandler)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("response = %d %q %v, want the handler's partial response untouched", rec.Code, rec.Body.String(), rec.Header())
+	}
+	if CodeOf(logged) != CodePanic {
+		t.Errorf("onPanic received %v, want PANIC error", logged)
+	}
+}
+
+func TestRecoverMiddlewarePassesAbortHandler(t *testing.T) {
+	called := false
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), func(r *http.Request, err error) { called = true })
+
+	rec := httptest.NewRecorder()
+	if panicked := serveAborted(handler, rec, httptest.NewRequest(http.MethodGet, "/stream", nil)); panicked != http.ErrAbortHandler {
+		t.Errorf("ServeHTTP panicked with %v, want %v", panicked, http.ErrAbortHandler)
+	}
+	if called || rec.Body.Len() != 0 {
+		t.Errorf("onPanic called = %v, body = %q; want a deliberate abort left alone", called, rec.Body.String())
+	}
+}
+
+func TestRecoverMiddlewareFlush(t *testing.T) {
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() through the middleware error = %v", err)
+		}
+		w.(http.Flusher).Flush()
+	}), nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if !rec.Flushed {
+		t.Error("the recorder was not flushed")
+	}
+}
+
+func TestRecoverClock(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := RecoverClock(func() time.Time { return at })
+
+	err := <-Go("clocked", func() error { panic("boom") }, clock)
+	var processingErr *ProcessingError
+	if !errors.As(err, &processingErr) || !processingErr.Timestamp.Equal(at) {
+		t.Errorf("Go() error = %v, want a panic stamped %v", err, at)
+	}
+	if got := fmt.Sprintf("%+v", err); !strings.Contains(got, "recover_test.go:") {
+		t.Errorf("%%+v of a recovered panic has no panic site:\n%s", got)
+	}
+	if got := detailed(demo.New(io.Discard, demo.Deterministic()), err); strings.Contains(got, "\n\t") {
+		t.Errorf("detailed() in a deterministic run printed stack frames:\n%s", got)
+	}
+}
@@ -0,0 +1,451 @@
+package example3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"practice/examples/demo"
+)
+
+// ErrCircuitOpen is returned when a circuit breaker rejects a call
+var ErrCircuitOpen = errors.New("circuit open")
+
+// EventKind identifies what happened in a retry loop or circuit breaker
+type EventKind string
+
+// Event kinds
+const (
+	EventAttemptFailed EventKind = "attempt_failed"
+	EventRetrying      EventKind = "retrying"
+	EventGaveUp        EventKind = "gave_up"
+	EventSucceeded     EventKind = "succeeded"
+	EventStateChanged  EventKind = "state_changed"
+	EventRejected      EventKind = "rejected"
+)
+
+// Event is emitted by Retry and CircuitBreaker for observability
+type Event struct {
+	Kind      EventKind
+	Operation string
+	Attempt   int
+	Err       error
+	Delay     time.Duration
+	From      BreakerState
+	To        BreakerState
+	Time      time.Time
+}
+
+// Observer receives events. It is called synchronously, so it should not
+// block.
+type Observer func(Event)
+
+// IsCallerFault reports whether err was caused by the caller rather than by
+// the operation: invalid input, a missing entity, a version conflict or a
+// cancelled context. Repeating the same call cannot fix these.
+func IsCallerFault(err error) bool {
+	var validationErr *ValidationError
+	return errors.As(err, &validationErr) ||
+		errors.Is(err, ErrInvalidInput) ||
+		errors.Is(err, ErrNotFound) ||
+		errors.Is(err, ErrConflict) ||
+		errors.Is(err, context.Canceled)
+}
+
+// ShouldRetry is the default retry classification. Caller faults are never
+// retried; a ProcessingError is retried when its code is registered as
+// retryable, and any error with a Temporary() bool method reporting true is
+// retried as well.
+func ShouldRetry(err error) bool {
+	if err == nil || IsCallerFault(err) {
+		return false
+	}
+
+	var processingErr *ProcessingError
+	if errors.As(err, &processingErr) && IsRetryable(err) {
+		return true
+	}
+
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// RetryPolicy configures Retry. Zero fields take the defaults noted.
+type RetryPolicy struct {
+	Operation      string           // name reported in events
+	MaxAttempts    int              // default 3
+	InitialBackoff time.Duration    // default 100ms
+	MaxBackoff     time.Duration    // default 5s
+	Multiplier     float64          // default 2
+	Jitter         float64          // fraction of each delay randomised, 0..1
+	Classify       func(error) bool // default ShouldRetry
+	OnEvent        Observer
+
+	// Now stamps events; default time.Now
+	Now func() time.Time
+
+	// Sleep waits between attempts, returning ctx.Err() if ctx is done
+	// first; default waits on a timer
+	Sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 5 * time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+	if p.Classify == nil {
+		p.Classify = ShouldRetry
+	}
+	if p.Now == nil {
+		p.Now = time.Now
+	}
+	if p.Sleep == nil {
+		p.Sleep = sleep
+	}
+	return p
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// emit stamps an event with the policy's clock and sends it to OnEvent
+func (p RetryPolicy) emit(e Event) {
+	if p.OnEvent != nil {
+		e.Operation = p.Operation
+		e.Time = p.Now()
+		p.OnEvent(e)
+	}
+}
+
+// backoff returns the delay before the given retry (1-based)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		delay *= p.Multiplier
+	}
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * p.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// Retry calls fn until it succeeds, returns an error the policy does not
+// retry, runs out of attempts, or ctx is done. The returned error wraps
+// the last error from fn.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	policy = policy.withDefaults()
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn(ctx)
+		if err == nil {
+			policy.emit(Event{Kind: EventSucceeded, Attempt: attempt})
+			return nil
+		}
+		policy.emit(Event{Kind: EventAttemptFailed, Attempt: attempt, Err: err})
+
+		if !policy.Classify(err) {
+			policy.emit(Event{Kind: EventGaveUp, Attempt: attempt, Err: err})
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			policy.emit(Event{Kind: EventGaveUp, Attempt: attempt, Err: err})
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		}
+
+		delay := policy.backoff(attempt)
+		policy.emit(Event{Kind: EventRetrying, Attempt: attempt, Err: err, Delay: delay})
+		if sleepErr := policy.Sleep(ctx, delay); sleepErr != nil {
+			return fmt.Errorf("retry interrupted after %d attempts: %w", attempt, errors.Join(sleepErr, err))
+		}
+	}
+}
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+// Circuit breaker states
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerConfig configures a CircuitBreaker. Zero fields take the defaults
+// noted.
+type BreakerConfig struct {
+	Name             string
+	FailureThreshold int              // consecutive failures that open the breaker, default 5
+	OpenTimeout      time.Duration    // time spent open before probing, default 30s
+	HalfOpenMaxCalls int              // concurrent probes allowed when half-open, default 1
+	SuccessThreshold int              // probe successes needed to close, default 1
+	IsFailure        func(error) bool // default: any error that is not a caller fault
+	OnEvent          Observer
+	Now              func() time.Time // default time.Now
+}
+
+// CircuitBreaker stops calling an operation that keeps failing, giving it
+// OpenTimeout to recover before letting probe calls through.
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int
+	successes int
+	inFlight  int
+	openedAt  time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
+	}
+	if cfg.HalfOpenMaxCalls <= 0 {
+		cfg.HalfOpenMaxCalls = 1
+	}
+	if cfg.SuccessThreshold <= 0 {
+		cfg.SuccessThreshold = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return !IsCallerFault(err) }
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// State returns the current state, moving from open to half-open when the
+// open timeout has elapsed.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	return b.state
+}
+
+// Execute runs fn if the breaker allows it. A rejected call returns a
+// ProcessingError with code CIRCUIT_OPEN wrapping ErrCircuitOpen. If fn
+// panics, the call counts as a failure and the panic continues.
+func (b *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if err := b.acquire(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			b.record(true, &PanicError{Value: r})
+			panic(r)
+		}
+		b.record(err != nil && b.cfg.IsFailure(err), err)
+	}()
+	return fn(ctx)
+}
+
+// acquire admits a call or rejects it
+func (b *CircuitBreaker) acquire() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	switch {
+	case b.state == StateOpen,
+		b.state == StateHalfOpen && b.inFlight >= b.cfg.HalfOpenMaxCalls:
+		b.emit(Event{Kind: EventRejected, From: b.state, To: b.state})
+		rejected := NewProcessingError("circuit_breaker", CodeCircuitOpen, ErrCircuitOpen)
+		rejected.Timestamp = b.cfg.Now()
+		return rejected.With("breaker", b.cfg.Name)
+	}
+
+	b.inFlight++
+	return nil
+}
+
+// record updates the counters with the outcome of an admitted call
+func (b *CircuitBreaker) record(failed bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inFlight--
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.transition(StateOpen, err)
+		}
+	case StateHalfOpen:
+		if failed {
+			b.transition(StateOpen, err)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.SuccessThreshold {
+			b.transition(StateClosed, nil)
+		}
+	case StateOpen:
+		// A call admitted before the breaker opened; its outcome no longer matters
+	}
+}
+
+// refresh moves an open breaker to half-open once its timeout has passed.
+// Callers must hold b.mu.
+func (b *CircuitBreaker) refresh() {
+	if b.state == StateOpen && b.cfg.Now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.transition(StateHalfOpen, nil)
+	}
+}
+
+// transition changes state and resets counters. Callers must hold b.mu.
+func (b *CircuitBreaker) transition(to BreakerState, err error) {
+	from := b.state
+	b.state = to
+	b.failures = 0
+	b.successes = 0
+	if to == StateOpen {
+		b.openedAt = b.cfg.Now()
+	}
+	b.emit(Event{Kind: EventStateChanged, From: from, To: to, Err: err})
+}
+
+// emit stamps an event with the breaker's name and clock and sends it to
+// OnEvent. Callers must hold b.mu.
+func (b *CircuitBreaker) emit(e Event) {
+	if b.cfg.OnEvent != nil {
+		e.Operation = b.cfg.Name
+		e.Time = b.cfg.Now()
+		b.cfg.OnEvent(e)
+	}
+}
+
+// RunResilience demonstrates retries and the circuit breaker
+func RunResilience(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	ctx := context.Background()
+	wait := func(ctx context.Context, d time.Duration) error {
+		env.Sleep(d)
+		return ctx.Err()
+	}
+	unavailable := func(operation string) error {
+		e := NewProcessingError(operation, CodeUnavailable, errors.New("backend unavailable"))
+		e.Timestamp = env.Now()
+		return e
+	}
+	logEvent := func(e Event) {
+		switch e.Kind {
+		case EventStateChanged:
+			fmt.Fprintf(env, "  [%s] %s: %s -> %s\n", e.Operation, e.Kind, e.From, e.To)
+		case EventRetrying:
+			fmt.Fprintf(env, "  [%s] %s after attempt %d in %v: %v\n", e.Operation, e.Kind, e.Attempt, e.Delay, e.Err)
+		case EventRejected:
+			fmt.Fprintf(env, "  [%s] %s while %s\n", e.Operation, e.Kind, e.From)
+		default:
+			fmt.Fprintf(env, "  [%s] %s (attempt %d)\n", e.Operation, e.Kind, e.Attempt)
+		}
+	}
+
+	// Scenario 1: a transient failure is retried until it succeeds
+	fmt.Fprintln(env, "\nScenario 1: Retry a transient failure")
+	calls := 0
+	err := Retry(ctx, RetryPolicy{Operation: "flaky", InitialBackoff: 10 * time.Millisecond, OnEvent: logEvent, Now: env.Now, Sleep: wait},
+		func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return unavailable("flaky")
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("flaky operation should have succeeded: %w", err)
+	}
+	fmt.Fprintf(env, "Succeeded after %d calls\n", calls)
+
+	// Scenario 2: a validation error is never retried
+	fmt.Fprintln(env, "\nScenario 2: Do not retry a validation error")
+	service := NewUserService(WithClock(env.Now))
+	err = Retry(ctx, RetryPolicy{Operation: "create_user", InitialBackoff: 10 * time.Millisecond, OnEvent: logEvent, Now: env.Now, Sleep: wait},
+		func(ctx context.Context) error {
+			return service.CreateUser(&User{ID: 1, Name: "", Age: 30})
+		})
+	fmt.Fprintf(env, "Returned immediately: %v\n", err)
+
+	// Scenario 3: repeated failures open the breaker
+	fmt.Fprintln(env, "\nScenario 3: Circuit breaker")
+	breaker := NewCircuitBreaker(BreakerConfig{
+		Name:             "backend",
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		OnEvent:          logEvent,
+		Now:              env.Now,
+	})
+	failing := func(ctx context.Context) error {
+		return unavailable("backend_call")
+	}
+	for i := 1; i <= 3; i++ {
+		err := breaker.Execute(ctx, failing)
+		fmt.Fprintf(env, "Call %d: %v (code %s)\n", i, errors.Unwrap(err), CodeOf(err))
+	}
+
+	// Wait out the open timeout on the environment's clock
+	for breaker.State() == StateOpen {
+		env.Sleep(10 * time.Millisecond)
+	}
+	fmt.Fprintf(env, "After timeout the breaker is %s\n", breaker.State())
+	if err := breaker.Execute(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		return fmt.Errorf("probe should have succeeded: %w", err)
+	}
+	fmt.Fprintf(env, "After a successful probe the breaker is %s\n", breaker.State())
+
+	return nil
+}
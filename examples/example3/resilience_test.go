@@ -0,0 +1,403 @@
+package example3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+)
+
+// manualClock is a clock that only moves when told to
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newManualClock() *manualClock {
+	return &manualClock{now: demo.Epoch}
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// eventLog records events for later comparison
+type eventLog struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) observe(e Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+}
+
+// kinds returns the kind of every event, with the state change for
+// state_changed events
+func (l *eventLog) kinds() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var kinds []string
+	for _, e := range l.events {
+		if e.Kind == EventStateChanged {
+			kinds = append(kinds, fmt.Sprintf("%s:%s->%s", e.Kind, e.From, e.To))
+			continue
+		}
+		kinds = append(kinds, string(e.Kind))
+	}
+	return kinds
+}
+
+func unavailableErr() error {
+	return NewProcessingError("backend", CodeUnavailable, errors.New("backend unavailable"))
+}
+
+// failingTimes returns a function that fails with err n times, then
+// succeeds, counting its calls
+func failingTimes(n int, err error, calls *int) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if *calls <= n {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestRetry(t *testing.T) {
+	validation := NewUserService().CreateUser(&User{ID: 1, Name: "", Age: 30})
+
+	tests := []struct {
+		name       string
+		failures   int
+		err        error
+		wantCalls  int
+		wantErr    error
+		wantDelays []time.Duration
+		wantEvents []string
+	}{
+		{
+			name: "succeeds first time", failures: 0, err: unavailableErr(),
+			wantCalls: 1, wantEvents: []string{"succeeded"},
+		},
+		{
+			name: "retries a transient failure", failures: 2, err: unavailableErr(),
+			wantCalls: 3, wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+			wantEvents: []string{"attempt_failed", "retrying", "attempt_failed", "retrying", "succeeded"},
+		},
+		{
+			name: "gives up after max attempts", failures: 5, err: unavailableErr(),
+			wantCalls: 3, wantErr: unavailableErr(),
+			wantDelays: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+			wantEvents: []string{"attempt_failed", "retrying", "attempt_failed", "retrying", "attempt_failed", "gave_up"},
+		},
+		{
+			name: "does not retry a validation error", failures: 5, err: validation,
+			wantCalls: 1, wantErr: validation,
+			wantEvents: []string{"attempt_failed", "gave_up"},
+		},
+		{
+			name: "does not retry an unclassified error", failures: 5, err: errors.New("boom"),
+			wantCalls: 1, wantErr: errors.New("boom"),
+			wantEvents: []string{"attempt_failed", "gave_up"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newManualClock()
+			var delays []time.Duration
+			var log eventLog
+			calls := 0
+			err := Retry(context.Background(), RetryPolicy{
+				Operation: "op",
+				OnEvent:   log.observe,
+				Now:       clock.Now,
+				Sleep: func(ctx context.Context, d time.Duration) error {
+					delays = append(delays, d)
+					clock.Advance(d)
+					return nil
+				},
+			}, failingTimes(tt.failures, tt.err, &calls))
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Retry() error = %v", err)
+			}
+			if tt.wantErr != nil && (err == nil || !errors.Is(err, tt.err)) {
+				t.Fatalf("Retry() error = %v, want it to wrap %v", err, tt.err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(delays, tt.wantDelays) {
+				t.Errorf("delays = %v, want %v", delays, tt.wantDelays)
+			}
+			if got := log.kinds(); !reflect.DeepEqual(got, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", got, tt.wantEvents)
+			}
+
+			// Events carry the operation and the injected clock's time
+			var elapsed time.Duration
+			for i, e := range log.events {
+				if e.Operation != "op" || !e.Time.Equal(demo.Epoch.Add(elapsed)) {
+					t.Errorf("event %d = %+v, want operation op at %v", i, e, demo.Epoch.Add(elapsed))
+				}
+				if e.Kind == EventRetrying {
+					elapsed += e.Delay
+				}
+			}
+		})
+	}
+}
+
+func TestRetryStopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Retry(ctx, RetryPolicy{
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			cancel()
+			return ctx.Err()
+		},
+	}, failingTimes(5, unavailableErr(), &calls))
+
+	if !errors.Is(err, context.Canceled) || CodeOf(err) != CodeUnavailable {
+		t.Errorf("Retry() error = %v, want it to wrap context.Canceled and the last failure", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+
+	// A context that is already done stops Retry before the first call
+	calls = 0
+	if err := Retry(ctx, RetryPolicy{}, failingTimes(0, nil, &calls)); !errors.Is(err, context.Canceled) || calls != 0 {
+		t.Errorf("Retry() with a done context = %v after %d calls", err, calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Multiplier: 3}.withDefaults()
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second}
+	for i, d := range want {
+		if got := policy.backoff(i + 1); got != d {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, d)
+		}
+	}
+
+	defaults := RetryPolicy{}.withDefaults()
+	if defaults.MaxAttempts != 3 || defaults.InitialBackoff != 100*time.Millisecond ||
+		defaults.MaxBackoff != 5*time.Second || defaults.Multiplier != 2 {
+		t.Errorf("defaults = %+v", defaults)
+	}
+
+	jittered := RetryPolicy{InitialBackoff: time.Second, Jitter: 0.5}.withDefaults()
+	for i := 0; i < 100; i++ {
+		if got := jittered.backoff(1); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("jittered backoff = %v, want between 500ms and 1s", got)
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"retryable code", unavailableErr(), true},
+		{"circuit open", NewProcessingError("op", CodeCircuitOpen, ErrCircuitOpen), true},
+		{"wrapped retryable code", fmt.Errorf("calling: %w", unavailableErr()), true},
+		{"non-retryable code", NewProcessingError("op", CodeUnknown, errors.New("boom")), false},
+		{"validation error", &ValidationError{Field: "name", Err: ErrInvalidInput}, false},
+		{"not found", NewProcessingError("op", CodeUnavailable, ErrNotFound), false},
+		{"conflict", fmt.Errorf("update: %w", ErrConflict), false},
+		{"cancelled", context.Canceled, false},
+		{"temporary", temporaryError{true}, true},
+		{"not temporary", temporaryError{false}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldRetry(tt.err); got != tt.want {
+				t.Errorf("ShouldRetry(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+type temporaryError struct{ temporary bool }
+
+func (e temporaryError) Error() string   { return "temporary error" }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+func TestCircuitBreakerStates(t *testing.T) {
+	clock := newManualClock()
+	var log eventLog
+	breaker := NewCircuitBreaker(BreakerConfig{
+		Name:             "backend",
+		FailureThreshold: 2,
+		OpenTimeout:      time.Minute,
+		OnEvent:          log.observe,
+		Now:              clock.Now,
+	})
+	ctx := context.Background()
+	fail := func(context.Context) error { return unavailableErr() }
+	succeed := func(context.Context) error { return nil }
+
+	// A success resets the count of consecutive failures
+	breaker.Execute(ctx, fail)
+	breaker.Execute(ctx, succeed)
+	breaker.Execute(ctx, fail)
+	if got := breaker.State(); got != StateClosed {
+		t.Fatalf("after non-consecutive failures state = %s, want closed", got)
+	}
+
+	breaker.Execute(ctx, fail)
+	if got := breaker.State(); got != StateOpen {
+		t.Fatalf("after two consecutive failures state = %s, want open", got)
+	}
+
+	called := false
+	err := breaker.Execute(ctx, func(context.Context) error { called = true; return nil })
+	if called || !errors.Is(err, ErrCircuitOpen) || CodeOf(err) != CodeCircuitOpen {
+		t.Errorf("open breaker ran the call (%v) or returned %v", called, err)
+	}
+	var rejected *ProcessingError
+	if !errors.As(err, &rejected) || !rejected.Timestamp.Equal(clock.Now()) {
+		t.Errorf("rejection = %+v, want it stamped by the breaker's clock at %v", rejected, clock.Now())
+	}
+
+	clock.Advance(time.Minute - time.Second)
+	if got := breaker.State(); got != StateOpen {
+		t.Errorf("before the timeout state = %s, want open", got)
+	}
+	clock.Advance(time.Second)
+	if got := breaker.State(); got != StateHalfOpen {
+		t.Fatalf("after the timeout state = %s, want half-open", got)
+	}
+
+	// A failed probe opens the breaker again
+	breaker.Execute(ctx, fail)
+	if got := breaker.State(); got != StateOpen {
+		t.Fatalf("after a failed probe state = %s, want open", got)
+	}
+
+	clock.Advance(time.Minute)
+	if err := breaker.Execute(ctx, succeed); err != nil {
+		t.Fatalf("probe error = %v", err)
+	}
+	if got := breaker.State(); got != StateClosed {
+		t.Errorf("after a successful probe state = %s, want closed", got)
+	}
+
+	want := []string{
+		"state_changed:closed->open",
+		"rejected",
+		"state_changed:open->half-open",
+		"state_changed:half-open->open",
+		"state_changed:open->half-open",
+		"state_changed:half-open->closed",
+	}
+	if got := log.kinds(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if first := log.events[0]; first.Operation != "backend" || !first.Time.Equal(demo.Epoch) || CodeOf(first.Err) != CodeUnavailable {
+		t.Errorf("opening event = %+v, want the breaker's name, clock and the failure", first)
+	}
+}
+
+func TestCircuitBreakerIsFailure(t *testing.T) {
+	ctx := context.Background()
+
+	// By default caller faults do not count against the operation
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1})
+	for i := 0; i < 3; i++ {
+		breaker.Execute(ctx, func(context.Context) error { return ErrNotFound })
+	}
+	if got := breaker.State(); got != StateClosed {
+		t.Errorf("after caller faults state = %s, want closed", got)
+	}
+
+	// A custom IsFailure decides what counts
+	breaker = NewCircuitBreaker(BreakerConfig{
+		FailureThreshold: 1,
+		IsFailure:        func(err error) bool { return errors.Is(err, ErrNotFound) },
+	})
+	breaker.Execute(ctx, func(context.Context) error { return ErrNotFound })
+	if got := breaker.State(); got != StateOpen {
+		t.Errorf("after a failure IsFailure accepts state = %s, want open", got)
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimitsProbes(t *testing.T) {
+	clock := newManualClock()
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, Now: clock.Now})
+	ctx := context.Background()
+	breaker.Execute(ctx, func(context.Context) error { return unavailableErr() })
+	clock.Advance(time.Second)
+
+	var nested error
+	err := breaker.Execute(ctx, func(ctx context.Context) error {
+		// A second call while the probe is in flight is rejected
+		nested = breaker.Execute(ctx, func(context.Context) error { return nil })
+		return nil
+	})
+	if err != nil || !errors.Is(nested, ErrCircuitOpen) {
+		t.Errorf("probe = %v, concurrent call = %v, want the concurrent call rejected", err, nested)
+	}
+	if got := breaker.State(); got != StateClosed {
+		t.Errorf("after the probe state = %s, want closed", got)
+	}
+}
+
+func TestCircuitBreakerPanicCountsAsFailure(t *testing.T) {
+	clock := newManualClock()
+	breaker := NewCircuitBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Second, Now: clock.Now})
+	ctx := context.Background()
+
+	// executePanicking returns the value the call panicked with
+	executePanicking := func(value any) (recovered any) {
+		defer func() { recovered = recover() }()
+		breaker.Execute(ctx, func(context.Context) error { panic(value) })
+		return nil
+	}
+
+	// Even a panic with a caller-fault value counts as a failure
+	if got := executePanicking(ErrInvalidInput); got != ErrInvalidInput {
+		t.Fatalf("panic value = %v, want it to propagate", got)
+	}
+	if got := breaker.State(); got != StateOpen {
+		t.Fatalf("after a panic state = %s, want open", got)
+	}
+
+	// A panicking probe releases its slot, so the next probe is admitted
+	clock.Advance(time.Second)
+	executePanicking("probe exploded")
+	clock.Advance(time.Second)
+	if err := breaker.Execute(ctx, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("probe after a panicking probe error = %v", err)
+	}
+	if got := breaker.State(); got != StateClosed {
+		t.Errorf("after a successful probe state = %s, want closed", got)
+	}
+}
+
+func TestBreakerStateString(t *testing.T) {
+	if got := BreakerState(7).String(); got != "BreakerState(7)" {
+		t.Errorf("String() = %q", got)
+	}
+}
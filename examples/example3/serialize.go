@@ -0,0 +1,414 @@
+package example3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Node types used in serialized error trees
+const (
+	NodeProcessing       = "ProcessingError"
+	NodeValidation       = "ValidationError"
+	NodeValidationErrors = "ValidationErrors"
+	NodeJoin             = "join"
+	NodeSentinel         = "sentinel"
+	NodeError            = "error"
+)
+
+// sentinels are the errors that keep their identity across a round trip
+var sentinels = map[string]error{
+	"ErrInvalidInput": ErrInvalidInput,
+	"ErrNotFound":     ErrNotFound,
+	"ErrConflict":     ErrConflict,
+	"ErrInvalidRule":  ErrInvalidRule,
+	"ErrCircuitOpen":  ErrCircuitOpen,
+}
+
+// ErrorNode is the structured form of one error in a chain. Causes holds
+// the wrapped errors: one for ordinary wrapping, several for errors.Join
+// and ValidationErrors.
+type ErrorNode struct {
+	Type      string       `json:"type"`
+	Message   string       `json:"message"`
+	Code      Code         `json:"code,omitempty"`
+	Sentinel  string       `json:"sentinel,omitempty"`
+	Field     string       `json:"field,omitempty"`
+	Value     interface{}  `json:"value,omitempty"`
+	Operation string       `json:"operation,omitempty"`
+	Timestamp *time.Time   `json:"timestamp,omitempty"`
+	Context   []Field      `json:"context,omitempty"`
+	Stack     []string     `json:"stack,omitempty"`
+	Causes    []*ErrorNode `json:"causes,omitempty"`
+}
+
+// ErrorTree walks err, including multi-errors, and returns its structure
+func ErrorTree(err error) *ErrorNode {
+	if err == nil {
+		return nil
+	}
+
+	for name, sentinel := range sentinels {
+		if err == sentinel {
+			return &ErrorNode{Type: NodeSentinel, Message: err.Error(), Sentinel: name}
+		}
+	}
+
+	node := &ErrorNode{Type: NodeError, Message: err.Error()}
+	switch e := err.(type) {
+	case *ProcessingError:
+		ts := e.Timestamp
+		node.Type = NodeProcessing
+		node.Code = e.Code
+		node.Operation = e.Operation
+		node.Timestamp = &ts
+		node.Context = e.Fields
+		for _, frame := range e.StackTrace() {
+			node.Stack = append(node.Stack, fmt.Sprintf("%s %s:%d", frame.Function, frame.File, frame.Line))
+		}
+	case *ValidationError:
+		node.Type = NodeValidation
+		node.Code = e.Code
+		node.Field = e.Field
+		node.Value = e.Value
+	case ValidationErrors:
+		node.Type = NodeValidationErrors
+	case interface{ Unwrap() []error }:
+		node.Type = NodeJoin
+	}
+
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, cause := range e.Unwrap() {
+			if cause != nil {
+				node.Causes = append(node.Causes, ErrorTree(cause))
+			}
+		}
+	case interface{ Unwrap() error }:
+		if cause := e.Unwrap(); cause != nil {
+			node.Causes = []*ErrorNode{ErrorTree(cause)}
+		}
+	}
+	return node
+}
+
+// MarshalErrorJSON encodes the full chain of err as JSON
+func MarshalErrorJSON(err error) ([]byte, error) {
+	return json.Marshal(ErrorTree(err))
+}
+
+// UnmarshalErrorJSON rebuilds an error encoded by MarshalErrorJSON. The
+// result matches errors.Is against ErrInvalidInput and ErrNotFound, and
+// errors.As against *ProcessingError and *ValidationError, just like the
+// original. Stack traces are not restored. null decodes to a nil error.
+func UnmarshalErrorJSON(data []byte) (error, error) {
+	var node *ErrorNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return node.Decode()
+}
+
+// Decode rebuilds the error described by the node
+func (n *ErrorNode) Decode() (error, error) {
+	if n == nil {
+		return nil, nil
+	}
+
+	causes := make([]error, 0, len(n.Causes))
+	for _, child := range n.Causes {
+		cause, err := child.Decode()
+		if err != nil {
+			return nil, err
+		}
+		causes = append(causes, cause)
+	}
+
+	var cause error
+	if len(causes) == 1 {
+		cause = causes[0]
+	}
+
+	switch n.Type {
+	case NodeSentinel:
+		sentinel, ok := sentinels[n.Sentinel]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown sentinel %q", ErrInvalidInput, n.Sentinel)
+		}
+		return sentinel, nil
+	case NodeProcessing:
+		e := &ProcessingError{
+			Operation: n.Operation,
+			Code:      n.Code,
+			Err:       cause,
+			Fields:    n.Context,
+		}
+		if n.Timestamp != nil {
+			e.Timestamp = *n.Timestamp
+		}
+		return e, nil
+	case NodeValidation:
+		return &ValidationError{Field: n.Field, Value: n.Value, Code: n.Code, Err: cause}, nil
+	case NodeValidationErrors:
+		errs := make(ValidationErrors, 0, len(causes))
+		for _, c := range causes {
+			ve, ok := c.(*ValidationError)
+			if !ok {
+				return nil, fmt.Errorf("%w: ValidationErrors can only hold ValidationError, got %T", ErrInvalidInput, c)
+			}
+			errs = append(errs, ve)
+		}
+		return errs, nil
+	case NodeJoin:
+		return &decodedError{msg: n.Message, causes: causes}, nil
+	case NodeError:
+		if len(causes) > 1 {
+			return &decodedError{msg: n.Message, causes: causes}, nil
+		}
+		return &decodedError{msg: n.Message, cause: cause}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown error node type %q", ErrInvalidInput, n.Type)
+	}
+}
+
+// decodedError stands in for an error type that cannot be reconstructed,
+// keeping its message and its place in the chain.
+type decodedError struct {
+	msg    string
+	cause  error
+	causes []error
+}
+
+func (e *decodedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the wrapped errors; errors.Is and errors.As use it
+func (e *decodedError) Unwrap() []error {
+	if e.causes != nil {
+		return e.causes
+	}
+	if e.cause != nil {
+		return []error{e.cause}
+	}
+	return nil
+}
+
+// MarshalErrorLogfmt encodes the full chain of err as logfmt key/value
+// pairs. Nested errors use dotted keys: err.cause.* for a single wrapped
+// error and err.causes.N.* for multi-errors. Context keys are
+// percent-encoded where they would otherwise break the key.
+func MarshalErrorLogfmt(err error) string {
+	var b strings.Builder
+	writeLogfmtNode(&b, "err", ErrorTree(err))
+	return b.String()
+}
+
+func writeLogfmtNode(b *strings.Builder, prefix string, n *ErrorNode) {
+	if n == nil {
+		return
+	}
+
+	pair := func(key, value string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(prefix + "." + key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(value))
+	}
+
+	pair("type", n.Type)
+	pair("msg", n.Message)
+	if n.Code != "" {
+		pair("code", string(n.Code))
+	}
+	if n.Sentinel != "" {
+		pair("sentinel", n.Sentinel)
+	}
+	if n.Operation != "" {
+		pair("op", n.Operation)
+	}
+	if n.Timestamp != nil {
+		pair("time", n.Timestamp.Format(time.RFC3339Nano))
+	}
+	if n.Type == NodeValidation {
+		pair("field", n.Field)
+		pair("value", fmt.Sprint(n.Value))
+	}
+	for _, f := range n.Context {
+		pair("ctx."+escapeLogfmtKey(f.Key), fmt.Sprint(f.Value))
+	}
+
+	if len(n.Causes) == 1 && n.Type != NodeJoin && n.Type != NodeValidationErrors {
+		writeLogfmtNode(b, prefix+".cause", n.Causes[0])
+		return
+	}
+	for i, child := range n.Causes {
+		writeLogfmtNode(b, prefix+".causes."+strconv.Itoa(i), child)
+	}
+}
+
+// logfmtValue quotes v when it cannot appear bare in logfmt
+func logfmtValue(v string) string {
+	if v == "" {
+		return `""`
+	}
+	for _, r := range v {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(v)
+		}
+	}
+	return v
+}
+
+// escapeLogfmtKey percent-encodes the bytes of a context key that cannot
+// appear in a bare logfmt key, and '%' itself
+func escapeLogfmtKey(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c <= ' ' || c == '=' || c == '"' || c == '%' || c >= 0x7f {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// UnmarshalErrorLogfmt rebuilds an error encoded by MarshalErrorLogfmt.
+// Values decode as strings, since logfmt carries no types. An empty line
+// decodes to a nil error, as MarshalErrorLogfmt encodes nil.
+func UnmarshalErrorLogfmt(line string) (error, error) {
+	if strings.TrimSpace(line) == "" {
+		return nil, nil
+	}
+	pairs, order, err := parseLogfmt(line)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := readLogfmtNode(pairs, order, "err")
+	if err != nil {
+		return nil, err
+	}
+	return node.Decode()
+}
+
+func readLogfmtNode(pairs map[string]string, order []string, prefix string) (*ErrorNode, error) {
+	typ, ok := pairs[prefix+".type"]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s.type", ErrInvalidInput, prefix)
+	}
+
+	n := &ErrorNode{
+		Type:      typ,
+		Message:   pairs[prefix+".msg"],
+		Code:      Code(pairs[prefix+".code"]),
+		Sentinel:  pairs[prefix+".sentinel"],
+		Field:     pairs[prefix+".field"],
+		Operation: pairs[prefix+".op"],
+	}
+	if v, ok := pairs[prefix+".value"]; ok {
+		n.Value = v
+	}
+	if v, ok := pairs[prefix+".time"]; ok {
+		ts, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s.time: %v", ErrInvalidInput, prefix, err)
+		}
+		n.Timestamp = &ts
+	}
+
+	ctxPrefix := prefix + ".ctx."
+	for _, key := range order {
+		if name, found := strings.CutPrefix(key, ctxPrefix); found {
+			name, err := url.PathUnescape(name)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidInput, key, err)
+			}
+			n.Context = append(n.Context, Field{Key: name, Value: pairs[key]})
+		}
+	}
+
+	if _, ok := pairs[prefix+".cause.type"]; ok {
+		child, err := readLogfmtNode(pairs, order, prefix+".cause")
+		if err != nil {
+			return nil, err
+		}
+		n.Causes = []*ErrorNode{child}
+		return n, nil
+	}
+	for i := 0; ; i++ {
+		childPrefix := prefix + ".causes." + strconv.Itoa(i)
+		if _, ok := pairs[childPrefix+".type"]; !ok {
+			break
+		}
+		child, err := readLogfmtNode(pairs, order, childPrefix)
+		if err != nil {
+			return nil, err
+		}
+		n.Causes = append(n.Causes, child)
+	}
+	return n, nil
+}
+
+// parseLogfmt splits a logfmt line into pairs, also returning the keys in
+// the order they appeared.
+func parseLogfmt(line string) (map[string]string, []string, error) {
+	pairs := make(map[string]string)
+	var order []string
+
+	for i := 0; i < len(line); {
+		if line[i] == ' ' {
+			i++
+			continue
+		}
+
+		eq := strings.IndexByte(line[i:], '=')
+		if eq < 0 {
+			return nil, nil, fmt.Errorf("%w: logfmt key without value at offset %d", ErrInvalidInput, i)
+		}
+		key := line[i : i+eq]
+		i += eq + 1
+
+		var value string
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(line) {
+				return nil, nil, fmt.Errorf("%w: unterminated quote for %s", ErrInvalidInput, key)
+			}
+			unquoted, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: bad quoted value for %s: %v", ErrInvalidInput, key, err)
+			}
+			value = unquoted
+			i = end + 1
+		} else {
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+			value = line[i : i+end]
+			i += end
+		}
+
+		if _, dup := pairs[key]; !dup {
+			order = append(order, key)
+		}
+		pairs[key] = value
+	}
+
+	return pairs, order, nil
+}
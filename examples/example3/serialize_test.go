@@ -0,0 +1,210 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleChain builds an error chain using every node type
+func sampleChain() error {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	validation := ValidationErrors{
+		{Field: "name", Value: "", Code: "USER_NAME_EMPTY", Err: fmt.Errorf("%w: cannot be empty", ErrInvalidInput)},
+		{Field: "age", Value: "-1", Code: "USER_AGE_OUT_OF_RANGE", Err: errors.New("must be at least 0")},
+	}
+	lookup := &ProcessingError{Operation: "get_user", Code: CodeUserNotFound, Err: ErrNotFound, Timestamp: at}
+	return (&ProcessingError{
+		Operation: "create_user",
+		Code:      CodeInvalidInput,
+		Err:       fmt.Errorf("saving: %w", errors.Join(validation, lookup)),
+		Timestamp: at,
+	}).With("user_id", "7").With("note", `say "hi" x=1`).With(`odd key="a=b" 100%`, "v")
+}
+
+func TestErrorRoundTrip(t *testing.T) {
+	SetStackCapture(false)
+	t.Cleanup(func() { SetStackCapture(true) })
+
+	codecs := []struct {
+		name      string
+		roundTrip func(error) (error, error)
+	}{
+		{"json", func(err error) (error, error) {
+			data, marshalErr := MarshalErrorJSON(err)
+			if marshalErr != nil {
+				return nil, marshalErr
+			}
+			return UnmarshalErrorJSON(data)
+		}},
+		{"logfmt", func(err error) (error, error) {
+			return UnmarshalErrorLogfmt(MarshalErrorLogfmt(err))
+		}},
+	}
+
+	for _, codec := range codecs {
+		t.Run(codec.name, func(t *testing.T) {
+			original := sampleChain()
+			decoded, err := codec.roundTrip(original)
+			if err != nil {
+				t.Fatalf("round trip error = %v", err)
+			}
+
+			if decoded.Error() != original.Error() {
+				t.Errorf("Error() =\n%s\nwant\n%s", decoded.Error(), original.Error())
+			}
+			for _, sentinel := range []error{ErrInvalidInput, ErrNotFound} {
+				if !errors.Is(decoded, sentinel) {
+					t.Errorf("errors.Is(decoded, %v) = false", sentinel)
+				}
+			}
+			if errors.Is(decoded, ErrConflict) {
+				t.Errorf("errors.Is(decoded, %v) = true for a sentinel that was not in the chain", ErrConflict)
+			}
+
+			var processingErr *ProcessingError
+			if !errors.As(decoded, &processingErr) {
+				t.Fatal("errors.As(decoded, *ProcessingError) = false")
+			}
+			wantFields := []Field{{Key: "user_id", Value: "7"}, {Key: "note", Value: `say "hi" x=1`}, {Key: `odd key="a=b" 100%`, Value: "v"}}
+			if processingErr.Operation != "create_user" || processingErr.Code != CodeInvalidInput ||
+				!reflect.DeepEqual(processingErr.Fields, wantFields) || !processingErr.Timestamp.Equal(sampleChain().(*ProcessingError).Timestamp) {
+				t.Errorf("decoded ProcessingError = %+v", processingErr)
+			}
+
+			var validation ValidationErrors
+			if !errors.As(decoded, &validation) {
+				t.Fatal("errors.As(decoded, ValidationErrors) = false")
+			}
+			if got := validation.Fields(); !reflect.DeepEqual(got, []string{"name", "age"}) {
+				t.Errorf("ValidationErrors.Fields() = %v", got)
+			}
+			if validation[1].Code != "USER_AGE_OUT_OF_RANGE" {
+				t.Errorf("ValidationError code = %s", validation[1].Code)
+			}
+			if CodeOf(decoded) != CodeInvalidInput {
+				t.Errorf("CodeOf(decoded) = %s, want %s", CodeOf(decoded), CodeInvalidInput)
+			}
+		})
+	}
+}
+
+func TestErrorRoundTripSentinel(t *testing.T) {
+	for _, sentinel := range sentinels {
+		decoded, err := UnmarshalErrorLogfmt(MarshalErrorLogfmt(sentinel))
+		if err != nil || decoded != sentinel {
+			t.Errorf("logfmt round trip of %v = %v, %v; want the sentinel itself", sentinel, decoded, err)
+		}
+		data, _ := MarshalErrorJSON(sentinel)
+		decoded, err = UnmarshalErrorJSON(data)
+		if err != nil || decoded != sentinel {
+			t.Errorf("json round trip of %v = %v, %v; want the sentinel itself", sentinel, decoded, err)
+		}
+	}
+}
+
+func TestErrorRoundTripNil(t *testing.T) {
+	if decoded, err := UnmarshalErrorLogfmt(MarshalErrorLogfmt(nil)); decoded != nil || err != nil {
+		t.Errorf("logfmt round trip of nil = %v, %v; want nil, nil", decoded, err)
+	}
+	data, err := MarshalErrorJSON(nil)
+	if err != nil {
+		t.Fatalf("MarshalErrorJSON(nil) error = %v", err)
+	}
+	if decoded, err := UnmarshalErrorJSON(data); decoded != nil || err != nil {
+		t.Errorf("json round trip of nil = %v, %v; want nil, nil", decoded, err)
+	}
+}
+
+func TestMarshalErrorLogfmtEscaping(t *testing.T) {
+	SetStackCapture(false)
+	t.Cleanup(func() { SetStackCapture(true) })
+
+	values := []string{
+		"plain",
+		"",
+		"with space",
+		`with "quotes"`,
+		"key=value",
+		"tab\there",
+		"new\nline",
+		`back\slash`,
+		"café",
+	}
+	for _, value := range values {
+		err := NewProcessingError("op", CodeUnknown, errors.New(value)).With("v", value)
+		line := MarshalErrorLogfmt(err)
+
+		decoded, decodeErr := UnmarshalErrorLogfmt(line)
+		if decodeErr != nil {
+			t.Errorf("UnmarshalErrorLogfmt(%s) error = %v", line, decodeErr)
+			continue
+		}
+		var processingErr *ProcessingError
+		if !errors.As(decoded, &processingErr) || processingErr.Fields[0].Value != value || processingErr.Err.Error() != value {
+			t.Errorf("value %q did not survive the round trip through %s", value, line)
+		}
+	}
+
+	line := MarshalErrorLogfmt(NewProcessingError("op", CodeUnknown, errors.New("x")).With("note", `say "hi" x=1`))
+	if want := `err.ctx.note="say \"hi\" x=1"`; !strings.Contains(line, want) {
+		t.Errorf("MarshalErrorLogfmt() = %s, want it to contain %s", line, want)
+	}
+
+	line = MarshalErrorLogfmt(NewProcessingError("op", CodeUnknown, errors.New("x")).With(`a b="c"%`, "v"))
+	if want := `err.ctx.a%20b%3D%22c%22%25=v`; !strings.Contains(line, want) {
+		t.Errorf("MarshalErrorLogfmt() = %s, want the context key escaped as %s", line, want)
+	}
+}
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"", `""`},
+		{"a b", `"a b"`},
+		{`a"b`, `"a\"b"`},
+		{"a=b", `"a=b"`},
+		{"a\tb", `"a\tb"`},
+		{"\x00", `"\x00"`},
+		{"café", "café"},
+	}
+	for _, tt := range tests {
+		if got := logfmtValue(tt.value); got != tt.want {
+			t.Errorf("logfmtValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalErrorRejectsMalformedInput(t *testing.T) {
+	jsonInputs := []string{
+		`not json`,
+		`{"type":"mystery","message":"x"}`,
+		`{"type":"sentinel","message":"x","sentinel":"ErrMystery"}`,
+		`{"type":"ValidationErrors","message":"x","causes":[{"type":"error","message":"y"}]}`,
+	}
+	for _, input := range jsonInputs {
+		if _, err := UnmarshalErrorJSON([]byte(input)); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("UnmarshalErrorJSON(%s) error = %v, want %v", input, err, ErrInvalidInput)
+		}
+	}
+
+	logfmtInputs := []string{
+		`err.msg=x`,
+		`err.type=error err.msg="unterminated`,
+		`err.type=error dangling`,
+		`err.type=error err.msg="bad \q escape"`,
+		`err.type=ProcessingError err.msg=x err.time=yesterday`,
+		`err.type=ProcessingError err.msg=x err.ctx.bad%zz=v`,
+	}
+	for _, input := range logfmtInputs {
+		if _, err := UnmarshalErrorLogfmt(input); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("UnmarshalErrorLogfmt(%s) error = %v, want %v", input, err, ErrInvalidInput)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"practice/examples/demo"
+)
+
+// maxStackDepth bounds the number of frames recorded per error
+const maxStackDepth = 32
+
+var stackCapture atomic.Bool
+
+func init() {
+	stackCapture.Store(true)
+}
+
+// SetStackCapture turns caller stack capture in NewProcessingError on or
+// off. Capturing costs a runtime.Callers walk per error, so hot paths that
+// produce many expected errors may want it disabled.
+func SetStackCapture(enabled bool) {
+	stackCapture.Store(enabled)
+}
+
+// Field is a key/value pair of context attached to a ProcessingError
+type Field struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// NewProcessingError creates a ProcessingError stamped with the current
+// time and, unless disabled with SetStackCapture, the caller's stack.
+func NewProcessingError(operation string, code Code, err error) *ProcessingError {
+	e := &ProcessingError{
+		Operation: operation,
+		Code:      code,
+		Err:       err,
+		Timestamp: time.Now(),
+	}
+	if stackCapture.Load() {
+		var pcs [maxStackDepth]uintptr
+		// Skip runtime.Callers and NewProcessingError itself
+		n := runtime.Callers(2, pcs[:])
+		e.stack = pcs[:n:n]
+	}
+	return e
+}
+
+// With attaches a context field and returns the error for chaining
+func (e *ProcessingError) With(key string, value interface{}) *ProcessingError {
+	e.Fields = append(e.Fields, Field{Key: key, Value: value})
+	return e
+}
+
+// StackTrace returns the frames captured when the error was created, or
+// nil if capture was disabled.
+func (e *ProcessingError) StackTrace() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+
+	frames := runtime.CallersFrames(e.stack)
+	var trace []runtime.Frame
+	for {
+		frame, more := frames.Next()
+		trace = append(trace, frame)
+		if !more {
+			break
+		}
+	}
+	return trace
+}
+
+// Format implements fmt.Formatter. %v and %s print the error message;
+// %+v prints every error in the chain with its code, fields and stack.
+func (e *ProcessingError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		writeChain(s, e, true)
+	case verb == 'v' || verb == 's':
+		io.WriteString(s, e.Error())
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(%T)", verb, e)
+	}
+}
+
+// detailed formats err like %+v. Deterministic runs leave out the stack
+// frames, whose paths and line numbers differ between machines.
+func detailed(env *demo.Env, err error) string {
+	var b strings.Builder
+	writeChain(&b, err, !env.Deterministic)
+	return b.String()
+}
+
+// writeChain prints err and each error it wraps, one per block, with the
+// stack of every ProcessingError when stacks is set
+func writeChain(w io.Writer, err error, stacks bool) {
+	for depth := 0; err != nil; depth++ {
+		if depth > 0 {
+			io.WriteString(w, "\ncaused by: ")
+		}
+
+		var next error
+		switch e := err.(type) {
+		case *ProcessingError:
+			fmt.Fprintf(w, "%s: processing error during %s", e.Timestamp.Format(time.RFC3339Nano), e.Operation)
+			if e.Code != "" {
+				fmt.Fprintf(w, " [%s]", e.Code)
+			}
+			for _, f := range e.Fields {
+				fmt.Fprintf(w, " %s=%v", f.Key, f.Value)
+			}
+			if stacks {
+				for _, frame := range e.StackTrace() {
+					fmt.Fprintf(w, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+				}
+			}
+			next = e.Err
+		case *ValidationError:
+			fmt.Fprintf(w, "validation failed for field %s with value %v", e.Field, e.Value)
+			if e.Code != "" {
+				fmt.Fprintf(w, " [%s]", e.Code)
+			}
+			next = e.Err
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				io.WriteString(w, "\n  - ")
+				io.WriteString(w, inner.Error())
+			}
+			return
+		default:
+			io.WriteString(w, err.Error())
+			next = errors.Unwrap(err)
+		}
+		err = next
+	}
+}
@@ -0,0 +1,111 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewProcessingErrorCapturesStack(t *testing.T) {
+	err := NewProcessingError("op", CodeUnknown, ErrNotFound)
+
+	trace := err.StackTrace()
+	if len(trace) == 0 {
+		t.Fatal("StackTrace() is empty")
+	}
+	if got := trace[0].Function; !strings.HasSuffix(got, ".TestNewProcessingErrorCapturesStack") {
+		t.Errorf("first frame = %s, want the caller of NewProcessingError", got)
+	}
+	if !strings.HasSuffix(trace[0].File, "stack_test.go") || trace[0].Line == 0 {
+		t.Errorf("first frame location = %s:%d", trace[0].File, trace[0].Line)
+	}
+	if len(trace) > maxStackDepth {
+		t.Errorf("StackTrace() has %d frames, want at most %d", len(trace), maxStackDepth)
+	}
+}
+
+func TestSetStackCapture(t *testing.T) {
+	SetStackCapture(false)
+	t.Cleanup(func() { SetStackCapture(true) })
+
+	if trace := NewProcessingError("op", CodeUnknown, ErrNotFound).StackTrace(); trace != nil {
+		t.Errorf("StackTrace() with capture disabled = %v, want nil", trace)
+	}
+
+	SetStackCapture(true)
+	if trace := NewProcessingError("op", CodeUnknown, ErrNotFound).StackTrace(); len(trace) == 0 {
+		t.Error("StackTrace() with capture re-enabled is empty")
+	}
+}
+
+func TestProcessingErrorWith(t *testing.T) {
+	err := NewProcessingError("op", CodeUnknown, ErrNotFound)
+	if got := err.With("user_id", 7).With("attempt", "2"); got != err {
+		t.Error("With() did not return the error it was called on")
+	}
+
+	want := []Field{{Key: "user_id", Value: 7}, {Key: "attempt", Value: "2"}}
+	if !reflect.DeepEqual(err.Fields, want) {
+		t.Errorf("Fields = %v, want %v", err.Fields, want)
+	}
+}
+
+func TestProcessingErrorFormat(t *testing.T) {
+	SetStackCapture(false)
+	t.Cleanup(func() { SetStackCapture(true) })
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	validation := ValidationErrors{
+		{Field: "name", Value: "", Code: "USER_NAME_EMPTY", Err: errors.New("cannot be empty")},
+		{Field: "age", Value: -1, Err: errors.New("must be at least 0")},
+	}
+	inner := &ProcessingError{Operation: "validate", Err: validation, Timestamp: at}
+	outer := &ProcessingError{Operation: "create_user", Code: CodeInvalidInput, Err: fmt.Errorf("saving: %w", inner), Timestamp: at}
+	outer.With("user_id", 7)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%v", outer.Error()},
+		{"%s", outer.Error()},
+		{"%q", fmt.Sprintf("%q", outer.Error())},
+		{"%d", "%!d(*example3.ProcessingError)"},
+		{"%+v", strings.Join([]string{
+			"2024-01-02T03:04:05.000000006Z: processing error during create_user [INVALID_INPUT] user_id=7",
+			"caused by: saving: " + inner.Error(),
+			"caused by: 2024-01-02T03:04:05.000000006Z: processing error during validate",
+			"caused by: ",
+			"  - " + validation[0].Error(),
+			"  - " + validation[1].Error(),
+		}, "\n")},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprintf(tt.format, outer); got != tt.want {
+			t.Errorf("Sprintf(%q) =\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+
+	// A single validation error prints its field, value and code
+	single := &ProcessingError{Operation: "op", Err: validation[0], Timestamp: at}
+	want := "2024-01-02T03:04:05.000000006Z: processing error during op\n" +
+		"caused by: validation failed for field name with value  [USER_NAME_EMPTY]\n" +
+		"caused by: cannot be empty"
+	if got := fmt.Sprintf("%+v", single); got != want {
+		t.Errorf("Sprintf(%%+v) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestProcessingErrorFormatPrintsStack(t *testing.T) {
+	got := fmt.Sprintf("%+v", NewProcessingError("op", CodeUnknown, ErrNotFound))
+
+	if !strings.Contains(got, ".TestProcessingErrorFormatPrintsStack\n\t\t") || !strings.Contains(got, "stack_test.go:") {
+		t.Errorf("Sprintf(%%+v) has no stack frame for the caller:\n%s", got)
+	}
+	if !strings.HasSuffix(got, "\ncaused by: "+ErrNotFound.Error()) {
+		t.Errorf("Sprintf(%%+v) does not end with the cause:\n%s", got)
+	}
+}
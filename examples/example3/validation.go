@@ -0,0 +1,81 @@
+package example3
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ValidationErrors collects every field violation found while validating a
+// value. It follows errors.Join semantics: Unwrap exposes each
+// *ValidationError, so errors.Is and errors.As see all of them.
+// Violations are kept in the order they were found.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Fields returns the names of the invalid fields in order
+func (e ValidationErrors) Fields() []string {
+	fields := make([]string, len(e))
+	for i, err := range e {
+		fields[i] = err.Field
+	}
+	return fields
+}
+
+// fieldViolation is the API representation of a single ValidationError
+type fieldViolation struct {
+	Field   string      `json:"field"`
+	Value   interface{} `json:"value"`
+	Code    Code        `json:"code,omitempty"`
+	Message string      `json:"message"`
+}
+
+// MarshalJSON renders the violations as an API error body
+func (e ValidationErrors) MarshalJSON() ([]byte, error) {
+	violations := make([]fieldViolation, len(e))
+	for i, err := range e {
+		violations[i] = fieldViolation{
+			Field: err.Field,
+			Value: err.Value,
+			Code:  err.Code,
+		}
+		if err.Err != nil {
+			violations[i].Message = err.Err.Error()
+		}
+	}
+
+	return json.Marshal(struct {
+		Code   Code             `json:"code"`
+		Errors []fieldViolation `json:"errors"`
+	}{
+		Code:   CodeInvalidInput,
+		Errors: violations,
+	})
+}
+
+// add appends a violation to the collection
+func (e *ValidationErrors) add(err *ValidationError) {
+	*e = append(*e, err)
+}
+
+// err returns the collection as an error, or nil when nothing was recorded
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
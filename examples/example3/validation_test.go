@@ -0,0 +1,75 @@
+package example3
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func sampleViolations() ValidationErrors {
+	return ValidationErrors{
+		{Field: "name", Value: "", Code: CodeUserNameEmpty, Err: errors.New("cannot be empty")},
+		{Field: "age", Value: 200, Code: CodeUserAgeRange, Err: &VersionConflictError{ID: 1}},
+		{Field: "email", Value: nil},
+	}
+}
+
+func TestValidationErrorsUnwrap(t *testing.T) {
+	errs := sampleViolations()
+	var err error = errs
+
+	unwrapped := errs.Unwrap()
+	if len(unwrapped) != len(errs) {
+		t.Fatalf("Unwrap() returned %d errors, want %d", len(unwrapped), len(errs))
+	}
+	for i := range errs {
+		if unwrapped[i] != error(errs[i]) {
+			t.Errorf("Unwrap()[%d] = %v, want %v", i, unwrapped[i], errs[i])
+		}
+	}
+
+	// errors.Is and errors.As see every violation, not just the first
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("errors.Is(%v) = false, want it to reach the second violation's cause", ErrConflict)
+	}
+	var conflict *VersionConflictError
+	if !errors.As(err, &conflict) || conflict.ID != 1 {
+		t.Errorf("errors.As(*VersionConflictError) = %v", conflict)
+	}
+	var first *ValidationError
+	if !errors.As(err, &first) || first.Field != "name" {
+		t.Errorf("errors.As(*ValidationError) = %v, want the first violation", first)
+	}
+}
+
+func TestValidationErrorsError(t *testing.T) {
+	errs := sampleViolations()
+	want := errs[0].Error() + "\n" + errs[1].Error() + "\n" + errs[2].Error()
+	if got := errs.Error(); got != want {
+		t.Errorf("Error() =\n%s\nwant\n%s", got, want)
+	}
+	if got := errs.Fields(); !reflect.DeepEqual(got, []string{"name", "age", "email"}) {
+		t.Errorf("Fields() = %v", got)
+	}
+
+	// An empty collection is not an error
+	if err := (ValidationErrors{}).err(); err != nil {
+		t.Errorf("empty ValidationErrors.err() = %v, want nil", err)
+	}
+}
+
+func TestValidationErrorsMarshalJSON(t *testing.T) {
+	data, err := json.Marshal(sampleViolations())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := `{"code":"INVALID_INPUT","errors":[` +
+		`{"field":"name","value":"","code":"USER_NAME_EMPTY","message":"cannot be empty"},` +
+		`{"field":"age","value":200,"code":"USER_AGE_OUT_OF_RANGE","message":"version conflict: user 1 is at version 0, update was based on version 0"},` +
+		`{"field":"email","value":null,"message":""}]}`
+	if string(data) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", data, want)
+	}
+}
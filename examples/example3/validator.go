@@ -0,0 +1,483 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidRule is returned when a validate tag cannot be parsed or names
+// an unknown rule. It indicates a programming error, not bad input.
+var ErrInvalidRule = errors.New("invalid validation rule")
+
+// RuleFunc checks a single field value. op is the comparison operator from
+// the tag ("=", "<=", ">=", "<", ">", "!=" or "" for bare rules) and param is
+// the text after it. A non-nil error describes why the value is invalid.
+// Tag arguments the rule cannot use are reported with ErrInvalidRule for
+// any value, since each rule is tried on the field type's zero value when
+// the struct is first seen.
+type RuleFunc func(v reflect.Value, op, param string) error
+
+// Validator validates structs using `validate` struct tags, e.g.
+//
+//	Age  int    `json:"age" validate:"min=0,max=150" code:"USER_AGE_OUT_OF_RANGE"`
+//	Name string `json:"name" validate:"required,len<=64"`
+//
+// Fields are named by their json tag. The optional code tag sets the Code
+// of the resulting *ValidationError, either for every rule on the field or
+// per rule, as in `code:"required=USER_NAME_EMPTY"`. Nested structs,
+// pointers to structs and slices of structs are validated recursively.
+// Parsed tags are cached per type, so reflection over tags happens once, and
+// a malformed tag fails the first validation of its type.
+type Validator struct {
+	mu    sync.RWMutex
+	rules map[string]RuleFunc
+	plans sync.Map // reflect.Type -> *typePlan
+}
+
+// NewValidator creates a validator with the built-in rules registered:
+// required, min, max, len and oneof.
+func NewValidator() *Validator {
+	return &Validator{
+		rules: map[string]RuleFunc{
+			"required": ruleRequired,
+			"min":      ruleMin,
+			"max":      ruleMax,
+			"len":      ruleLen,
+			"oneof":    ruleOneOf,
+		},
+	}
+}
+
+var defaultValidator = NewValidator()
+
+// Validate validates v with the default validator
+func Validate(v interface{}) error {
+	return defaultValidator.Validate(v)
+}
+
+// RegisterRule adds a custom rule to the default validator
+func RegisterRule(name string, fn RuleFunc) error {
+	return defaultValidator.RegisterRule(name, fn)
+}
+
+// RegisterRule adds a custom rule. Rules must be registered before the
+// first validation of a type that uses them.
+func (v *Validator) RegisterRule(name string, fn RuleFunc) error {
+	if !isRuleName(name) || fn == nil {
+		return fmt.Errorf("%w: bad rule name %q", ErrInvalidRule, name)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, exists := v.rules[name]; exists {
+		return fmt.Errorf("%w: rule %s already registered", ErrInvalidRule, name)
+	}
+	v.rules[name] = fn
+	return nil
+}
+
+// Validate checks every tagged field of v, which must be a struct or a
+// pointer to one. It returns ValidationErrors listing each invalid field in
+// declaration order, or nil when v is valid.
+func (v *Validator) Validate(value interface{}) error {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("%w: cannot validate nil %T", ErrInvalidInput, value)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: cannot validate %T, want struct", ErrInvalidInput, value)
+	}
+
+	var errs ValidationErrors
+	if err := v.validateStruct(rv, "", &errs); err != nil {
+		return err
+	}
+	return errs.err()
+}
+
+// typePlan is the parsed form of a struct type's validate tags
+type typePlan struct {
+	fields []fieldPlan
+}
+
+// fieldPlan describes how to validate a single struct field
+type fieldPlan struct {
+	index int
+	name  string
+	codes map[string]Code // rule name -> code, "" for the field default
+	rules []boundRule
+	dive  bool
+}
+
+// boundRule is a rule with its tag arguments resolved
+type boundRule struct {
+	name  string
+	op    string
+	param string
+	fn    RuleFunc
+}
+
+func (v *Validator) plan(t reflect.Type) (*typePlan, error) {
+	if p, ok := v.plans.Load(t); ok {
+		return p.(*typePlan), nil
+	}
+
+	p := &typePlan{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fp := fieldPlan{
+			index: i,
+			name:  fieldName(f),
+			codes: parseCodes(f.Tag.Get("code")),
+			dive:  containsStruct(f.Type),
+		}
+
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules, err := v.parseTag(tag)
+			if err == nil {
+				err = checkArgs(f.Type, rules)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+			}
+			fp.rules = rules
+		}
+
+		if len(fp.rules) > 0 || fp.dive {
+			p.fields = append(p.fields, fp)
+		}
+	}
+
+	// A concurrent caller may have stored the same plan; either copy is fine.
+	actual, _ := v.plans.LoadOrStore(t, p)
+	return actual.(*typePlan), nil
+}
+
+func (v *Validator) parseTag(tag string) ([]boundRule, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	var rules []boundRule
+	for _, token := range strings.Split(tag, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		name, op, param := splitRule(token)
+		fn, ok := v.rules[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown rule %q", ErrInvalidRule, token)
+		}
+		rules = append(rules, boundRule{name: name, op: op, param: param, fn: fn})
+	}
+	return rules, nil
+}
+
+// checkArgs tries each rule on the zero value of the type it will check,
+// so a malformed tag is caught whatever the field later holds
+func checkArgs(t reflect.Type, rules []boundRule) error {
+	for _, r := range rules {
+		target := t
+		if r.name != "required" {
+			for target.Kind() == reflect.Ptr {
+				target = target.Elem()
+			}
+		}
+		if target.Kind() == reflect.Interface {
+			// The kind is only known once there is a value
+			continue
+		}
+		if err := r.fn(reflect.Zero(target), r.op, r.param); errors.Is(err, ErrInvalidRule) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Validator) validateStruct(rv reflect.Value, prefix string, errs *ValidationErrors) error {
+	p, err := v.plan(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, fp := range p.fields {
+		fv := rv.Field(fp.index)
+		path := fp.name
+		if prefix != "" {
+			path = prefix + "." + fp.name
+		}
+
+		failed, err := checkRules(fv, path, fp, errs)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", rv.Type().Name(), rv.Type().Field(fp.index).Name, err)
+		}
+		if failed {
+			continue
+		}
+		if fp.dive {
+			if err := v.dive(fv, path, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRules applies the field's rules, recording the first failure. A
+// misconfigured rule is returned as an error instead of being recorded.
+func checkRules(fv reflect.Value, path string, fp fieldPlan, errs *ValidationErrors) (bool, error) {
+	for _, r := range fp.rules {
+		target := fv
+		if r.name != "required" {
+			target = indirect(fv)
+			if !target.IsValid() {
+				// Optional nil pointers are only checked by required
+				return false, nil
+			}
+		}
+
+		if err := r.fn(target, r.op, r.param); err != nil {
+			if errors.Is(err, ErrInvalidRule) {
+				return false, err
+			}
+			code, ok := fp.codes[r.name]
+			if !ok {
+				code, ok = fp.codes[""]
+			}
+			if !ok {
+				code = CodeInvalidInput
+			}
+			errs.add(&ValidationError{
+				Field: path,
+				Value: valueOf(fv),
+				Code:  code,
+				Err:   err,
+			})
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// dive validates structs reachable from fv
+func (v *Validator) dive(fv reflect.Value, path string, errs *ValidationErrors) error {
+	fv = indirect(fv)
+	if !fv.IsValid() {
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		return v.validateStruct(fv, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := v.dive(fv.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseCodes parses a code tag into rule-specific and default codes
+func parseCodes(tag string) map[string]Code {
+	if tag == "" {
+		return nil
+	}
+
+	codes := make(map[string]Code)
+	for _, entry := range strings.Split(tag, ",") {
+		rule, code, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			rule, code = "", rule
+		}
+		codes[rule] = Code(code)
+	}
+	return codes
+}
+
+// fieldName returns the json name of a field, falling back to the Go name
+func fieldName(f reflect.StructField) string {
+	if tag := f.Tag.Get("json"); tag != "" {
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// containsStruct reports whether values of t can hold nested structs
+func containsStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t.PkgPath() != "time"
+}
+
+// indirect dereferences pointers, returning the zero Value for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func valueOf(v reflect.Value) interface{} {
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
+
+func isRuleName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitRule splits a tag token such as "len<=64" into name, op and param
+func splitRule(token string) (name, op, param string) {
+	i := strings.IndexAny(token, "=<>!")
+	if i < 0 {
+		return token, "", ""
+	}
+	name, rest := token[:i], token[i:]
+	for _, candidate := range []string{"<=", ">=", "!=", "=", "<", ">"} {
+		if strings.HasPrefix(rest, candidate) {
+			return name, candidate, rest[len(candidate):]
+		}
+	}
+	return name, rest[:1], rest[1:]
+}
+
+// compare applies op to the ordered pair (got, want)
+func compare(got float64, op string, want float64) (bool, error) {
+	switch op {
+	case "=":
+		return got == want, nil
+	case "!=":
+		return got != want, nil
+	case "<":
+		return got < want, nil
+	case "<=":
+		return got <= want, nil
+	case ">":
+		return got > want, nil
+	case ">=":
+		return got >= want, nil
+	default:
+		return false, fmt.Errorf("%w: unsupported operator %q", ErrInvalidRule, op)
+	}
+}
+
+// number returns the numeric value of v, or its length for sized kinds
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	default:
+		return 0, false
+	}
+}
+
+func ruleRequired(v reflect.Value, op, param string) error {
+	if !v.IsValid() || v.IsZero() {
+		return errors.New("cannot be empty")
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return errors.New("cannot be empty")
+	}
+	return nil
+}
+
+func boundRuleFunc(op string, desc string) RuleFunc {
+	return func(v reflect.Value, tagOp, param string) error {
+		if tagOp != "=" {
+			return fmt.Errorf("%w: want %s=N", ErrInvalidRule, desc)
+		}
+		want, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %s=%s is not a number", ErrInvalidRule, desc, param)
+		}
+		got, ok := number(v)
+		if !ok {
+			return fmt.Errorf("%w: %s does not apply to %s", ErrInvalidRule, desc, v.Kind())
+		}
+		if ok, _ := compare(got, op, want); !ok {
+			if desc == "min" {
+				return fmt.Errorf("must be at least %s", param)
+			}
+			return fmt.Errorf("must be at most %s", param)
+		}
+		return nil
+	}
+}
+
+var (
+	ruleMin = boundRuleFunc(">=", "min")
+	ruleMax = boundRuleFunc("<=", "max")
+)
+
+func ruleLen(v reflect.Value, op, param string) error {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return fmt.Errorf("%w: len does not apply to %s", ErrInvalidRule, v.Kind())
+	}
+
+	want, err := strconv.Atoi(param)
+	if err != nil {
+		return fmt.Errorf("%w: len%s%s is not a number", ErrInvalidRule, op, param)
+	}
+	ok, err := compare(float64(v.Len()), op, float64(want))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("length must be %s %d", op, want)
+	}
+	return nil
+}
+
+func ruleOneOf(v reflect.Value, op, param string) error {
+	if op != "=" {
+		return fmt.Errorf("%w: want oneof=a|b", ErrInvalidRule)
+	}
+
+	got := fmt.Sprint(v.Interface())
+	options := strings.Split(param, "|")
+	for _, option := range options {
+		if got == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(options, ", "))
+}
@@ -0,0 +1,312 @@
+package example3
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// validateField validates a struct with a single field tagged tag
+func validateField[T any](tag string, value T) error {
+	typ := reflect.StructOf([]reflect.StructField{{
+		Name: "Field",
+		Type: reflect.TypeOf(value),
+		Tag:  reflect.StructTag(fmt.Sprintf(`json:"field" validate:%q`, tag)),
+	}})
+	v := reflect.New(typ).Elem()
+	v.Field(0).Set(reflect.ValueOf(value))
+	return NewValidator().Validate(v.Interface())
+}
+
+func TestValidatorRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantMsg string // "" means valid
+	}{
+		{"required string", validateField("required", "x"), ""},
+		{"required empty string", validateField("required", ""), "cannot be empty"},
+		{"required zero int", validateField("required", 0), "cannot be empty"},
+		{"required empty slice", validateField("required", []string{}), "cannot be empty"},
+		{"required nil pointer", validateField("required", (*int)(nil)), "cannot be empty"},
+		{"required set pointer", validateField("required", new(int)), ""},
+
+		{"min int", validateField("min=0", 0), ""},
+		{"min int below", validateField("min=0", -1), "must be at least 0"},
+		{"min float", validateField("min=0.5", 0.25), "must be at least 0.5"},
+		{"min uint", validateField("min=2", uint8(2)), ""},
+		{"min string length", validateField("min=2", "a"), "must be at least 2"},
+		{"max int", validateField("max=150", 150), ""},
+		{"max int above", validateField("max=150", 151), "must be at most 150"},
+		{"max slice length", validateField("max=1", []int{1, 2}), "must be at most 1"},
+		{"min nil pointer is optional", validateField("min=1", (*int)(nil)), ""},
+
+		{"len equal", validateField("len=3", "abc"), ""},
+		{"len equal fails", validateField("len=3", "ab"), "length must be = 3"},
+		{"len not equal", validateField("len!=0", "a"), ""},
+		{"len at most", validateField("len<=2", "abc"), "length must be <= 2"},
+		{"len less than", validateField("len<3", []int{1, 2}), ""},
+		{"len at least", validateField("len>=1", map[string]int{}), "length must be >= 1"},
+		{"len greater than", validateField("len>1", [2]int{}), ""},
+		{"len counts bytes", validateField("len<=2", "é"), ""},
+
+		{"oneof match", validateField("oneof=pending|completed", "pending"), ""},
+		{"oneof mismatch", validateField("oneof=pending|completed", "done"), "must be one of pending, completed"},
+		{"oneof int", validateField("oneof=1|2", 2), ""},
+
+		{"rules run in order", validateField("required,len<=2", ""), "cannot be empty"},
+		{"blank tokens are skipped", validateField(" required , ,", "x"), ""},
+		{"dash skips the field", validateField("-", ""), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantMsg == "" {
+				if tt.err != nil {
+					t.Errorf("Validate() error = %v, want nil", tt.err)
+				}
+				return
+			}
+
+			var errs ValidationErrors
+			if !errors.As(tt.err, &errs) || len(errs) != 1 {
+				t.Fatalf("Validate() error = %v, want one ValidationError", tt.err)
+			}
+			if errs[0].Field != "field" || errs[0].Code != CodeInvalidInput || errs[0].Err.Error() != tt.wantMsg {
+				t.Errorf("violation = %+v, want field %q with code %s and message %q", errs[0], "field", CodeInvalidInput, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestValidatorMalformedTags(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"unknown rule", validateField("nonsense", "x")},
+		{"unknown rule with param", validateField("between=1", 1)},
+		{"min without a number", validateField("min=abc", 1)},
+		{"min without =", validateField("min", 1)},
+		{"max with <=", validateField("max<=3", 1)},
+		{"min on a bool", validateField("min=1", true)},
+		{"len on an int", validateField("len=1", 1)},
+		{"len without a number", validateField("len<=x", "x")},
+		{"len with a bad operator", validateField("len!3", "x")},
+		{"oneof without =", validateField("oneof", "x")},
+		{"min on a nil pointer", validateField[*int]("min=abc", nil)},
+		{"after a failing rule", validateField("required,len=x", "")},
+		{"min on a bool pointer", validateField("min=1", new(bool))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !errors.Is(tt.err, ErrInvalidRule) {
+				t.Errorf("Validate() error = %v, want %v", tt.err, ErrInvalidRule)
+			}
+			var errs ValidationErrors
+			if errors.As(tt.err, &errs) {
+				t.Errorf("Validate() reported a misconfigured rule as bad input: %v", tt.err)
+			}
+		})
+	}
+}
+
+type address struct {
+	City string `json:"city" validate:"required"`
+	Zip  string `json:"zip" validate:"len=5"`
+}
+
+type lineItem struct {
+	SKU      string `json:"sku" validate:"required"`
+	Quantity int    `json:"quantity" validate:"min=1"`
+}
+
+type order struct {
+	Customer string     `json:"customer" validate:"required" code:"ORDER_CUSTOMER_MISSING"`
+	Status   string     `json:"status" validate:"oneof=open|closed" code:"oneof=ORDER_STATUS_UNKNOWN"`
+	Shipping address    `json:"shipping"`
+	Billing  *address   `json:"billing"`
+	Items    []lineItem `json:"items" validate:"required"`
+	Gifts    []*lineItem
+	Notes    string `json:"-" validate:"len<=3"`
+	internal string `validate:"required"`
+}
+
+func TestValidatorNestedFields(t *testing.T) {
+	valid := func() *order {
+		return &order{
+			Customer: "ada",
+			Status:   "open",
+			Shipping: address{City: "London", Zip: "12345"},
+			Items:    []lineItem{{SKU: "a", Quantity: 1}},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		mutate     func(o *order)
+		wantFields []string
+		wantCodes  []Code
+	}{
+		{name: "valid", mutate: func(o *order) {}},
+		{
+			name:       "nested struct",
+			mutate:     func(o *order) { o.Shipping.City = "" },
+			wantFields: []string{"shipping.city"},
+			wantCodes:  []Code{CodeInvalidInput},
+		},
+		{
+			name:       "pointer to struct",
+			mutate:     func(o *order) { o.Billing = &address{City: "Paris", Zip: "1"} },
+			wantFields: []string{"billing.zip"},
+			wantCodes:  []Code{CodeInvalidInput},
+		},
+		{
+			name:       "slice of structs",
+			mutate:     func(o *order) { o.Items = append(o.Items, lineItem{SKU: "", Quantity: 0}) },
+			wantFields: []string{"items[1].sku", "items[1].quantity"},
+			wantCodes:  []Code{CodeInvalidInput, CodeInvalidInput},
+		},
+		{
+			name:       "slice of pointers skips nil",
+			mutate:     func(o *order) { o.Gifts = []*lineItem{nil, {SKU: "g"}} },
+			wantFields: []string{"Gifts[1].quantity"},
+			wantCodes:  []Code{CodeInvalidInput},
+		},
+		{
+			name:       "failed field is not dived into",
+			mutate:     func(o *order) { o.Items = nil },
+			wantFields: []string{"items"},
+			wantCodes:  []Code{CodeInvalidInput},
+		},
+		{
+			name: "every violation in declaration order with codes",
+			mutate: func(o *order) {
+				o.Customer, o.Status, o.Notes = "", "lost", "too long"
+			},
+			wantFields: []string{"customer", "status", "Notes"},
+			wantCodes:  []Code{"ORDER_CUSTOMER_MISSING", "ORDER_STATUS_UNKNOWN", CodeInvalidInput},
+		},
+	}
+
+	validator := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid()
+			tt.mutate(o)
+			err := validator.Validate(o)
+
+			var errs ValidationErrors
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &errs) {
+				t.Fatalf("Validate() error = %v, want ValidationErrors", err)
+			}
+			if got := errs.Fields(); !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("Fields() = %v, want %v", got, tt.wantFields)
+			}
+			for i, e := range errs {
+				if i < len(tt.wantCodes) && e.Code != tt.wantCodes[i] {
+					t.Errorf("errs[%d].Code = %s, want %s", i, e.Code, tt.wantCodes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidatorInput(t *testing.T) {
+	validator := NewValidator()
+	for _, value := range []interface{}{nil, (*order)(nil), 42, "order"} {
+		if err := validator.Validate(value); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("Validate(%#v) error = %v, want %v", value, err, ErrInvalidInput)
+		}
+	}
+
+	// Pointers to pointers are followed
+	o := &order{Customer: "ada", Status: "open", Shipping: address{City: "x", Zip: "12345"}, Items: []lineItem{{SKU: "a", Quantity: 1}}}
+	if err := validator.Validate(&o); err != nil {
+		t.Errorf("Validate(**order) error = %v", err)
+	}
+}
+
+func TestValidatorRegisterRule(t *testing.T) {
+	validator := NewValidator()
+	lower := func(v reflect.Value, op, param string) error {
+		if s := v.String(); s != strings.ToLower(s) {
+			return errors.New("must be lower case")
+		}
+		return nil
+	}
+
+	for _, name := range []string{"", "has space", "a=b"} {
+		if err := validator.RegisterRule(name, lower); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("RegisterRule(%q) error = %v, want %v", name, err, ErrInvalidRule)
+		}
+	}
+	if err := validator.RegisterRule("nil_rule", nil); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("RegisterRule(nil) error = %v, want %v", err, ErrInvalidRule)
+	}
+	if err := validator.RegisterRule("required", lower); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("RegisterRule(required) error = %v, want %v", err, ErrInvalidRule)
+	}
+	if err := validator.RegisterRule("lower", lower); err != nil {
+		t.Fatalf("RegisterRule(lower) error = %v", err)
+	}
+
+	type handle struct {
+		Name string `json:"name" validate:"required,lower"`
+	}
+	if err := validator.Validate(handle{Name: "ada"}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	var errs ValidationErrors
+	if err := validator.Validate(handle{Name: "Ada"}); !errors.As(err, &errs) || errs[0].Err.Error() != "must be lower case" {
+		t.Errorf("Validate() error = %v, want the custom rule's violation", err)
+	}
+}
+
+func TestValidatorConcurrentUse(t *testing.T) {
+	validator := NewValidator()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(age int) {
+			defer wg.Done()
+			err := validator.Validate(&User{ID: age, Name: "ada", Age: age})
+			if (age > 150) != (err != nil) {
+				t.Errorf("Validate(age %d) error = %v", age, err)
+			}
+		}(i * 5)
+	}
+	wg.Wait()
+}
+
+func TestValidateUserCodes(t *testing.T) {
+	err := NewUserService().ValidateUser(&User{Name: strings.Repeat("x", 65), Age: 200})
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ValidateUser() error = %v, want ValidationErrors", err)
+	}
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Field + ":" + string(e.Code)
+	}
+	want := []string{"name:" + string(CodeInvalidInput), "age:USER_AGE_OUT_OF_RANGE"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+
+	errs = nil
+	if !errors.As(NewUserService().ValidateUser(&User{Age: 30}), &errs) || errs[0].Code != "USER_NAME_EMPTY" {
+		t.Errorf("empty name violation = %v, want code USER_NAME_EMPTY", errs)
+	}
+}
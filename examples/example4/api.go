@@ -0,0 +1,264 @@
+package example4
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxBodyBytes bounds request bodies
+const maxBodyBytes = 1 << 20
+
+// userRequest is the body of POST and PUT requests. The ID comes from the
+// path.
+type userRequest struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+// errorResponse is the body of every error response
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewHandler exposes service over REST:
+//
+//	POST   /users/{id}  create a user, 201
+//	GET    /users/{id}  fetch a user, 200
+//	PUT    /users/{id}  replace a user's name and age, 200
+//	DELETE /users/{id}  delete a user, 204
+//
+// ErrInvalidInput maps to 400, ErrNotFound to 404 and ErrAlreadyExists to
+// 409; anything else is a 500.
+func NewHandler(service *UserService) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user, err := decodeUser(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := service.CreateUser(user); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, user)
+	})
+
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		user, err := service.GetUser(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, user)
+	})
+
+	mux.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		user, err := decodeUser(w, r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := service.UpdateUser(user); err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, user)
+	})
+
+	mux.HandleFunc("DELETE /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := service.DeleteUser(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// pathID parses the {id} path segment
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, fmt.Errorf("%w: user ID %q is not a number", ErrInvalidInput, r.PathValue("id"))
+	}
+	return id, nil
+}
+
+// decodeUser reads the user in a POST or PUT request
+func decodeUser(w http.ResponseWriter, r *http.Request) (*User, error) {
+	id, err := pathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var body userRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: malformed request body: %v", ErrInvalidInput, err)
+	}
+	return &User{ID: id, Name: body.Name, Age: body.Age}, nil
+}
+
+// statusOf maps a service error to an HTTP status
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyExists):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := statusOf(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		// Don't leak internals such as file paths
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Client calls the REST API served by NewHandler. Its methods mirror
+// UserService and return errors that match the same sentinels with
+// errors.Is, so code written against the service reads the same over HTTP.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the API at baseURL. A nil httpClient uses
+// http.DefaultClient.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    httpClient,
+	}
+}
+
+// CreateUser creates user and updates it with the stored copy
+func (c *Client) CreateUser(user *User) error {
+	return c.do(http.MethodPost, user.ID, userRequest{Name: user.Name, Age: user.Age}, user)
+}
+
+// GetUser retrieves a user by ID
+func (c *Client) GetUser(id int) (*User, error) {
+	var user User
+	if err := c.do(http.MethodGet, id, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser replaces a user's name and age
+func (c *Client) UpdateUser(user *User) error {
+	return c.do(http.MethodPut, user.ID, userRequest{Name: user.Name, Age: user.Age}, user)
+}
+
+// DeleteUser deletes a user by ID
+func (c *Client) DeleteUser(id int) error {
+	return c.do(http.MethodDelete, id, nil, nil)
+}
+
+// do sends a request for /users/{id} and decodes a successful response
+// into out
+func (c *Client) do(method string, id int, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fmt.Sprintf("%s/users/%d", c.baseURL, id), body)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s /users/%d: %w", method, id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// APIError is a non-2xx response. It unwraps to the sentinel error the
+// status maps to, if any.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return ErrInvalidInput
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+// responseError decodes an error response
+func responseError(resp *http.Response) error {
+	var body errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: body.Error}
+}
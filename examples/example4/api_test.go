@@ -0,0 +1,110 @@
+package example4
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *Client) {
+	t.Helper()
+	server := httptest.NewServer(NewHandler(NewUserService()))
+	t.Cleanup(server.Close)
+	return server, NewClient(server.URL, server.Client())
+}
+
+func TestHandlerStatusCodes(t *testing.T) {
+	server, client := newTestServer(t)
+	if err := client.CreateUser(&User{ID: 1, Name: "John Doe", Age: 30}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"create", http.MethodPost, "/users/2", `{"name":"Jane Doe","age":28}`, http.StatusCreated},
+		{"create duplicate", http.MethodPost, "/users/1", `{"name":"John Doe","age":30}`, http.StatusConflict},
+		{"create invalid", http.MethodPost, "/users/3", `{"name":"","age":30}`, http.StatusBadRequest},
+		{"create malformed", http.MethodPost, "/users/3", `{"name":`, http.StatusBadRequest},
+		{"create unknown field", http.MethodPost, "/users/3", `{"name":"A","age":1,"role":"admin"}`, http.StatusBadRequest},
+		{"bad id", http.MethodGet, "/users/abc", "", http.StatusBadRequest},
+		{"get", http.MethodGet, "/users/1", "", http.StatusOK},
+		{"get missing", http.MethodGet, "/users/99", "", http.StatusNotFound},
+		{"create oversized", http.MethodPost, "/users/3", `{"name":"` + strings.Repeat("a", maxBodyBytes) + `","age":1}`, http.StatusBadRequest},
+		{"update", http.MethodPut, "/users/1", `{"name":"John Updated","age":31}`, http.StatusOK},
+		{"update invalid", http.MethodPut, "/users/1", `{"name":"John","age":200}`, http.StatusBadRequest},
+		{"update missing", http.MethodPut, "/users/99", `{"name":"Nobody","age":30}`, http.StatusNotFound},
+		{"delete missing", http.MethodDelete, "/users/99", "", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/users/2", "", http.StatusNoContent},
+		{"wrong method", http.MethodPatch, "/users/1", "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	_, client := newTestServer(t)
+
+	err := client.CreateUser(&User{ID: 1, Name: "", Age: 30})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("CreateUser(invalid) error = %v, want %v", err, ErrInvalidInput)
+	}
+
+	_, err = client.GetUser(1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetUser(missing) error = %#v, want a 404 APIError matching %v", err, ErrNotFound)
+	}
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	_, client := newTestServer(t)
+
+	user := &User{ID: 1, Name: "John Doe", Age: 30}
+	if err := client.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if user.CreatedAt.IsZero() {
+		t.Error("CreateUser() did not fill in CreatedAt")
+	}
+	created := user.CreatedAt
+
+	user.Name = "John Updated"
+	if err := client.UpdateUser(user); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	got, err := client.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if !sameUser(got, user) || !got.CreatedAt.Equal(created) {
+		t.Errorf("GetUser() = %+v, want %+v created at %v", got, user, created)
+	}
+}
+
+func TestConcurrentClients(t *testing.T) {
+	_, client := newTestServer(t)
+	if err := runConcurrentClients(client, 8, 1); err != nil {
+		t.Fatal(err)
+	}
+}
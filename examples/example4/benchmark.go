@@ -0,0 +1,519 @@
+package example4
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// DatasetSizes are the numbers of preloaded users each sized benchmark runs
+// against
+var DatasetSizes = []int{100, 1000, 10000}
+
+// NamedBenchmark is one user service benchmark. Setup prepares a fresh
+// service and returns the operation to time; op(i) runs iteration i. The
+// go test benchmarks and RunBenchmarkWithOptions share these.
+type NamedBenchmark struct {
+	Name  string
+	Setup func() (op func(i int) error, err error)
+}
+
+// Benchmarks returns the user service benchmarks. Sized benchmarks are
+// named Operation/size=N.
+func Benchmarks() []NamedBenchmark {
+	benchmarks := []NamedBenchmark{
+		{Name: "ValidateUser", Setup: benchmarkValidateUser},
+	}
+	for _, size := range DatasetSizes {
+		suffix := fmt.Sprintf("/size=%d", size)
+		benchmarks = append(benchmarks,
+			NamedBenchmark{Name: "CreateUser" + suffix, Setup: benchmarkCreateUser(size)},
+			NamedBenchmark{Name: "GetUser" + suffix, Setup: benchmarkGetUser(size)},
+			NamedBenchmark{Name: "UpdateUser" + suffix, Setup: benchmarkUpdateUser(size)},
+		)
+	}
+	return benchmarks
+}
+
+// seededService returns a service preloaded with size users, IDs 0..size-1
+func seededService(size int) (*UserService, error) {
+	service := NewUserService()
+	for i := 0; i < size; i++ {
+		if err := service.CreateUser(&User{ID: i, Name: "Seed User", Age: 30}); err != nil {
+			return nil, fmt.Errorf("failed to seed user %d: %w", i, err)
+		}
+	}
+	return service, nil
+}
+
+func benchmarkValidateUser() (func(i int) error, error) {
+	service := NewUserService()
+	user := &User{ID: 1, Name: "John Doe", Age: 30}
+
+	return func(int) error {
+		return service.ValidateUser(user)
+	}, nil
+}
+
+func benchmarkCreateUser(size int) func() (func(i int) error, error) {
+	return func() (func(i int) error, error) {
+		service, err := seededService(size)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(i int) error {
+			// A fresh user every iteration; IDs above the seeded range
+			return service.CreateUser(&User{ID: size + i, Name: "John Doe", Age: 30})
+		}, nil
+	}
+}
+
+func benchmarkGetUser(size int) func() (func(i int) error, error) {
+	return func() (func(i int) error, error) {
+		service, err := seededService(size)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(i int) error {
+			_, err := service.GetUser(i % size)
+			return err
+		}, nil
+	}
+}
+
+func benchmarkUpdateUser(size int) func() (func(i int) error, error) {
+	return func() (func(i int) error, error) {
+		service, err := seededService(size)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(i int) error {
+			return service.UpdateUser(&User{ID: i % size, Name: "John Updated", Age: 31})
+		}, nil
+	}
+}
+
+// BenchmarkOptions configures RunBenchmarkWithOptions
+type BenchmarkOptions struct {
+	// Samples is how many times each benchmark runs; more samples make
+	// the comparison more sensitive. Default 5.
+	Samples int
+	// BenchTime is the target duration of each sample. Default 200ms.
+	BenchTime time.Duration
+	// SavePath, if set, is where the results are written as a baseline.
+	SavePath string
+	// ComparePath, if set, is a baseline to compare the results against.
+	ComparePath string
+	// Alpha is the significance level for reporting a change. Default 0.05.
+	Alpha float64
+}
+
+// Sample is one run of a benchmark
+type Sample struct {
+	N           int     `json:"n"`
+	NsPerOp     float64 `json:"ns_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+}
+
+// BenchmarkResult holds every sample of one benchmark
+type BenchmarkResult struct {
+	Name    string   `json:"name"`
+	Samples []Sample `json:"samples"`
+}
+
+// Baseline is a saved set of benchmark results
+type Baseline struct {
+	CreatedAt  time.Time         `json:"created_at"`
+	GoVersion  string            `json:"go_version"`
+	GOOS       string            `json:"goos"`
+	GOARCH     string            `json:"goarch"`
+	Benchmarks []BenchmarkResult `json:"benchmarks"`
+}
+
+// RunBenchmark runs the user service benchmarks and prints the results
+func RunBenchmark(w io.Writer) error {
+	return RunBenchmarkWithOptions(w, BenchmarkOptions{})
+}
+
+// RunBenchmarkWithOptions runs the benchmarks through testing.Benchmark,
+// optionally saving them as a baseline and comparing them to a previous one
+func RunBenchmarkWithOptions(w io.Writer, opts BenchmarkOptions) error {
+	if opts.Samples <= 0 {
+		opts.Samples = 5
+	}
+	if opts.BenchTime <= 0 {
+		opts.BenchTime = 200 * time.Millisecond
+	}
+	if opts.Alpha <= 0 {
+		opts.Alpha = 0.05
+	}
+
+	var previous *Baseline
+	if opts.ComparePath != "" {
+		baseline, err := LoadBaseline(opts.ComparePath)
+		if err != nil {
+			return err
+		}
+		previous = baseline
+	}
+
+	current := &Baseline{
+		CreatedAt: time.Now(),
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+	}
+
+	fmt.Fprintf(w, "\nRunning %d samples of each benchmark (%v per sample):\n", opts.Samples, opts.BenchTime)
+	for _, bm := range Benchmarks() {
+		result := BenchmarkResult{Name: bm.Name}
+		for i := 0; i < opts.Samples; i++ {
+			sample, err := runSample(bm, opts.BenchTime)
+			if err != nil {
+				return err
+			}
+			result.Samples = append(result.Samples, sample)
+		}
+		current.Benchmarks = append(current.Benchmarks, result)
+
+		mean, stddev := meanStddev(result.nsPerOp())
+		last := result.Samples[len(result.Samples)-1]
+		fmt.Fprintf(w, "%-28s %12.1f ns/op ±%4.1f%%  %6d B/op  %4d allocs/op\n",
+			bm.Name, mean, percent(stddev, mean), last.BytesPerOp, last.AllocsPerOp)
+	}
+
+	if previous != nil {
+		fmt.Fprintf(w, "\nComparison against %s (%s):\n", opts.ComparePath, previous.CreatedAt.Format(time.RFC3339))
+		for _, c := range Compare(previous, current, opts.Alpha) {
+			fmt.Fprintln(w, c)
+		}
+	}
+
+	if opts.SavePath != "" {
+		if err := current.Save(opts.SavePath); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nSaved baseline to %s\n", opts.SavePath)
+	}
+
+	return nil
+}
+
+// runSample runs bm once through testing.Benchmark, which grows b.N until
+// a run lasts at least benchTime
+func runSample(bm NamedBenchmark, benchTime time.Duration) (Sample, error) {
+	restore, err := setBenchTime(benchTime)
+	if err != nil {
+		return Sample{}, err
+	}
+	defer restore()
+
+	var benchErr error
+	r := testing.Benchmark(func(b *testing.B) {
+		op, err := bm.Setup()
+		if err != nil {
+			benchErr = err
+			b.FailNow()
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := op(i); err != nil {
+				benchErr = err
+				b.FailNow()
+			}
+		}
+	})
+	if benchErr != nil {
+		return Sample{}, fmt.Errorf("benchmark %s: %w", bm.Name, benchErr)
+	}
+	if r.N == 0 {
+		return Sample{}, fmt.Errorf("benchmark %s failed", bm.Name)
+	}
+
+	return Sample{
+		N:           r.N,
+		NsPerOp:     float64(r.T.Nanoseconds()) / float64(r.N),
+		AllocsPerOp: r.AllocsPerOp(),
+		BytesPerOp:  r.AllocedBytesPerOp(),
+	}, nil
+}
+
+var (
+	testFlagsOnce sync.Once
+	testFlags     *flag.FlagSet
+)
+
+// setBenchTime sets -test.benchtime, which testing.Benchmark reads, and
+// returns a func restoring the previous value. testing.Init registers its
+// flags on flag.CommandLine, so it runs against a scoped FlagSet to keep
+// them out of the program's own flags; under go test they are already
+// registered globally and Init does nothing.
+func setBenchTime(d time.Duration) (restore func(), err error) {
+	testFlagsOnce.Do(func() {
+		global := flag.CommandLine
+		flag.CommandLine = flag.NewFlagSet("testing", flag.ContinueOnError)
+		testing.Init()
+		testFlags, flag.CommandLine = flag.CommandLine, global
+	})
+
+	f := testFlags.Lookup("test.benchtime")
+	if f == nil {
+		f = flag.Lookup("test.benchtime")
+	}
+	if f == nil {
+		return nil, errors.New("failed to set benchmark time: testing flags are not registered")
+	}
+
+	previous := f.Value.String()
+	if err := f.Value.Set(d.String()); err != nil {
+		return nil, fmt.Errorf("failed to set benchmark time: %w", err)
+	}
+	return func() { f.Value.Set(previous) }, nil
+}
+
+func (r BenchmarkResult) nsPerOp() []float64 {
+	values := make([]float64, len(r.Samples))
+	for i, s := range r.Samples {
+		values[i] = s.NsPerOp
+	}
+	return values
+}
+
+// LoadBaseline reads a baseline written by Baseline.Save
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("%w: corrupt baseline %s: %v", ErrInvalidInput, path, err)
+	}
+	return &baseline, nil
+}
+
+// Save writes the baseline as indented JSON
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write baseline: %w", err)
+	}
+	return nil
+}
+
+// Comparison is the change in one benchmark between two baselines
+type Comparison struct {
+	Name        string
+	OldMean     float64
+	NewMean     float64
+	DeltaPct    float64
+	PValue      float64
+	Significant bool
+}
+
+func (c Comparison) String() string {
+	if math.IsNaN(c.PValue) {
+		return fmt.Sprintf("%-28s %12.1f -> %12.1f ns/op  (not enough samples)", c.Name, c.OldMean, c.NewMean)
+	}
+	delta := "~"
+	if c.Significant {
+		delta = fmt.Sprintf("%+.2f%%", c.DeltaPct)
+	}
+	return fmt.Sprintf("%-28s %12.1f -> %12.1f ns/op  %9s  (p=%.3f)", c.Name, c.OldMean, c.NewMean, delta, c.PValue)
+}
+
+// Compare matches benchmarks by name and tests each for a change in mean
+// ns/op with Welch's t-test. A change is significant when its p-value is
+// below alpha; benchmarks missing from either side are skipped.
+func Compare(old, new *Baseline, alpha float64) []Comparison {
+	oldByName := make(map[string]BenchmarkResult, len(old.Benchmarks))
+	for _, r := range old.Benchmarks {
+		oldByName[r.Name] = r
+	}
+
+	var comparisons []Comparison
+	for _, r := range new.Benchmarks {
+		prev, ok := oldByName[r.Name]
+		if !ok {
+			continue
+		}
+
+		oldMean, _ := meanStddev(prev.nsPerOp())
+		newMean, _ := meanStddev(r.nsPerOp())
+		p := welchTTest(prev.nsPerOp(), r.nsPerOp())
+		comparisons = append(comparisons, Comparison{
+			Name:        r.Name,
+			OldMean:     oldMean,
+			NewMean:     newMean,
+			DeltaPct:    percent(newMean-oldMean, oldMean),
+			PValue:      p,
+			Significant: !math.IsNaN(p) && p < alpha,
+		})
+	}
+
+	sort.Slice(comparisons, func(i, j int) bool { return comparisons[i].Name < comparisons[j].Name })
+	return comparisons
+}
+
+func percent(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
+
+// meanStddev returns the mean and sample standard deviation
+func meanStddev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)-1))
+}
+
+// welchTTest returns the two-sided p-value for the hypothesis that a and b
+// have the same mean, without assuming equal variances. It returns NaN
+// when either side has fewer than two samples.
+func welchTTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+
+	meanA, sdA := meanStddev(a)
+	meanB, sdB := meanStddev(b)
+	varA := sdA * sdA / float64(len(a))
+	varB := sdB * sdB / float64(len(b))
+	if varA+varB == 0 {
+		if meanA == meanB {
+			return 1
+		}
+		return 0
+	}
+
+	t := (meanA - meanB) / math.Sqrt(varA+varB)
+	df := (varA + varB) * (varA + varB) /
+		(varA*varA/float64(len(a)-1) + varB*varB/float64(len(b)-1))
+
+	// Two-sided p-value of Student's t: I_{df/(df+t²)}(df/2, 1/2)
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// errNoConvergence is returned internally when the continued fraction for
+// the incomplete beta function does not converge
+var errNoConvergence = errors.New("incomplete beta did not converge")
+
+// regularizedIncompleteBeta computes I_x(a, b) using the continued fraction
+// expansion from Numerical Recipes.
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+
+	lbeta := lgamma(a+b) - lgamma(a) - lgamma(b)
+	front := math.Exp(lbeta + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly for x < (a+1)/(a+b+2)
+	if x < (a+1)/(a+b+2) {
+		cf, err := betaContinuedFraction(x, a, b)
+		if err != nil {
+			return math.NaN()
+		}
+		return front * cf / a
+	}
+	cf, err := betaContinuedFraction(1-x, b, a)
+	if err != nil {
+		return math.NaN()
+	}
+	return 1 - front*cf/b
+}
+
+func lgamma(x float64) float64 {
+	v, _ := math.Lgamma(x)
+	return v
+}
+
+func betaContinuedFraction(x, a, b float64) (float64, error) {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-12
+		tiny          = 1e-300
+	)
+
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			return h, nil
+		}
+	}
+	return 0, errNoConvergence
+}
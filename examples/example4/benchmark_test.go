@@ -0,0 +1,226 @@
+package example4
+
+import (
+	"errors"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BenchmarkUserService runs every user service benchmark as a
+// sub-benchmark, e.g. go test -bench 'UserService/GetUser' -benchmem
+func BenchmarkUserService(b *testing.B) {
+	for _, bm := range Benchmarks() {
+		b.Run(bm.Name, func(b *testing.B) {
+			op, err := bm.Setup()
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := op(i); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestRunSample(t *testing.T) {
+	benchtime := flag.Lookup("test.benchtime").Value.String()
+	bm := Benchmarks()[0]
+	sample, err := runSample(bm, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("runSample() error = %v", err)
+	}
+	if sample.N < 2 || sample.NsPerOp <= 0 || time.Duration(sample.NsPerOp*float64(sample.N)) < 5*time.Millisecond {
+		t.Errorf("runSample() = %+v, want several iterations filling 5ms", sample)
+	}
+	if got := flag.Lookup("test.benchtime").Value.String(); got != benchtime {
+		t.Errorf("-test.benchtime = %s after runSample, want it restored to %s", got, benchtime)
+	}
+
+	failing := NamedBenchmark{Name: "Failing", Setup: func() (func(int) error, error) {
+		return func(int) error { return ErrNotFound }, nil
+	}}
+	if _, err := runSample(failing, time.Millisecond); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "Failing") {
+		t.Errorf("runSample() of a failing benchmark error = %v", err)
+	}
+}
+
+func TestRegularizedIncompleteBeta(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, a, b float64
+		want    float64
+	}{
+		{"below range", -1, 2, 3, 0},
+		{"above range", 2, 2, 3, 1},
+		{"b=1 is x^a", 0.3, 2.5, 1, math.Pow(0.3, 2.5)},
+		{"a=1 is 1-(1-x)^b", 0.3, 1, 4, 1 - math.Pow(0.7, 4)},
+		{"symmetric at one half", 0.5, 7, 7, 0.5},
+		{"upper branch", 0.9, 2, 1, 0.81},
+		// Cauchy: two-sided p of Student's t with 1 df is 1 - 2/π·atan|t|
+		{"one degree of freedom", 1.0 / (1 + 4), 0.5, 0.5, 1 - 2/math.Pi*math.Atan(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := regularizedIncompleteBeta(tt.x, tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("I_%v(%v, %v) = %v, want %v", tt.x, tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// studentP is the two-sided p-value of Student's t with df degrees of
+// freedom, as welchTTest computes it
+func studentP(t, df float64) float64 {
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+func TestStudentPAgainstTables(t *testing.T) {
+	// Two-sided critical values from standard t tables
+	tests := []struct {
+		t, df, p float64
+	}{
+		{12.706, 1, 0.05},
+		{4.303, 2, 0.05},
+		{2.228, 10, 0.05},
+		{2.042, 30, 0.05},
+		{4.032, 5, 0.01},
+		{2.845, 20, 0.01},
+		{1.812, 10, 0.10},
+	}
+	for _, tt := range tests {
+		if got := studentP(tt.t, tt.df); math.Abs(got-tt.p) > 5e-4 {
+			t.Errorf("p(t=%v, df=%v) = %.5f, want %.3f", tt.t, tt.df, got, tt.p)
+		}
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// Equal variances and two samples each give t = -2√2 with 2 df,
+		// whose two-sided p is exactly 1 - |t|/√(2+t²) = 1 - √0.8
+		{"two degrees of freedom", []float64{0, 2}, []float64{4, 6}, 1 - math.Sqrt(0.8)},
+		{"order does not matter", []float64{4, 6}, []float64{0, 2}, 1 - math.Sqrt(0.8)},
+		{"identical constant samples", []float64{5, 5, 5}, []float64{5, 5}, 1},
+		{"different constant samples", []float64{5, 5, 5}, []float64{6, 6}, 0},
+		{"same mean", []float64{1, 2, 3}, []float64{0, 2, 4}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := welchTTest(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("welchTTest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := welchTTest([]float64{1}, []float64{1, 2}); !math.IsNaN(got) {
+		t.Errorf("welchTTest() with one sample = %v, want NaN", got)
+	}
+}
+
+func TestMeanStddev(t *testing.T) {
+	mean, sd := meanStddev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if mean != 5 || math.Abs(sd-math.Sqrt(32.0/7)) > 1e-12 {
+		t.Errorf("meanStddev() = %v, %v, want 5, %v", mean, sd, math.Sqrt(32.0/7))
+	}
+	if mean, sd := meanStddev(nil); mean != 0 || sd != 0 {
+		t.Errorf("meanStddev(nil) = %v, %v", mean, sd)
+	}
+	if mean, sd := meanStddev([]float64{3}); mean != 3 || sd != 0 {
+		t.Errorf("meanStddev([3]) = %v, %v", mean, sd)
+	}
+}
+
+func baselineOf(results map[string][]float64) *Baseline {
+	b := &Baseline{}
+	for name, values := range results {
+		result := BenchmarkResult{Name: name}
+		for _, v := range values {
+			result.Samples = append(result.Samples, Sample{N: 1000, NsPerOp: v})
+		}
+		b.Benchmarks = append(b.Benchmarks, result)
+	}
+	return b
+}
+
+func TestCompare(t *testing.T) {
+	old := baselineOf(map[string][]float64{
+		"Steady":    {100, 101, 99, 100, 100},
+		"Slower":    {100, 101, 99, 100, 100},
+		"Removed":   {100, 100},
+		"OneSample": {100},
+	})
+	current := baselineOf(map[string][]float64{
+		"Steady":    {100, 99, 101, 100, 100},
+		"Slower":    {150, 151, 149, 150, 150},
+		"Added":     {100, 100},
+		"OneSample": {100, 110},
+	})
+
+	got := Compare(old, current, 0.05)
+	var names []string
+	for _, c := range got {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "OneSample,Slower,Steady" {
+		t.Fatalf("Compare() names = %v, want the shared benchmarks in order", names)
+	}
+
+	oneSample, slower, steady := got[0], got[1], got[2]
+	if !math.IsNaN(oneSample.PValue) || oneSample.Significant || !strings.Contains(oneSample.String(), "not enough samples") {
+		t.Errorf("one-sample comparison = %+v", oneSample)
+	}
+	if !slower.Significant || slower.PValue >= 0.001 || slower.DeltaPct != 50 || slower.OldMean != 100 || slower.NewMean != 150 {
+		t.Errorf("slower comparison = %+v, want a significant +50%%", slower)
+	}
+	if !strings.Contains(slower.String(), "+50.00%") {
+		t.Errorf("slower String() = %q", slower.String())
+	}
+	if steady.Significant || steady.PValue != 1 || !strings.Contains(steady.String(), " ~ ") {
+		t.Errorf("steady comparison = %+v (%s), want no significant change", steady, steady)
+	}
+}
+
+func TestBaselineSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	want := baselineOf(map[string][]float64{"GetUser/size=100": {12.5, 13}})
+	want.CreatedAt = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want.GoVersion, want.GOOS, want.GOARCH = "go1.23.3", "linux", "amd64"
+
+	if err := want.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	got, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.GoVersion != want.GoVersion ||
+		len(got.Benchmarks) != 1 || got.Benchmarks[0].Samples[1] != want.Benchmarks[0].Samples[1] {
+		t.Errorf("LoadBaseline() = %+v, want %+v", got, want)
+	}
+
+	if _, err := LoadBaseline(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadBaseline() of a missing file error = %v, want %v", err, os.ErrNotExist)
+	}
+	corrupt := filepath.Join(t.TempDir(), "corrupt.json")
+	os.WriteFile(corrupt, []byte("{not json"), 0o644)
+	if _, err := LoadBaseline(corrupt); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("LoadBaseline() of a corrupt file error = %v, want %v", err, ErrInvalidInput)
+	}
+	if err := want.Save(filepath.Join(t.TempDir(), "missing", "dir", "b.json")); err == nil {
+		t.Error("Save() into a missing directory succeeded, want an error")
+	}
+}
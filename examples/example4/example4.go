@@ -4,7 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
+
+	"practice/examples/demo"
+	"practice/examples/tabletest"
 )
 
 // Common errors
@@ -23,14 +31,39 @@ type User struct {
 
 // UserService handles user operations
 type UserService struct {
-	users map[int]*User
+	repo UserRepository
+	now  func() time.Time
+
+	// updateMu makes UpdateUser's read of CreatedAt and its write one step
+	updateMu sync.Mutex
+}
+
+// Option configures a UserService
+type Option func(*UserService)
+
+// WithClock replaces time.Now for creation times
+func WithClock(now func() time.Time) Option {
+	return func(s *UserService) {
+		s.now = now
+	}
+}
+
+// NewUserService creates a new user service backed by an in-memory
+// repository
+func NewUserService(opts ...Option) *UserService {
+	return NewUserServiceWithRepository(NewMemoryRepository(), opts...)
 }
 
-// NewUserService creates a new user service
-func NewUserService() *UserService {
-	return &UserService{
-		users: make(map[int]*User),
+// NewUserServiceWithRepository creates a user service backed by repo
+func NewUserServiceWithRepository(repo UserRepository, opts ...Option) *UserService {
+	s := &UserService{
+		repo: repo,
+		now:  time.Now,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // ValidateUser validates a user
@@ -53,161 +86,165 @@ func (s *UserService) CreateUser(user *User) error {
 		return err
 	}
 
-	// Check if user already exists
-	if _, exists := s.users[user.ID]; exists {
-		return fmt.Errorf("user with ID %d already exists", user.ID)
-	}
-
 	// Create the user
-	user.CreatedAt = time.Now()
-	s.users[user.ID] = user
-	return nil
+	user.CreatedAt = s.now()
+	return s.repo.Create(user)
 }
 
 // GetUser retrieves a user by ID
 func (s *UserService) GetUser(id int) (*User, error) {
-	user, exists := s.users[id]
-	if !exists {
-		return nil, fmt.Errorf("%w: user with ID %d", ErrNotFound, id)
-	}
-	return user, nil
+	return s.repo.Get(id)
 }
 
-// UpdateUser updates an existing user
+// UpdateUser replaces an existing user's name and age. The stored
+// CreatedAt is kept and copied into user.
 func (s *UserService) UpdateUser(user *User) error {
 	// Validate the user
 	if err := s.ValidateUser(user); err != nil {
 		return err
 	}
 
-	// Check if user exists
-	if _, exists := s.users[user.ID]; !exists {
-		return fmt.Errorf("%w: user with ID %d", ErrNotFound, user.ID)
-	}
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
 
-	// Update the user
-	s.users[user.ID] = user
-	return nil
+	existing, err := s.repo.Get(user.ID)
+	if err != nil {
+		return err
+	}
+	user.CreatedAt = existing.CreatedAt
+	return s.repo.Update(user)
 }
 
 // DeleteUser deletes a user by ID
 func (s *UserService) DeleteUser(id int) error {
-	if _, exists := s.users[id]; !exists {
-		return fmt.Errorf("%w: user with ID %d", ErrNotFound, id)
-	}
-
-	delete(s.users, id)
-	return nil
+	return s.repo.Delete(id)
 }
 
-// Run demonstrates the user service with various operations
-func Run() error {
-	service := NewUserService()
-
-	// Test cases for user creation
-	testCases := []struct {
-		name    string
-		user    *User
-		wantErr bool
-	}{
-		{
-			name: "valid user",
-			user: &User{
-				ID:   1,
-				Name: "John Doe",
-				Age:  30,
+// createUserTable returns the user creation cases. Each case creates a
+// user on service and reads it back. Run prints it as a walkthrough and the
+// package tests run it under go test.
+func createUserTable(service *UserService) tabletest.Table[*User, *User] {
+	return tabletest.Table[*User, *User]{
+		Name: "create_user",
+		Cases: []tabletest.Case[*User, *User]{
+			{
+				Name:  "valid user",
+				Input: &User{ID: 1, Name: "John Doe", Age: 30},
+				Want:  &User{ID: 1, Name: "John Doe", Age: 30},
 			},
-			wantErr: false,
-		},
-		{
-			name: "invalid name",
-			user: &User{
-				ID:   2,
-				Name: "",
-				Age:  30,
+			{
+				Name:  "invalid name",
+				Input: &User{ID: 2, Name: "", Age: 30},
+				ErrIs: ErrInvalidInput,
 			},
-			wantErr: true,
-		},
-		{
-			name: "invalid age",
-			user: &User{
-				ID:   3,
-				Name: "Jane Doe",
-				Age:  -1,
+			{
+				Name:  "invalid age",
+				Input: &User{ID: 3, Name: "Jane Doe", Age: -1},
+				ErrIs: ErrInvalidInput,
 			},
-			wantErr: true,
+		},
+		Run: func(user *User) (*User, error) {
+			if err := service.CreateUser(user); err != nil {
+				return nil, err
+			}
+			return service.GetUser(user.ID)
+		},
+		Equal: sameUser,
+		Describe: func(w io.Writer, user *User, err error) {
+			if err != nil {
+				fmt.Fprintf(w, "Expected error: %v\n", err)
+				return
+			}
+			userJSON, _ := json.MarshalIndent(user, "", "  ")
+			fmt.Fprintf(w, "Created user: %s\n", userJSON)
 		},
 	}
+}
 
-	// Run test cases
-	for _, tc := range testCases {
-		fmt.Printf("\nTesting: %s\n", tc.name)
+// sameUser compares users ignoring the server-assigned CreatedAt
+func sameUser(got, want *User) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.ID == want.ID && got.Name == want.Name && got.Age == want.Age
+}
 
-		// Create user
-		err := service.CreateUser(tc.user)
-		if err != nil {
-			if !tc.wantErr {
-				return fmt.Errorf("unexpected error: %w", err)
-			}
-			fmt.Printf("Expected error: %v\n", err)
-			continue
-		}
+// Run demonstrates the user service with various operations
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	return createUserTable(NewUserService(WithClock(env.Now))).Print(env)
+}
 
-		// Try to get the user
-		user, err := service.GetUser(tc.user.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get user: %w", err)
-		}
+// RunIntegration runs the integration scenarios over HTTP: each
+// repository backend is served by NewHandler on a local test server and
+// driven through Client
+func RunIntegration(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
 
-		// Print user details
-		userJSON, _ := json.MarshalIndent(user, "", "  ")
-		fmt.Printf("Created user: %s\n", userJSON)
+	dir, err := os.MkdirTemp("", "example4-")
+	if err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
 	}
+	defer os.RemoveAll(dir)
 
-	return nil
-}
-
-// RunBenchmark demonstrates benchmarking the user service
-func RunBenchmark() error {
-	service := NewUserService()
+	path := filepath.Join(dir, "users.jsonl")
+	fileRepo, err := NewFileRepository(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file repository: %w", err)
+	}
+	defer fileRepo.Close()
 
-	// Create a test user
-	user := &User{
-		ID:   1,
-		Name: "John Doe",
-		Age:  30,
+	backends := []struct {
+		name string
+		repo UserRepository
+	}{
+		{name: "memory", repo: NewMemoryRepository()},
+		{name: "file", repo: fileRepo},
 	}
 
-	// Benchmark user creation
-	fmt.Println("\nBenchmarking user creation:")
-	start := time.Now()
-	for i := 0; i < 1000; i++ {
-		user.ID = i
-		if err := service.CreateUser(user); err != nil {
-			return fmt.Errorf("failed to create user: %w", err)
+	for _, backend := range backends {
+		server := httptest.NewServer(NewHandler(NewUserServiceWithRepository(backend.repo, WithClock(env.Now))))
+		fmt.Fprintf(env, "\nRunning integration test scenarios against the %s repository:\n", backend.name)
+		err := runIntegrationScenarios(env, NewClient(server.URL, server.Client()))
+		server.Close()
+		if err != nil {
+			return fmt.Errorf("%s repository: %w", backend.name, err)
 		}
 	}
-	duration := time.Since(start)
-	fmt.Printf("Created 1000 users in %v\n", duration)
-
-	// Benchmark user retrieval
-	fmt.Println("\nBenchmarking user retrieval:")
-	start = time.Now()
-	for i := 0; i < 1000; i++ {
-		if _, err := service.GetUser(i); err != nil {
-			return fmt.Errorf("failed to get user: %w", err)
-		}
+
+	// The file repository survives a restart of the server
+	fmt.Fprintln(env, "\nScenario 6: Restart the server on the file repository")
+	server := httptest.NewServer(NewHandler(NewUserServiceWithRepository(fileRepo, WithClock(env.Now))))
+	persisted := &User{ID: 2, Name: "Jane Doe", Age: 28}
+	err = NewClient(server.URL, server.Client()).CreateUser(persisted)
+	server.Close()
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := fileRepo.Close(); err != nil {
+		return fmt.Errorf("failed to close file repository: %w", err)
+	}
+
+	reopened, err := NewFileRepository(path)
+	if err != nil {
+		return fmt.Errorf("failed to reopen file repository: %w", err)
 	}
-	duration = time.Since(start)
-	fmt.Printf("Retrieved 1000 users in %v\n", duration)
+	defer reopened.Close()
+	server = httptest.NewServer(NewHandler(NewUserServiceWithRepository(reopened, WithClock(env.Now))))
+	defer server.Close()
+
+	user, err := NewClient(server.URL, server.Client()).GetUser(persisted.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user after restart: %w", err)
+	}
+	fmt.Fprintf(env, "User after restart: %+v\n", user)
 
 	return nil
 }
 
-// RunIntegration demonstrates integration testing scenarios
-func RunIntegration() error {
-	service := NewUserService()
-
+// runIntegrationScenarios runs the create, update, delete, error mapping
+// and concurrency scenarios through client
+func runIntegrationScenarios(w io.Writer, client *Client) error {
 	// Create a user
 	user := &User{
 		ID:   1,
@@ -215,44 +252,112 @@ func RunIntegration() error {
 		Age:  30,
 	}
 
-	fmt.Println("\nRunning integration test scenarios:")
-
 	// Test scenario 1: Create and retrieve
-	fmt.Println("\nScenario 1: Create and retrieve user")
-	if err := service.CreateUser(user); err != nil {
+	fmt.Fprintln(w, "\nScenario 1: Create and retrieve user")
+	if err := client.CreateUser(user); err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
-	retrieved, err := service.GetUser(user.ID)
+	retrieved, err := client.GetUser(user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get user: %w", err)
 	}
-	fmt.Printf("Retrieved user: %+v\n", retrieved)
+	fmt.Fprintf(w, "Retrieved user: %+v\n", retrieved)
 
 	// Test scenario 2: Update user
-	fmt.Println("\nScenario 2: Update user")
+	fmt.Fprintln(w, "\nScenario 2: Update user")
 	user.Name = "John Updated"
-	if err := service.UpdateUser(user); err != nil {
+	if err := client.UpdateUser(user); err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	updated, err := service.GetUser(user.ID)
+	updated, err := client.GetUser(user.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get updated user: %w", err)
 	}
-	fmt.Printf("Updated user: %+v\n", updated)
+	fmt.Fprintf(w, "Updated user: %+v\n", updated)
 
 	// Test scenario 3: Delete user
-	fmt.Println("\nScenario 3: Delete user")
-	if err := service.DeleteUser(user.ID); err != nil {
+	fmt.Fprintln(w, "\nScenario 3: Delete user")
+	if err := client.DeleteUser(user.ID); err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
-	_, err = service.GetUser(user.ID)
+	_, err = client.GetUser(user.ID)
 	if err == nil {
 		return fmt.Errorf("user should not exist after deletion")
 	}
-	fmt.Printf("User deleted successfully, error on retrieval: %v\n", err)
+	fmt.Fprintf(w, "User deleted successfully, error on retrieval: %v\n", err)
+
+	// Test scenario 4: Errors map to status codes and back
+	fmt.Fprintln(w, "\nScenario 4: Error status mapping")
+	err = client.CreateUser(&User{ID: 3, Name: "", Age: 30})
+	if !errors.Is(err, ErrInvalidInput) {
+		return fmt.Errorf("invalid user: got %v, want %v", err, ErrInvalidInput)
+	}
+	fmt.Fprintf(w, "Invalid user rejected: %v\n", err)
+
+	err = client.UpdateUser(&User{ID: 404, Name: "Nobody", Age: 30})
+	if !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("missing user: got %v, want %v", err, ErrNotFound)
+	}
+	fmt.Fprintf(w, "Missing user reported: %v\n", err)
+
+	// Test scenario 5: Concurrent clients
+	fmt.Fprintln(w, "\nScenario 5: Concurrent clients")
+	if err := runConcurrentClients(client, 10, 100); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "10 clients created, updated, read and deleted 10 users each")
 
 	return nil
 }
+
+// runConcurrentClients has each of clients goroutines create, update, read
+// back and delete its own users, with IDs starting at firstID
+func runConcurrentClients(client *Client, clients, firstID int) error {
+	const usersPerClient = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, clients)
+	for c := 0; c < clients; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			for i := 0; i < usersPerClient; i++ {
+				id := firstID + c*usersPerClient + i
+				user := &User{ID: id, Name: fmt.Sprintf("Client %d user %d", c, i), Age: 20 + i}
+				if err := client.CreateUser(user); err != nil {
+					errs <- fmt.Errorf("client %d: failed to create user %d: %w", c, id, err)
+					return
+				}
+				user.Age++
+				if err := client.UpdateUser(user); err != nil {
+					errs <- fmt.Errorf("client %d: failed to update user %d: %w", c, id, err)
+					return
+				}
+				got, err := client.GetUser(id)
+				if err != nil {
+					errs <- fmt.Errorf("client %d: failed to get user %d: %w", c, id, err)
+					return
+				}
+				if !sameUser(got, user) {
+					errs <- fmt.Errorf("client %d: user %d is %+v, want %+v", c, id, got, user)
+					return
+				}
+				if err := client.DeleteUser(id); err != nil {
+					errs <- fmt.Errorf("client %d: failed to delete user %d: %w", c, id, err)
+					return
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(errs)
+
+	var failures []error
+	for err := range errs {
+		failures = append(failures, err)
+	}
+	return errors.Join(failures...)
+}
@@ -0,0 +1,55 @@
+package example4
+
+import (
+	"encoding/json"
+	"flag"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"practice/examples/tabletest/tabletesttest"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCreateUser(t *testing.T) {
+	table := createUserTable(NewUserService())
+	table.Parallel = true
+	tabletesttest.Run(t, table)
+}
+
+func TestCreateUserGolden(t *testing.T) {
+	table := createUserTable(NewUserService())
+	table.GoldenDir = filepath.Join("testdata", table.Name)
+	table.UpdateGolden = *update
+	table.Golden = func(user *User, err error) []byte {
+		if err != nil {
+			return []byte("error: " + err.Error() + "\n")
+		}
+		stable := *user
+		stable.CreatedAt = time.Time{}
+		data, _ := json.MarshalIndent(stable, "", "  ")
+		return append(data, '\n')
+	}
+	tabletesttest.Run(t, table)
+}
+
+func TestUpdateUserKeepsCreatedAt(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service := NewUserService(WithClock(func() time.Time { return created }))
+	if err := service.CreateUser(&User{ID: 1, Name: "John Doe", Age: 30}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	user := &User{ID: 1, Name: "John Updated", Age: 31, CreatedAt: created.Add(time.Hour)}
+	if err := service.UpdateUser(user); err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	got, err := service.GetUser(1)
+	if err != nil {
+		t.Fatalf("GetUser() error = %v", err)
+	}
+	if !sameUser(got, user) || !got.CreatedAt.Equal(created) || !user.CreatedAt.Equal(created) {
+		t.Errorf("after UpdateUser() stored %+v and returned %+v, want both created at %v", got, user, created)
+	}
+}
@@ -0,0 +1,23 @@
+package example4
+
+// FailNextWrite makes the next append write only the first n bytes of its
+// record and then fail with err, as a full disk would
+func (r *FileRepository) FailNextWrite(n int, err error) {
+	r.file = &failingLogFile{logFile: r.file, n: n, err: err}
+}
+
+type failingLogFile struct {
+	logFile
+	n   int
+	err error
+}
+
+func (f *failingLogFile) Write(p []byte) (int, error) {
+	if f.err == nil {
+		return f.logFile.Write(p)
+	}
+	n, _ := f.logFile.Write(p[:min(f.n, len(p))])
+	err := f.err
+	f.err = nil
+	return n, err
+}
@@ -0,0 +1,393 @@
+// Code generated by mockgen from practice/examples/example4. DO NOT EDIT.
+
+// Package mocks holds test doubles for the interfaces of practice/examples/example4.
+package mocks
+
+import (
+	"practice/examples/example4"
+	"practice/examples/mock"
+)
+
+// UserRepository is a test double for example4.UserRepository
+type UserRepository struct {
+	recorder mock.Recorder
+
+	// CreateFunc, if set, handles Create calls that match no expectation
+	CreateFunc func(user *example4.User) error
+
+	// DeleteFunc, if set, handles Delete calls that match no expectation
+	DeleteFunc func(id int) error
+
+	// GetFunc, if set, handles Get calls that match no expectation
+	GetFunc func(id int) (*example4.User, error)
+
+	// ListFunc, if set, handles List calls that match no expectation
+	ListFunc func() ([]*example4.User, error)
+
+	// UpdateFunc, if set, handles Update calls that match no expectation
+	UpdateFunc func(user *example4.User) error
+}
+
+var _ example4.UserRepository = (*UserRepository)(nil)
+
+// NewUserRepository returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewUserRepository(t mock.TB) *UserRepository {
+	m := &UserRepository{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *UserRepository) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Create implements example4.UserRepository
+func (m *UserRepository) Create(user *example4.User) error {
+	if results, ok := m.recorder.Called("Create", user); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.CreateFunc != nil {
+		return m.CreateFunc(user)
+	}
+	m.recorder.Unexpected("Create", user)
+	var r0 error
+	return r0
+}
+
+// UserRepositoryCreateCall is an expectation on UserRepository.Create
+type UserRepositoryCreateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectCreate expects a call to Create whose arguments match the given
+// matchers or equal the given values
+func (m *UserRepository) ExpectCreate(user interface{}) UserRepositoryCreateCall {
+	return UserRepositoryCreateCall{m.recorder.Expect("Create", 1, user)}
+}
+
+// Return sets the values Create returns
+func (c UserRepositoryCreateCall) Return(r0 error) UserRepositoryCreateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Create returns from its arguments
+func (c UserRepositoryCreateCall) Do(fn func(user *example4.User) error) UserRepositoryCreateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		user, _ := args[0].(*example4.User)
+		r0 := fn(user)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c UserRepositoryCreateCall) Times(n int) UserRepositoryCreateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c UserRepositoryCreateCall) AnyTimes() UserRepositoryCreateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// UserRepositoryCreateArgs holds the arguments of one Create call
+type UserRepositoryCreateArgs struct {
+	User *example4.User
+}
+
+// CreateCalls returns the arguments of every Create call in order
+func (m *UserRepository) CreateCalls() []UserRepositoryCreateArgs {
+	var out []UserRepositoryCreateArgs
+	for _, call := range m.recorder.Calls("Create") {
+		var args UserRepositoryCreateArgs
+		args.User, _ = call.Args[0].(*example4.User)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Delete implements example4.UserRepository
+func (m *UserRepository) Delete(id int) error {
+	if results, ok := m.recorder.Called("Delete", id); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(id)
+	}
+	m.recorder.Unexpected("Delete", id)
+	var r0 error
+	return r0
+}
+
+// UserRepositoryDeleteCall is an expectation on UserRepository.Delete
+type UserRepositoryDeleteCall struct {
+	e *mock.Expectation
+}
+
+// ExpectDelete expects a call to Delete whose arguments match the given
+// matchers or equal the given values
+func (m *UserRepository) ExpectDelete(id interface{}) UserRepositoryDeleteCall {
+	return UserRepositoryDeleteCall{m.recorder.Expect("Delete", 1, id)}
+}
+
+// Return sets the values Delete returns
+func (c UserRepositoryDeleteCall) Return(r0 error) UserRepositoryDeleteCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Delete returns from its arguments
+func (c UserRepositoryDeleteCall) Do(fn func(id int) error) UserRepositoryDeleteCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(int)
+		r0 := fn(id)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c UserRepositoryDeleteCall) Times(n int) UserRepositoryDeleteCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c UserRepositoryDeleteCall) AnyTimes() UserRepositoryDeleteCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// UserRepositoryDeleteArgs holds the arguments of one Delete call
+type UserRepositoryDeleteArgs struct {
+	ID int
+}
+
+// DeleteCalls returns the arguments of every Delete call in order
+func (m *UserRepository) DeleteCalls() []UserRepositoryDeleteArgs {
+	var out []UserRepositoryDeleteArgs
+	for _, call := range m.recorder.Calls("Delete") {
+		var args UserRepositoryDeleteArgs
+		args.ID, _ = call.Args[0].(int)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Get implements example4.UserRepository
+func (m *UserRepository) Get(id int) (*example4.User, error) {
+	if results, ok := m.recorder.Called("Get", id); ok {
+		r0, _ := results[0].(*example4.User)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.GetFunc != nil {
+		return m.GetFunc(id)
+	}
+	m.recorder.Unexpected("Get", id)
+	var r0 *example4.User
+	var r1 error
+	return r0, r1
+}
+
+// UserRepositoryGetCall is an expectation on UserRepository.Get
+type UserRepositoryGetCall struct {
+	e *mock.Expectation
+}
+
+// ExpectGet expects a call to Get whose arguments match the given
+// matchers or equal the given values
+func (m *UserRepository) ExpectGet(id interface{}) UserRepositoryGetCall {
+	return UserRepositoryGetCall{m.recorder.Expect("Get", 2, id)}
+}
+
+// Return sets the values Get returns
+func (c UserRepositoryGetCall) Return(r0 *example4.User, r1 error) UserRepositoryGetCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Get returns from its arguments
+func (c UserRepositoryGetCall) Do(fn func(id int) (*example4.User, error)) UserRepositoryGetCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		id, _ := args[0].(int)
+		r0, r1 := fn(id)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c UserRepositoryGetCall) Times(n int) UserRepositoryGetCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c UserRepositoryGetCall) AnyTimes() UserRepositoryGetCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// UserRepositoryGetArgs holds the arguments of one Get call
+type UserRepositoryGetArgs struct {
+	ID int
+}
+
+// GetCalls returns the arguments of every Get call in order
+func (m *UserRepository) GetCalls() []UserRepositoryGetArgs {
+	var out []UserRepositoryGetArgs
+	for _, call := range m.recorder.Calls("Get") {
+		var args UserRepositoryGetArgs
+		args.ID, _ = call.Args[0].(int)
+		out = append(out, args)
+	}
+	return out
+}
+
+// List implements example4.UserRepository
+func (m *UserRepository) List() ([]*example4.User, error) {
+	if results, ok := m.recorder.Called("List"); ok {
+		r0, _ := results[0].([]*example4.User)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ListFunc != nil {
+		return m.ListFunc()
+	}
+	m.recorder.Unexpected("List")
+	var r0 []*example4.User
+	var r1 error
+	return r0, r1
+}
+
+// UserRepositoryListCall is an expectation on UserRepository.List
+type UserRepositoryListCall struct {
+	e *mock.Expectation
+}
+
+// ExpectList expects a call to List whose arguments match the given
+// matchers or equal the given values
+func (m *UserRepository) ExpectList() UserRepositoryListCall {
+	return UserRepositoryListCall{m.recorder.Expect("List", 2)}
+}
+
+// Return sets the values List returns
+func (c UserRepositoryListCall) Return(r0 []*example4.User, r1 error) UserRepositoryListCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values List returns from its arguments
+func (c UserRepositoryListCall) Do(fn func() ([]*example4.User, error)) UserRepositoryListCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		r0, r1 := fn()
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c UserRepositoryListCall) Times(n int) UserRepositoryListCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c UserRepositoryListCall) AnyTimes() UserRepositoryListCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// UserRepositoryListArgs holds the arguments of one List call
+type UserRepositoryListArgs struct {
+}
+
+// ListCalls returns the arguments of every List call in order
+func (m *UserRepository) ListCalls() []UserRepositoryListArgs {
+	var out []UserRepositoryListArgs
+	for _, call := range m.recorder.Calls("List") {
+		_ = call
+		var args UserRepositoryListArgs
+		out = append(out, args)
+	}
+	return out
+}
+
+// Update implements example4.UserRepository
+func (m *UserRepository) Update(user *example4.User) error {
+	if results, ok := m.recorder.Called("Update", user); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(user)
+	}
+	m.recorder.Unexpected("Update", user)
+	var r0 error
+	return r0
+}
+
+// UserRepositoryUpdateCall is an expectation on UserRepository.Update
+type UserRepositoryUpdateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectUpdate expects a call to Update whose arguments match the given
+// matchers or equal the given values
+func (m *UserRepository) ExpectUpdate(user interface{}) UserRepositoryUpdateCall {
+	return UserRepositoryUpdateCall{m.recorder.Expect("Update", 1, user)}
+}
+
+// Return sets the values Update returns
+func (c UserRepositoryUpdateCall) Return(r0 error) UserRepositoryUpdateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Update returns from its arguments
+func (c UserRepositoryUpdateCall) Do(fn func(user *example4.User) error) UserRepositoryUpdateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		user, _ := args[0].(*example4.User)
+		r0 := fn(user)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c UserRepositoryUpdateCall) Times(n int) UserRepositoryUpdateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c UserRepositoryUpdateCall) AnyTimes() UserRepositoryUpdateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// UserRepositoryUpdateArgs holds the arguments of one Update call
+type UserRepositoryUpdateArgs struct {
+	User *example4.User
+}
+
+// UpdateCalls returns the arguments of every Update call in order
+func (m *UserRepository) UpdateCalls() []UserRepositoryUpdateArgs {
+	var out []UserRepositoryUpdateArgs
+	for _, call := range m.recorder.Calls("Update") {
+		var args UserRepositoryUpdateArgs
+		args.User, _ = call.Args[0].(*example4.User)
+		out = append(out, args)
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package example4
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"practice/examples/proptest"
+)
+
+// userGen generates users with ages well outside the valid range and
+// names that are often empty, shrinking one field at a time.
+func userGen() proptest.Gen[User] {
+	ids := proptest.Int(-1000, 1000)
+	names := proptest.String(20, proptest.Printing)
+	ages := proptest.Int(-200, 300)
+
+	return proptest.Gen[User]{
+		Generate: func(r *rand.Rand) User {
+			return User{ID: ids.Generate(r), Name: names.Generate(r), Age: ages.Generate(r)}
+		},
+		Shrink: func(u User) []User {
+			var out []User
+			for _, id := range ids.Shrink(u.ID) {
+				out = append(out, User{ID: id, Name: u.Name, Age: u.Age})
+			}
+			for _, name := range names.Shrink(u.Name) {
+				out = append(out, User{ID: u.ID, Name: name, Age: u.Age})
+			}
+			for _, age := range ages.Shrink(u.Age) {
+				out = append(out, User{ID: u.ID, Name: u.Name, Age: age})
+			}
+			return out
+		},
+	}
+}
+
+// isValid is the specification ValidateUser must implement
+func isValid(u User) bool {
+	return u.Name != "" && u.Age >= 0 && u.Age <= 150
+}
+
+// checkValidate asserts ValidateUser rejects iff the user is invalid
+func checkValidate(u User) error {
+	err := NewUserService().ValidateUser(&u)
+	switch {
+	case isValid(u) && err != nil:
+		return fmt.Errorf("valid user rejected: %w", err)
+	case !isValid(u) && err == nil:
+		return fmt.Errorf("invalid user accepted")
+	case err != nil && !errors.Is(err, ErrInvalidInput):
+		return fmt.Errorf("rejection %q does not wrap ErrInvalidInput", err)
+	}
+	return nil
+}
+
+// checkCreateGet asserts a created user reads back unchanged, and an
+// invalid one is rejected without being stored
+func checkCreateGet(u User) error {
+	service := NewUserService()
+	input := u
+
+	err := service.CreateUser(&input)
+	if !isValid(u) {
+		if err == nil {
+			return fmt.Errorf("invalid user created")
+		}
+		if _, getErr := service.GetUser(u.ID); !errors.Is(getErr, ErrNotFound) {
+			return fmt.Errorf("rejected user was stored")
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("CreateUser() error = %w", err)
+	}
+
+	got, err := service.GetUser(u.ID)
+	if err != nil {
+		return fmt.Errorf("GetUser() error = %w", err)
+	}
+	if !sameUser(got, &u) || !got.CreatedAt.Equal(input.CreatedAt) {
+		return fmt.Errorf("GetUser() = %+v, want %+v", got, input)
+	}
+	return nil
+}
+
+// checkDeleteGet asserts a deleted user is gone
+func checkDeleteGet(u User) error {
+	if !isValid(u) {
+		return nil
+	}
+
+	service := NewUserService()
+	if err := service.CreateUser(&u); err != nil {
+		return fmt.Errorf("CreateUser() error = %w", err)
+	}
+	if err := service.DeleteUser(u.ID); err != nil {
+		return fmt.Errorf("DeleteUser() error = %w", err)
+	}
+	if _, err := service.GetUser(u.ID); !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("GetUser() after delete error = %v, want %v", err, ErrNotFound)
+	}
+	return nil
+}
+
+func TestPropertyValidateUser(t *testing.T) {
+	proptest.Check(t, userGen(), checkValidate)
+}
+
+func TestPropertyCreateThenGet(t *testing.T) {
+	proptest.Check(t, userGen(), checkCreateGet)
+}
+
+func TestPropertyDeleteThenGet(t *testing.T) {
+	proptest.Check(t, userGen(), checkDeleteGet)
+}
+
+// failureRecorder captures Fatalf instead of stopping the test
+type failureRecorder struct {
+	testing.TB
+	message string
+}
+
+func (r *failureRecorder) Helper() {}
+
+func (r *failureRecorder) Fatalf(format string, args ...interface{}) {
+	r.message = fmt.Sprintf(format, args...)
+}
+
+func TestShrinkFindsMinimalCounterexample(t *testing.T) {
+	// A deliberately wrong specification: claims ages up to 200 are valid
+	wrong := func(u User) error {
+		err := NewUserService().ValidateUser(&u)
+		if u.Name != "" && u.Age >= 0 && u.Age <= 200 && err != nil {
+			return err
+		}
+		return nil
+	}
+
+	recorder := &failureRecorder{TB: t}
+	proptest.Check(recorder, userGen(), wrong, proptest.Config{Seed: 1, Runs: 1000})
+
+	if recorder.message == "" {
+		t.Fatal("Check() did not report the wrong specification")
+	}
+	want := "shrunk input (" // followed by the step count
+	if !strings.Contains(recorder.message, want) || !strings.Contains(recorder.message, "ID:0 Name:  Age:151") {
+		t.Errorf("Check() did not shrink to the boundary case:\n%s", recorder.message)
+	}
+}
+
+func FuzzValidateUser(f *testing.F) {
+	f.Add("John Doe", 30)
+	f.Add("", 30)
+	f.Add("Jane Doe", -1)
+	f.Add("Old", 151)
+	f.Add("Edge", 150)
+	f.Add("Edge", 0)
+
+	f.Fuzz(func(t *testing.T, name string, age int) {
+		if err := checkValidate(User{ID: 1, Name: name, Age: age}); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func FuzzCreateUser(f *testing.F) {
+	f.Add(1, "John Doe", 30)
+	f.Add(-5, "", 30)
+	f.Add(0, "Jane Doe", 151)
+	f.Add(1<<31, "\x00\xff", 0)
+
+	f.Fuzz(func(t *testing.T, id int, name string, age int) {
+		u := User{ID: id, Name: name, Age: age}
+		if err := checkCreateGet(u); err != nil {
+			t.Error(err)
+		}
+		if err := checkDeleteGet(u); err != nil {
+			t.Error(err)
+		}
+	})
+}
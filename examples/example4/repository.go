@@ -0,0 +1,374 @@
+//go:generate go run practice/examples/mock/mockgen -out mocks/mocks.go
+
+package example4
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrAlreadyExists is returned when creating a user whose ID is taken
+var ErrAlreadyExists = errors.New("already exists")
+
+// UserRepository stores users. Implementations must be safe for concurrent
+// use, must not retain the *User passed in, and must return copies so
+// callers cannot modify stored users in place.
+type UserRepository interface {
+	Create(user *User) error
+	Get(id int) (*User, error)
+	Update(user *User) error
+	Delete(id int) error
+	List() ([]*User, error)
+}
+
+// MemoryRepository keeps users in a map
+type MemoryRepository struct {
+	mu    sync.RWMutex
+	users map[int]*User
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		users: make(map[int]*User),
+	}
+}
+
+// Create stores a new user
+func (r *MemoryRepository) Create(user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("%w: user with ID %d", ErrAlreadyExists, user.ID)
+	}
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+// Get retrieves a user by ID
+func (r *MemoryRepository) Get(id int) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: user with ID %d", ErrNotFound, id)
+	}
+	found := *user
+	return &found, nil
+}
+
+// Update replaces an existing user
+func (r *MemoryRepository) Update(user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; !exists {
+		return fmt.Errorf("%w: user with ID %d", ErrNotFound, user.ID)
+	}
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+// Delete removes a user by ID
+func (r *MemoryRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return fmt.Errorf("%w: user with ID %d", ErrNotFound, id)
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// List returns all users ordered by ID
+func (r *MemoryRepository) List() ([]*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedCopies(r.users), nil
+}
+
+// sortedCopies copies the users in a map into a slice ordered by ID
+func sortedCopies(users map[int]*User) []*User {
+	list := make([]*User, 0, len(users))
+	for _, user := range users {
+		found := *user
+		list = append(list, &found)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// fileRecord is one line of the JSON lines log
+type fileRecord struct {
+	Op   string `json:"op"`
+	ID   int    `json:"id"`
+	User *User  `json:"user,omitempty"`
+}
+
+// Log operations
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// FileRepository persists users to a single JSON lines file. Every change
+// is appended as one record and synced before the call returns; opening
+// the file replays the log. Compact rewrites the file with only the
+// current users.
+type FileRepository struct {
+	mu    sync.RWMutex
+	path  string
+	file  logFile
+	size  int64 // of the log, up to the last complete record
+	users map[int]*User
+}
+
+// logFile is the part of *os.File the repository writes through
+type logFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+}
+
+// NewFileRepository opens or creates the log at path and loads its users
+func NewFileRepository(path string) (*FileRepository, error) {
+	users, size, err := replay(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open user log: %w", err)
+	}
+	// Drop a torn final record so the next append starts on a fresh line
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate torn user log record: %w", err)
+	}
+
+	return &FileRepository{
+		path:  path,
+		file:  file,
+		size:  size,
+		users: users,
+	}, nil
+}
+
+// replay rebuilds the user map from the log at path. It also returns the
+// size of the log up to the last complete record: a final line without
+// its newline was torn by a crash mid-append and is ignored.
+func replay(path string) (map[int]*User, int64, error) {
+	users := make(map[int]*User)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return users, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open user log: %w", err)
+	}
+	defer file.Close()
+
+	// bufio.Reader rather than bufio.Scanner, which rejects lines over 64KB
+	reader := bufio.NewReader(file)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return users, size, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read user log: %w", err)
+		}
+
+		var record fileRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, 0, fmt.Errorf("corrupt user log %s at line %d: %w", path, line, err)
+		}
+
+		switch record.Op {
+		case opPut:
+			if record.User == nil {
+				return nil, 0, fmt.Errorf("corrupt user log %s at line %d: put without user", path, line)
+			}
+			users[record.ID] = record.User
+		case opDelete:
+			delete(users, record.ID)
+		default:
+			return nil, 0, fmt.Errorf("corrupt user log %s at line %d: unknown op %q", path, line, record.Op)
+		}
+		size += int64(len(data))
+	}
+}
+
+// appendRecord writes a record and syncs it. Callers must hold r.mu.
+func (r *FileRepository) appendRecord(record fileRecord) error {
+	if r.file == nil {
+		return fmt.Errorf("user log %s is closed", r.path)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode user record: %w", err)
+	}
+	n, err := r.file.Write(append(data, '\n'))
+	if err != nil {
+		// Drop the partial record, or every later append would be glued to it
+		if truncErr := r.file.Truncate(r.size); truncErr != nil {
+			r.file.Close()
+			r.file = nil
+			return fmt.Errorf("failed to write user log: %w; closed it after failing to remove the partial record: %v", err, truncErr)
+		}
+		return fmt.Errorf("failed to write user log: %w", err)
+	}
+	r.size += int64(n)
+	if err := r.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync user log: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new user
+func (r *FileRepository) Create(user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; exists {
+		return fmt.Errorf("%w: user with ID %d", ErrAlreadyExists, user.ID)
+	}
+	stored := *user
+	if err := r.appendRecord(fileRecord{Op: opPut, ID: user.ID, User: &stored}); err != nil {
+		return err
+	}
+	r.users[user.ID] = &stored
+	return nil
+}
+
+// Get retrieves a user by ID
+func (r *FileRepository) Get(id int) (*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, exists := r.users[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: user with ID %d", ErrNotFound, id)
+	}
+	found := *user
+	return &found, nil
+}
+
+// Update replaces an existing user
+func (r *FileRepository) Update(user *User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[user.ID]; !exists {
+		return fmt.Errorf("%w: user with ID %d", ErrNotFound, user.ID)
+	}
+	stored := *user
+	if err := r.appendRecord(fileRecord{Op: opPut, ID: user.ID, User: &stored}); err != nil {
+		return err
+	}
+	r.users[user.ID] = &stored
+	return nil
+}
+
+// Delete removes a user by ID
+func (r *FileRepository) Delete(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.users[id]; !exists {
+		return fmt.Errorf("%w: user with ID %d", ErrNotFound, id)
+	}
+	if err := r.appendRecord(fileRecord{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// List returns all users ordered by ID
+func (r *FileRepository) List() ([]*User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return sortedCopies(r.users), nil
+}
+
+// Compact rewrites the log so it holds one record per current user. The
+// new log is written beside the old one and renamed over it, so a crash
+// leaves either the old or the new file intact. The new file is opened for
+// appending before the rename, so once the rename succeeds there is
+// nothing left to fail.
+func (r *FileRepository) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return fmt.Errorf("user log %s is closed", r.path)
+	}
+
+	tmpPath := r.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted log: %w", err)
+	}
+	fail := func(format string, err error) error {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf(format, err)
+	}
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, user := range sortedCopies(r.users) {
+		if err := encoder.Encode(fileRecord{Op: opPut, ID: user.ID, User: user}); err != nil {
+			return fail("failed to write compacted log: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fail("failed to write compacted log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fail("failed to sync compacted log: %w", err)
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return fail("failed to stat compacted log: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fail("failed to replace user log: %w", err)
+	}
+
+	r.file.Close()
+	r.file = tmp
+	r.size = info.Size()
+	return nil
+}
+
+// Close closes the underlying file
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
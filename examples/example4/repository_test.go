@@ -0,0 +1,194 @@
+package example4_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"practice/examples/example4"
+	"practice/examples/example4/repotest"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	repotest.TestUserRepository(t, func(t *testing.T) example4.UserRepository {
+		return example4.NewMemoryRepository()
+	})
+}
+
+func TestFileRepository(t *testing.T) {
+	repotest.TestUserRepository(t, func(t *testing.T) example4.UserRepository {
+		repo, err := example4.NewFileRepository(filepath.Join(t.TempDir(), "users.jsonl"))
+		if err != nil {
+			t.Fatalf("NewFileRepository() error = %v", err)
+		}
+		t.Cleanup(func() { repo.Close() })
+		return repo
+	})
+}
+
+func TestFileRepositoryReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+
+	repo, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() error = %v", err)
+	}
+	for id := 1; id <= 3; id++ {
+		if err := repo.Create(&example4.User{ID: id, Name: "User", Age: 30}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+	longName := strings.Repeat("n", 70_000)
+	if err := repo.Create(&example4.User{ID: 5, Name: longName, Age: 50}); err != nil {
+		t.Fatalf("Create() long name error = %v", err)
+	}
+	if err := repo.Update(&example4.User{ID: 2, Name: "Updated", Age: 31}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err := repo.Delete(3); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if err := repo.Create(&example4.User{ID: 4, Name: "After compaction", Age: 40}); err != nil {
+		t.Fatalf("Create() after Compact error = %v", err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	reopened, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() reopen error = %v", err)
+	}
+	defer reopened.Close()
+
+	users, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	want := []struct {
+		id   int
+		name string
+	}{{1, "User"}, {2, "Updated"}, {4, "After compaction"}, {5, longName}}
+	if len(users) != len(want) {
+		t.Fatalf("List() after reopen returned %d users, want %d", len(users), len(want))
+	}
+	for i, w := range want {
+		if users[i].ID != w.id || users[i].Name != w.name {
+			t.Errorf("users[%d] = ID %d name of %d bytes, want ID %d name of %d bytes", i, users[i].ID, len(users[i].Name), w.id, len(w.name))
+		}
+	}
+}
+
+func TestFileRepositoryTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+
+	repo, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() error = %v", err)
+	}
+	if err := repo.Create(&example4.User{ID: 1, Name: "Kept", Age: 30}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	repo.Close()
+
+	// Simulate a crash part way through appending the second record
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	file.WriteString(`{"op":"put","id":2,"user":{"id":2,"na`)
+	file.Close()
+
+	reopened, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() with a torn record error = %v", err)
+	}
+	if err := reopened.Create(&example4.User{ID: 3, Name: "After crash", Age: 40}); err != nil {
+		t.Fatalf("Create() after recovery error = %v", err)
+	}
+	reopened.Close()
+
+	// The torn record is gone and the next append starts on its own line
+	again, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() after recovery error = %v", err)
+	}
+	defer again.Close()
+	users, _ := again.List()
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 3 {
+		t.Errorf("List() after recovery = %+v, want users 1 and 3", users)
+	}
+}
+
+func TestFileRepositoryFailedWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	repo, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() error = %v", err)
+	}
+	if err := repo.Create(&example4.User{ID: 1, Name: "Kept", Age: 30}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// The disk fills up part way through the second record
+	repo.FailNextWrite(10, syscall.ENOSPC)
+	if err := repo.Create(&example4.User{ID: 2, Name: "Lost", Age: 30}); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Create() on a full disk error = %v, want %v", err, syscall.ENOSPC)
+	}
+	if _, err := repo.Get(2); !errors.Is(err, example4.ErrNotFound) {
+		t.Errorf("Get() of the failed user error = %v, want %v", err, example4.ErrNotFound)
+	}
+	if err := repo.Create(&example4.User{ID: 3, Name: "After", Age: 40}); err != nil {
+		t.Fatalf("Create() after the failed write error = %v", err)
+	}
+
+	// Compact starts a new file, whose size the next repair must use
+	if err := repo.Compact(); err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	repo.FailNextWrite(5, syscall.ENOSPC)
+	if err := repo.Delete(1); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("Delete() on a full disk error = %v, want %v", err, syscall.ENOSPC)
+	}
+	repo.Close()
+
+	// The partial record was removed, so the log still replays
+	reopened, err := example4.NewFileRepository(path)
+	if err != nil {
+		t.Fatalf("NewFileRepository() after a failed write error = %v", err)
+	}
+	defer reopened.Close()
+	users, _ := reopened.List()
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 3 {
+		t.Errorf("List() after reopening = %+v, want users 1 and 3", users)
+	}
+}
+
+func TestFileRepositoryCorruptRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.jsonl")
+	if err := os.WriteFile(path, []byte("{\"op\":\"put\",\"id\":1,\"user\":{\"id\":1}}\nnot json\n"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := example4.NewFileRepository(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("NewFileRepository() error = %v, want a corrupt log error at line 2", err)
+	}
+}
+
+func TestFileRepositoryCompactAfterClose(t *testing.T) {
+	repo, err := example4.NewFileRepository(filepath.Join(t.TempDir(), "users.jsonl"))
+	if err != nil {
+		t.Fatalf("NewFileRepository() error = %v", err)
+	}
+	repo.Close()
+
+	if err := repo.Compact(); err == nil {
+		t.Error("Compact() after Close succeeded, want an error")
+	}
+}
@@ -0,0 +1,230 @@
+// Package repotest provides a conformance suite for example4.UserRepository
+// implementations, in the style of testing/fstest.
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"practice/examples/example4"
+)
+
+// Factory returns a new, empty repository. Cleanup should be registered
+// with t.Cleanup.
+type Factory func(t *testing.T) example4.UserRepository
+
+// TestUserRepository runs the conformance suite against repositories made
+// by newRepo. Every subtest gets a fresh repository.
+func TestUserRepository(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo example4.UserRepository)
+	}{
+		{"CreateThenGet", testCreateThenGet},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"GetMissing", testGetMissing},
+		{"Update", testUpdate},
+		{"UpdateMissing", testUpdateMissing},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"ListOrderedByID", testListOrderedByID},
+		{"ReturnsCopies", testReturnsCopies},
+		{"DoesNotRetainInput", testDoesNotRetainInput},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"LongName", testLongName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+func newUser(id int) *example4.User {
+	return &example4.User{
+		ID:        id,
+		Name:      fmt.Sprintf("User %d", id),
+		Age:       20 + id%50,
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func mustCreate(t *testing.T, repo example4.UserRepository, user *example4.User) {
+	t.Helper()
+	if err := repo.Create(user); err != nil {
+		t.Fatalf("Create(%d) error = %v", user.ID, err)
+	}
+}
+
+func assertEqual(t *testing.T, got, want *example4.User) {
+	t.Helper()
+	if got.ID != want.ID || got.Name != want.Name || got.Age != want.Age || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("got user %+v, want %+v", got, want)
+	}
+}
+
+func testCreateThenGet(t *testing.T, repo example4.UserRepository) {
+	want := newUser(1)
+	mustCreate(t, repo, want)
+
+	got, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	assertEqual(t, got, want)
+}
+
+func testCreateDuplicate(t *testing.T, repo example4.UserRepository) {
+	mustCreate(t, repo, newUser(1))
+
+	err := repo.Create(newUser(1))
+	if !errors.Is(err, example4.ErrAlreadyExists) {
+		t.Errorf("Create() duplicate error = %v, want %v", err, example4.ErrAlreadyExists)
+	}
+}
+
+func testGetMissing(t *testing.T, repo example4.UserRepository) {
+	if _, err := repo.Get(42); !errors.Is(err, example4.ErrNotFound) {
+		t.Errorf("Get() missing error = %v, want %v", err, example4.ErrNotFound)
+	}
+}
+
+func testUpdate(t *testing.T, repo example4.UserRepository) {
+	mustCreate(t, repo, newUser(1))
+
+	want := newUser(1)
+	want.Name = "Updated"
+	if err := repo.Update(want); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	got, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	assertEqual(t, got, want)
+}
+
+func testUpdateMissing(t *testing.T, repo example4.UserRepository) {
+	if err := repo.Update(newUser(42)); !errors.Is(err, example4.ErrNotFound) {
+		t.Errorf("Update() missing error = %v, want %v", err, example4.ErrNotFound)
+	}
+}
+
+func testDelete(t *testing.T, repo example4.UserRepository) {
+	mustCreate(t, repo, newUser(1))
+
+	if err := repo.Delete(1); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := repo.Get(1); !errors.Is(err, example4.ErrNotFound) {
+		t.Errorf("Get() after Delete error = %v, want %v", err, example4.ErrNotFound)
+	}
+
+	// The ID is free again
+	mustCreate(t, repo, newUser(1))
+}
+
+func testDeleteMissing(t *testing.T, repo example4.UserRepository) {
+	if err := repo.Delete(42); !errors.Is(err, example4.ErrNotFound) {
+		t.Errorf("Delete() missing error = %v, want %v", err, example4.ErrNotFound)
+	}
+}
+
+func testListOrderedByID(t *testing.T, repo example4.UserRepository) {
+	for _, id := range []int{3, 1, 2} {
+		mustCreate(t, repo, newUser(id))
+	}
+
+	users, err := repo.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("List() returned %d users, want 3", len(users))
+	}
+	for i, user := range users {
+		if user.ID != i+1 {
+			t.Errorf("List()[%d].ID = %d, want %d", i, user.ID, i+1)
+		}
+	}
+}
+
+func testReturnsCopies(t *testing.T, repo example4.UserRepository) {
+	mustCreate(t, repo, newUser(1))
+
+	got, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got.Name = "Modified"
+
+	again, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if again.Name == "Modified" {
+		t.Errorf("modifying a returned user changed the stored user")
+	}
+}
+
+func testDoesNotRetainInput(t *testing.T, repo example4.UserRepository) {
+	user := newUser(1)
+	mustCreate(t, repo, user)
+	user.Name = "Modified"
+
+	got, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.Name == "Modified" {
+		t.Errorf("modifying the created user changed the stored user")
+	}
+}
+
+func testConcurrentCreate(t *testing.T, repo example4.UserRepository) {
+	const writers = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Create(newUser(1))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, example4.ErrAlreadyExists):
+			t.Errorf("Create() concurrent error = %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("%d concurrent creates of the same ID succeeded, want 1", created)
+	}
+}
+
+// testLongName stores a record bigger than bufio.Scanner's 64KB line limit
+func testLongName(t *testing.T, repo example4.UserRepository) {
+	want := newUser(1)
+	want.Name = strings.Repeat("n", 70_000)
+	mustCreate(t, repo, want)
+
+	got, err := repo.Get(1)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	assertEqual(t, got, want)
+}
@@ -0,0 +1,89 @@
+package example5
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// AuditLog is an append-only record of task changes. Implementations must
+// be safe for concurrent use.
+//
+// Delivery is at least once: services append an event before they apply
+// the change, so a change that cannot be logged is never made, but a
+// change that fails after its event was appended, such as a SQL commit
+// that fails, leaves an entry for a change that did not happen. Readers
+// that need the exact state should check it against the service.
+type AuditLog interface {
+	Append(ctx context.Context, event ChangeEvent) error
+}
+
+// WithAuditLog records every change the service makes in log
+func WithAuditLog(log AuditLog) Option {
+	return func(c *config) {
+		c.auditLog = log
+	}
+}
+
+// MemoryAuditLog keeps events in memory, oldest first
+type MemoryAuditLog struct {
+	mu     sync.RWMutex
+	events []ChangeEvent
+}
+
+// NewMemoryAuditLog creates an empty in-memory audit log
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+// Append adds an event to the end of the log
+func (l *MemoryAuditLog) Append(ctx context.Context, event ChangeEvent) error {
+	if err := checkContext(ctx, "append audit event"); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event.clone())
+	return nil
+}
+
+// Events returns the logged events matching filter, oldest first
+func (l *MemoryAuditLog) Events(filter WatchFilter) []ChangeEvent {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var events []ChangeEvent
+	for _, e := range l.events {
+		if filter.Matches(e) {
+			events = append(events, e.clone())
+		}
+	}
+	return slices.Clip(events)
+}
+
+// JSONAuditLog writes each event as one line of JSON, for a file opened
+// with os.O_APPEND or any other stream
+type JSONAuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAuditLog creates an audit log writing to w
+func NewJSONAuditLog(w io.Writer) *JSONAuditLog {
+	return &JSONAuditLog{enc: json.NewEncoder(w)}
+}
+
+// Append writes the event as a line of JSON
+func (l *JSONAuditLog) Append(ctx context.Context, event ChangeEvent) error {
+	if err := checkContext(ctx, "append audit event"); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(event); err != nil {
+		return fmt.Errorf("failed to append audit event: %w", err)
+	}
+	return nil
+}
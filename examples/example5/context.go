@@ -0,0 +1,55 @@
+package example5
+
+import (
+	"context"
+	"fmt"
+)
+
+// contextKey keeps the package's context values apart from everyone else's
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+)
+
+// WithActor returns a context carrying the ID of the user acting on tasks.
+// Services record it as CreatedBy and UpdatedBy.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFrom returns the actor stored by WithActor, or "" if there is none
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// WithRequestID returns a context carrying the ID of the request being
+// served. Services record it on the tasks the request changes.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFrom returns the request ID stored by WithRequestID, or "" if
+// there is none
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// checkContext returns ctx's error, if it is done, wrapped with the action
+// that was cut short. errors.Is still matches context.Canceled and
+// context.DeadlineExceeded.
+func checkContext(ctx context.Context, action string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return nil
+}
+
+// stamp records who changed task, and in which request
+func stamp(ctx context.Context, task *Task) {
+	task.UpdatedBy = ActorFrom(ctx)
+	task.RequestID = RequestIDFrom(ctx)
+}
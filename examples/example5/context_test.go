@@ -0,0 +1,57 @@
+package example5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// expiringContext reports itself cancelled once its first checks calls to
+// Err have passed, to cancel an operation part way through
+type expiringContext struct {
+	context.Context
+	checks int
+}
+
+func (c *expiringContext) Err() error {
+	if c.checks <= 0 {
+		return context.Canceled
+	}
+	c.checks--
+	return nil
+}
+
+func TestListStopsWhenCancelledMidScan(t *testing.T) {
+	service := NewTaskService()
+	for i := 0; i < 10; i++ {
+		task := &Task{ID: fmt.Sprint(i), Title: "Task", Status: StatusPending}
+		if err := service.Create(context.Background(), task); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	ctx := &expiringContext{Context: context.Background(), checks: 3}
+	_, err := service.List(ctx, ListOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("List error = %v, want %v", err, context.Canceled)
+	}
+	if want := "failed to list tasks: context canceled"; err.Error() != want {
+		t.Errorf("List error = %q, want %q", err, want)
+	}
+}
+
+func TestContextValues(t *testing.T) {
+	ctx := context.Background()
+	if ActorFrom(ctx) != "" || RequestIDFrom(ctx) != "" {
+		t.Error("empty context has an actor or request ID")
+	}
+
+	ctx = WithRequestID(WithActor(ctx, "alice"), "req-1")
+	if got := ActorFrom(ctx); got != "alice" {
+		t.Errorf("ActorFrom = %q, want alice", got)
+	}
+	if got := RequestIDFrom(ctx); got != "req-1" {
+		t.Errorf("RequestIDFrom = %q, want req-1", got)
+	}
+}
@@ -0,0 +1,211 @@
+package example5
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ChangeKind says what happened to a task
+type ChangeKind string
+
+// Kinds of change
+const (
+	ChangeCreated ChangeKind = "created"
+	ChangeUpdated ChangeKind = "updated"
+	ChangeDeleted ChangeKind = "deleted"
+)
+
+// FieldChange is one field that differs between two versions of a task
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// ChangeEvent describes one change to a task. Before is nil for a created
+// task and After is nil for a deleted one.
+type ChangeEvent struct {
+	Kind      ChangeKind    `json:"kind"`
+	TaskID    string        `json:"task_id"`
+	Before    *Task         `json:"before,omitempty"`
+	After     *Task         `json:"after,omitempty"`
+	Diff      []FieldChange `json:"diff"`
+	Actor     string        `json:"actor,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+	At        time.Time     `json:"at"`
+}
+
+// diffFields are the fields a ChangeEvent's Diff compares, by JSON name.
+// Fields the service maintains itself, like UpdatedAt, are left out.
+var diffFields = []struct {
+	name string
+	get  func(*Task) any
+}{
+	{"title", func(t *Task) any { return t.Title }},
+	{"description", func(t *Task) any { return t.Description }},
+	{"status", func(t *Task) any { return t.Status }},
+	{"priority", func(t *Task) any { return t.Priority }},
+	{"due", func(t *Task) any {
+		if t.Due == nil {
+			return nil
+		}
+		return *t.Due
+	}},
+	{"tags", func(t *Task) any { return t.Tags }},
+	{"blocked_by", func(t *Task) any { return t.BlockedBy }},
+}
+
+// diffTasks lists the fields that differ between before and after. A nil
+// task compares as the zero Task, so a creation lists every field set.
+func diffTasks(before, after *Task) []FieldChange {
+	if before == nil {
+		before = &Task{}
+	}
+	if after == nil {
+		after = &Task{}
+	}
+	diff := []FieldChange{}
+	for _, f := range diffFields {
+		b, a := f.get(before), f.get(after)
+		if !reflect.DeepEqual(b, a) {
+			diff = append(diff, FieldChange{Field: f.name, Before: b, After: a})
+		}
+	}
+	return diff
+}
+
+// newChangeEvent describes the change from before to after, made by the
+// actor in ctx at the given time. It keeps its own copies of the tasks.
+func newChangeEvent(ctx context.Context, kind ChangeKind, before, after *Task, at time.Time) ChangeEvent {
+	e := ChangeEvent{
+		Kind:      kind,
+		Diff:      diffTasks(before, after),
+		Actor:     ActorFrom(ctx),
+		RequestID: RequestIDFrom(ctx),
+		At:        at,
+	}
+	if before != nil {
+		e.Before = before.clone()
+		e.TaskID = before.ID
+	}
+	if after != nil {
+		e.After = after.clone()
+		e.TaskID = after.ID
+	}
+	return e
+}
+
+// clone returns a copy that shares no tasks with e
+func (e ChangeEvent) clone() ChangeEvent {
+	if e.Before != nil {
+		e.Before = e.Before.clone()
+	}
+	if e.After != nil {
+		e.After = e.After.clone()
+	}
+	e.Diff = slices.Clone(e.Diff)
+	return e
+}
+
+// WatchFilter selects the events a watcher receives. The zero value
+// selects every event.
+type WatchFilter struct {
+	// TaskID limits events to one task
+	TaskID string
+
+	// Kinds limits events to these kinds of change
+	Kinds []ChangeKind
+}
+
+// Matches reports whether e passes the filter
+func (f WatchFilter) Matches(e ChangeEvent) bool {
+	if f.TaskID != "" && e.TaskID != f.TaskID {
+		return false
+	}
+	return len(f.Kinds) == 0 || slices.Contains(f.Kinds, e.Kind)
+}
+
+// WatchBuffer is how many events a watcher may fall behind. A watcher
+// that falls further behind has its channel closed, so a slow consumer
+// never holds up the service and can tell it missed events.
+const WatchBuffer = 64
+
+// feed appends a service's change events to its audit log and fans them
+// out to watchers
+type feed struct {
+	log AuditLog
+
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	filter WatchFilter
+	ch     chan ChangeEvent
+}
+
+func newFeed(log AuditLog) *feed {
+	return &feed{
+		log:      log,
+		watchers: make(map[*watcher]struct{}),
+	}
+}
+
+// record appends e to the audit log, if there is one. Services record an
+// event before they apply the change, so a change that could not be
+// logged is not made; the SQL service records inside its transaction, so
+// a failed commit leaves the entry behind (see AuditLog).
+func (f *feed) record(ctx context.Context, e ChangeEvent) error {
+	if f.log == nil {
+		return nil
+	}
+	return f.log.Append(ctx, e.clone())
+}
+
+// publish hands e to every watcher whose filter matches. Services publish
+// in the order their changes are applied.
+func (f *feed) publish(e ChangeEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for w := range f.watchers {
+		if !w.filter.Matches(e) {
+			continue
+		}
+		select {
+		case w.ch <- e.clone():
+		default:
+			f.remove(w)
+		}
+	}
+}
+
+// watch subscribes to events matching filter until ctx is done
+func (f *feed) watch(ctx context.Context, filter WatchFilter) (<-chan ChangeEvent, error) {
+	if err := checkContext(ctx, "watch tasks"); err != nil {
+		return nil, err
+	}
+
+	w := &watcher{filter: filter, ch: make(chan ChangeEvent, WatchBuffer)}
+	f.mu.Lock()
+	f.watchers[w] = struct{}{}
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.remove(w)
+	}()
+	return w.ch, nil
+}
+
+// remove closes w's channel; f.mu must be held
+func (f *feed) remove(w *watcher) {
+	if _, ok := f.watchers[w]; ok {
+		delete(f.watchers, w)
+		close(w.ch)
+	}
+}
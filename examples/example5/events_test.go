@@ -0,0 +1,82 @@
+package example5
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"practice/examples/demo"
+)
+
+func TestDiffTasks(t *testing.T) {
+	before := &Task{ID: "1", Title: "Old", Description: "Same", Status: StatusPending}
+	after := &Task{ID: "1", Title: "New", Description: "Same", Status: StatusBlocked}
+
+	want := []FieldChange{
+		{Field: "title", Before: "Old", After: "New"},
+		{Field: "status", Before: StatusPending, After: StatusBlocked},
+	}
+	if got := diffTasks(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffTasks = %+v, want %+v", got, want)
+	}
+	if got := diffTasks(before, before.clone()); len(got) != 0 {
+		t.Errorf("diffTasks of equal tasks = %+v, want none", got)
+	}
+}
+
+func TestSlowWatcherIsDropped(t *testing.T) {
+	f := newFeed(nil)
+	slow, err := f.watch(context.Background(), WatchFilter{})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	for i := 0; i < WatchBuffer+1; i++ {
+		f.publish(ChangeEvent{Kind: ChangeUpdated, TaskID: "1"})
+	}
+
+	n := 0
+	for range slow {
+		n++
+	}
+	if n != WatchBuffer {
+		t.Errorf("slow watcher received %d events before close, want %d", n, WatchBuffer)
+	}
+	if len(f.watchers) != 0 {
+		t.Errorf("feed still has %d watchers", len(f.watchers))
+	}
+}
+
+func TestJSONAuditLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewJSONAuditLog(&buf)
+	ctx := WithActor(context.Background(), "alice")
+	task := &Task{ID: "1", Title: "Write docs", Status: StatusPending}
+
+	for _, e := range []ChangeEvent{
+		newChangeEvent(ctx, ChangeCreated, nil, task, demo.Epoch),
+		newChangeEvent(ctx, ChangeDeleted, task, nil, demo.Epoch),
+	} {
+		if err := log.Append(ctx, e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	dec := json.NewDecoder(&buf)
+	var kinds []ChangeKind
+	for dec.More() {
+		var e ChangeEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if e.Actor != "alice" || e.TaskID != "1" {
+			t.Errorf("decoded event %+v", e)
+		}
+		kinds = append(kinds, e.Kind)
+	}
+	if want := []ChangeKind{ChangeCreated, ChangeDeleted}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("logged kinds %v, want %v", kinds, want)
+	}
+}
@@ -1,16 +1,26 @@
+//go:generate go run practice/examples/mock/mockgen -out mocks/mocks.go
+
 package example5
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
 	"time"
+
+	"practice/examples/demo"
+	"practice/examples/tabletest"
 )
 
 // Common errors
 var (
-	ErrInvalidInput = errors.New("invalid input")
-	ErrNotFound     = errors.New("not found")
+	ErrInvalidInput  = errors.New("invalid input")
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
 )
 
 // Task represents a task in the system
@@ -18,34 +28,129 @@ type Task struct {
 	ID          string    `json:"id"`
 	Title       string    `json:"title"`
 	Description string    `json:"description"`
-	Status      string    `json:"status"`
+	Status      Status    `json:"status"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// CreatedBy and UpdatedBy are the actors from the context of the
+	// requests that created and last changed the task, and RequestID is the
+	// ID of that last request. The service maintains them.
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	// History records every status change, oldest first. The service
+	// maintains it; values passed to Create and Update are ignored.
+	History []StatusChange `json:"history,omitempty"`
+
+	// Priority, Due and Tags plan the work. Tags are stored lowercased and
+	// sorted, the due date in UTC; a nil Due means no due date.
+	Priority Priority   `json:"priority,omitempty"`
+	Due      *time.Time `json:"due,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+
+	// BlockedBy lists the IDs of the tasks that must be final before this
+	// one can start. They must exist and must not wait on this task. When
+	// a task is deleted it is dropped from every BlockedBy.
+	BlockedBy []string `json:"blocked_by,omitempty"`
 }
 
-// TaskService defines the interface for task operations
+// clone returns a copy that shares no memory with t
+func (t *Task) clone() *Task {
+	c := *t
+	c.History = slices.Clone(t.History)
+	c.Tags = slices.Clone(t.Tags)
+	c.BlockedBy = slices.Clone(t.BlockedBy)
+	if t.Due != nil {
+		due := *t.Due
+		c.Due = &due
+	}
+	return &c
+}
+
+// TaskService defines the interface for task operations. Implementations
+// keep their own copies of tasks, so changing a *Task has no effect until
+// it is passed to Update. Every method returns an error wrapping ctx.Err()
+// once ctx is done, and records the actor and request ID found in ctx on
+// the tasks it changes.
 type TaskService interface {
 	Create(ctx context.Context, task *Task) error
 	Get(ctx context.Context, id string) (*Task, error)
 	Update(ctx context.Context, task *Task) error
+
+	// Delete removes a task. Tasks blocked by it lose the dependency, each
+	// as an update recorded and delivered after the deletion.
 	Delete(ctx context.Context, id string) error
-	List(ctx context.Context) ([]*Task, error)
+
+	List(ctx context.Context, opts ListOptions) (*TaskPage, error)
+	Transition(ctx context.Context, id string, to Status) (*Task, error)
+
+	// Next returns up to limit unfinished tasks, or all of them if limit
+	// is 0, ordered so that each comes after the tasks blocking it and the
+	// most urgent task free to start comes first
+	Next(ctx context.Context, limit int) ([]*Task, error)
+
+	// Search returns up to limit tasks whose title or description match
+	// query, or all of them if limit is 0, most relevant first. Words
+	// match regardless of case; all of them must match. "word*" matches
+	// words starting with "word", and "quoted words" must appear together
+	// in that order.
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+
+	// Watch delivers a ChangeEvent for every Create, Update, Transition
+	// and Delete matching filter, in the order the changes were made, until
+	// ctx is done. Then the channel is closed.
+	Watch(ctx context.Context, filter WatchFilter) (<-chan ChangeEvent, error)
 }
 
-// taskService implements the TaskService interface
+// taskService implements the TaskService interface. It is safe for
+// concurrent use.
 type taskService struct {
+	mu    sync.RWMutex
 	tasks map[string]*Task
+	now   func() time.Time
+	feed  *feed
+	index *searchIndex
+}
+
+// config holds the settings Options change, shared by every TaskService
+// implementation in the package
+type config struct {
+	now      func() time.Time
+	auditLog AuditLog
+}
+
+// Option configures a task service
+type Option func(*config)
+
+// WithClock replaces time.Now for creation and update times
+func WithClock(now func() time.Time) Option {
+	return func(c *config) {
+		c.now = now
+	}
+}
+
+func newConfig(opts []Option) config {
+	c := config{now: time.Now}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
 }
 
-// NewTaskService creates a new task service
-func NewTaskService() TaskService {
+// NewTaskService creates a new in-memory task service
+func NewTaskService(opts ...Option) TaskService {
+	cfg := newConfig(opts)
 	return &taskService{
 		tasks: make(map[string]*Task),
+		now:   cfg.now,
+		feed:  newFeed(cfg.auditLog),
+		index: newSearchIndex(),
 	}
 }
 
 // validateTask validates a task
-func (s *taskService) validateTask(task *Task) error {
+func validateTask(task *Task) error {
 	if task.Title == "" {
 		return fmt.Errorf("%w: title cannot be empty", ErrInvalidInput)
 	}
@@ -54,196 +159,383 @@ func (s *taskService) validateTask(task *Task) error {
 		return fmt.Errorf("%w: status cannot be empty", ErrInvalidInput)
 	}
 
-	return nil
+	if !task.Status.Valid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidInput, task.Status)
+	}
+
+	return validatePlanning(task)
 }
 
-// Create creates a new task
+// Create creates a new task and sets its timestamps
 func (s *taskService) Create(ctx context.Context, task *Task) error {
-	if err := s.validateTask(task); err != nil {
+	if err := checkContext(ctx, "create task"); err != nil {
+		return err
+	}
+	if err := validateTask(task); err != nil {
 		return err
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if _, exists := s.tasks[task.ID]; exists {
-		return fmt.Errorf("task with ID %s already exists", task.ID)
+		return fmt.Errorf("%w: task with ID %s", ErrAlreadyExists, task.ID)
+	}
+
+	created := task.clone()
+	normalizePlanning(created)
+	if err := checkDependencies(created, s.blockers); err != nil {
+		return err
 	}
 
-	now := time.Now()
-	task.CreatedAt = now
-	task.UpdatedAt = now
-	s.tasks[task.ID] = task
+	now := s.now()
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	created.CreatedBy = ActorFrom(ctx)
+	stamp(ctx, created)
+	created.History = nil
+
+	event := newChangeEvent(ctx, ChangeCreated, nil, created, now)
+	if err := s.feed.record(ctx, event); err != nil {
+		return err
+	}
+	s.tasks[task.ID] = created
+	s.index.put(created)
+	s.feed.publish(event)
+	*task = *created.clone()
 	return nil
 }
 
 // Get retrieves a task by ID
 func (s *taskService) Get(ctx context.Context, id string) (*Task, error) {
+	if err := checkContext(ctx, "get task"); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	task, exists := s.tasks[id]
 	if !exists {
 		return nil, fmt.Errorf("%w: task with ID %s", ErrNotFound, id)
 	}
-	return task, nil
+	return task.clone(), nil
 }
 
-// Update updates an existing task
+// Update updates an existing task. A status change must be allowed by the
+// transition table and is recorded in the history.
 func (s *taskService) Update(ctx context.Context, task *Task) error {
-	if err := s.validateTask(task); err != nil {
+	if err := checkContext(ctx, "update task"); err != nil {
+		return err
+	}
+	if err := validateTask(task); err != nil {
 		return err
 	}
 
-	if _, exists := s.tasks[task.ID]; !exists {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, exists := s.tasks[task.ID]
+	if !exists {
 		return fmt.Errorf("%w: task with ID %s", ErrNotFound, task.ID)
 	}
 
-	task.UpdatedAt = time.Now()
-	s.tasks[task.ID] = task
+	updated := task.clone()
+	normalizePlanning(updated)
+	if err := checkDependencies(updated, s.blockers); err != nil {
+		return err
+	}
+	updated.Status = stored.Status
+	updated.CreatedAt = stored.CreatedAt
+	updated.CreatedBy = stored.CreatedBy
+	updated.History = slices.Clone(stored.History)
+	now := s.now()
+	if task.Status != stored.Status {
+		if err := changeStatus(ctx, updated, task.Status, now); err != nil {
+			return err
+		}
+	}
+	updated.UpdatedAt = now
+	stamp(ctx, updated)
+
+	event := newChangeEvent(ctx, ChangeUpdated, stored, updated, now)
+	if err := s.feed.record(ctx, event); err != nil {
+		return err
+	}
+	s.tasks[task.ID] = updated
+	s.index.put(updated)
+	s.feed.publish(event)
+	*task = *updated.clone()
 	return nil
 }
 
-// Delete deletes a task by ID
+// Delete deletes a task by ID. Tasks blocked by it lose the dependency,
+// each as an update.
 func (s *taskService) Delete(ctx context.Context, id string) error {
-	if _, exists := s.tasks[id]; !exists {
+	if err := checkContext(ctx, "delete task"); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, exists := s.tasks[id]
+	if !exists {
 		return fmt.Errorf("%w: task with ID %s", ErrNotFound, id)
 	}
 
+	// Tasks blocked by the deleted task lose the dependency, which is an
+	// update of each of them, in ID order
+	now := s.now()
+	events := []ChangeEvent{newChangeEvent(ctx, ChangeDeleted, stored, nil, now)}
+	var unblocked []*Task
+	for _, depID := range slices.Sorted(maps.Keys(s.tasks)) {
+		if dependent := s.tasks[depID]; slices.Contains(dependent.BlockedBy, id) {
+			updated := unblock(ctx, dependent, id, now)
+			unblocked = append(unblocked, updated)
+			events = append(events, newChangeEvent(ctx, ChangeUpdated, dependent, updated, now))
+		}
+	}
+	for _, event := range events {
+		if err := s.feed.record(ctx, event); err != nil {
+			return err
+		}
+	}
+
 	delete(s.tasks, id)
+	s.index.remove(id)
+	for _, updated := range unblocked {
+		s.tasks[updated.ID] = updated
+		s.index.put(updated)
+	}
+	for _, event := range events {
+		s.feed.publish(event)
+	}
 	return nil
 }
 
-// List returns all tasks
-func (s *taskService) List(ctx context.Context) ([]*Task, error) {
-	tasks := make([]*Task, 0, len(s.tasks))
-	for _, task := range s.tasks {
-		tasks = append(tasks, task)
-	}
-	return tasks, nil
+// Watch subscribes to changes matching filter until ctx is done
+func (s *taskService) Watch(ctx context.Context, filter WatchFilter) (<-chan ChangeEvent, error) {
+	return s.feed.watch(ctx, filter)
 }
 
-// Run demonstrates the task service with various operations
-func Run() error {
-	service := NewTaskService()
-
-	// Test cases for task creation
-	testCases := []struct {
-		name    string
-		task    *Task
-		wantErr bool
-	}{
-		{
-			name: "valid task",
-			task: &Task{
-				ID:          "1",
-				Title:       "Complete project",
-				Description: "Finish the Go package design example",
-				Status:      "pending",
+// createTaskTable returns the task creation cases. Each case creates a
+// task on service and reads it back. Run prints it as a walkthrough and the
+// package tests run it under go test.
+func createTaskTable(service TaskService) tabletest.Table[*Task, *Task] {
+	return tabletest.Table[*Task, *Task]{
+		Name: "create_task",
+		Cases: []tabletest.Case[*Task, *Task]{
+			{
+				Name: "valid task",
+				Input: &Task{
+					ID:          "1",
+					Title:       "Complete project",
+					Description: "Finish the Go package design example",
+					Status:      StatusPending,
+				},
+				Want: &Task{
+					ID:          "1",
+					Title:       "Complete project",
+					Description: "Finish the Go package design example",
+					Status:      StatusPending,
+				},
 			},
-			wantErr: false,
-		},
-		{
-			name: "invalid title",
-			task: &Task{
-				ID:          "2",
-				Title:       "",
-				Description: "Invalid task",
-				Status:      "pending",
+			{
+				Name: "invalid title",
+				Input: &Task{
+					ID:          "2",
+					Title:       "",
+					Description: "Invalid task",
+					Status:      StatusPending,
+				},
+				ErrIs: ErrInvalidInput,
 			},
-			wantErr: true,
-		},
-		{
-			name: "invalid status",
-			task: &Task{
-				ID:          "3",
-				Title:       "Another task",
-				Description: "With invalid status",
-				Status:      "",
+			{
+				Name: "invalid status",
+				Input: &Task{
+					ID:          "3",
+					Title:       "Another task",
+					Description: "With invalid status",
+					Status:      "",
+				},
+				ErrIs: ErrInvalidInput,
 			},
-			wantErr: true,
 		},
-	}
-
-	// Run test cases
-	for _, tc := range testCases {
-		fmt.Printf("\nTesting: %s\n", tc.name)
-
-		// Create task
-		err := service.Create(context.Background(), tc.task)
-		if err != nil {
-			if !tc.wantErr {
-				return fmt.Errorf("unexpected error: %w", err)
+		Run: func(task *Task) (*Task, error) {
+			if err := service.Create(context.Background(), task); err != nil {
+				return nil, err
 			}
-			fmt.Printf("Expected error: %v\n", err)
-			continue
-		}
-
-		// Try to get the task
-		task, err := service.Get(context.Background(), tc.task.ID)
-		if err != nil {
-			return fmt.Errorf("failed to get task: %w", err)
-		}
-
-		// Print task details
-		fmt.Printf("Created task: %+v\n", task)
+			return service.Get(context.Background(), task.ID)
+		},
+		Equal: func(got, want *Task) bool {
+			return got.ID == want.ID && got.Title == want.Title &&
+				got.Description == want.Description && got.Status == want.Status
+		},
+		Describe: func(w io.Writer, task *Task, err error) {
+			if err != nil {
+				fmt.Fprintf(w, "Expected error: %v\n", err)
+				return
+			}
+			fmt.Fprintf(w, "Created task: %+v\n", task)
+		},
 	}
+}
 
-	return nil
+// Run demonstrates the task service with various operations
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	return createTaskTable(NewTaskService(WithClock(env.Now))).Print(env)
 }
 
 // RunIntegration demonstrates integration testing scenarios
-func RunIntegration() error {
-	service := NewTaskService()
+func RunIntegration(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	audit := NewMemoryAuditLog()
+	service := NewTaskService(WithClock(env.Now), WithAuditLog(audit))
+
+	// Every call is made on behalf of one actor within one request
+	ctx := WithRequestID(WithActor(context.Background(), "integration-test"), env.NewID())
 
 	// Create a task
 	task := &Task{
 		ID:          "1",
 		Title:       "Integration test task",
 		Description: "Testing task operations",
-		Status:      "pending",
+		Status:      StatusPending,
 	}
 
-	fmt.Println("\nRunning integration test scenarios:")
+	fmt.Fprintln(env, "\nRunning integration test scenarios:")
 
 	// Test scenario 1: Create and retrieve
-	fmt.Println("\nScenario 1: Create and retrieve task")
-	if err := service.Create(context.Background(), task); err != nil {
+	fmt.Fprintln(env, "\nScenario 1: Create and retrieve task")
+	if err := service.Create(ctx, task); err != nil {
 		return fmt.Errorf("failed to create task: %w", err)
 	}
 
-	retrieved, err := service.Get(context.Background(), task.ID)
+	retrieved, err := service.Get(ctx, task.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get task: %w", err)
 	}
-	fmt.Printf("Retrieved task: %+v\n", retrieved)
+	fmt.Fprintf(env, "Retrieved task: %+v\n", retrieved)
 
 	// Test scenario 2: Update task
-	fmt.Println("\nScenario 2: Update task")
-	task.Status = "completed"
-	if err := service.Update(context.Background(), task); err != nil {
+	fmt.Fprintln(env, "\nScenario 2: Update task")
+	task.Status = StatusInProgress
+	if err := service.Update(ctx, task); err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
-	updated, err := service.Get(context.Background(), task.ID)
+	if _, err := service.Transition(ctx, task.ID, StatusCompleted); err != nil {
+		return fmt.Errorf("failed to complete task: %w", err)
+	}
+
+	updated, err := service.Get(ctx, task.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get updated task: %w", err)
 	}
-	fmt.Printf("Updated task: %+v\n", updated)
+	fmt.Fprintf(env, "Updated task: %+v\n", updated)
+
+	// Completed tasks are final
+	_, err = service.Transition(ctx, task.ID, StatusPending)
+	var transitionErr *TransitionError
+	if !errors.As(err, &transitionErr) {
+		return fmt.Errorf("reopening a completed task should fail, got %v", err)
+	}
+	fmt.Fprintf(env, "Reopening rejected: %v\n", err)
 
 	// Test scenario 3: List tasks
-	fmt.Println("\nScenario 3: List tasks")
-	tasks, err := service.List(context.Background())
+	fmt.Fprintln(env, "\nScenario 3: List tasks")
+	for i, title := range []string{"Write docs", "Review code", "Deploy release"} {
+		extra := &Task{ID: fmt.Sprint(i + 2), Title: title, Status: StatusPending}
+		if err := service.Create(ctx, extra); err != nil {
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+	}
+
+	all, err := service.List(ctx, ListOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to list tasks: %w", err)
 	}
-	fmt.Printf("Total tasks: %d\n", len(tasks))
+	fmt.Fprintf(env, "Total tasks: %d\n", len(all.Tasks))
+
+	listOpts := ListOptions{Status: StatusPending, SortBy: SortByTitle, Limit: 2}
+	for pageNum := 1; ; pageNum++ {
+		page, err := service.List(ctx, listOpts)
+		if err != nil {
+			return fmt.Errorf("failed to list tasks: %w", err)
+		}
+		fmt.Fprintf(env, "Pending tasks by title, page %d:", pageNum)
+		for _, t := range page.Tasks {
+			fmt.Fprintf(env, " %q", t.Title)
+		}
+		fmt.Fprintln(env)
+		if page.NextCursor == "" {
+			break
+		}
+		listOpts.Cursor = page.NextCursor
+	}
 
 	// Test scenario 4: Delete task
-	fmt.Println("\nScenario 4: Delete task")
-	if err := service.Delete(context.Background(), task.ID); err != nil {
+	fmt.Fprintln(env, "\nScenario 4: Delete task")
+	if err := service.Delete(ctx, task.ID); err != nil {
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
-	_, err = service.Get(context.Background(), task.ID)
+	_, err = service.Get(ctx, task.ID)
 	if err == nil {
 		return fmt.Errorf("task should be deleted")
 	}
-	fmt.Printf("Task deleted successfully, error on retrieval: %v\n", err)
+	fmt.Fprintf(env, "Task deleted successfully, error on retrieval: %v\n", err)
+
+	// Test scenario 5: Cancelled request
+	fmt.Fprintln(env, "\nScenario 5: Cancelled request")
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = service.Create(cancelled, &Task{ID: "5", Title: "Never stored", Status: StatusPending})
+	if !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("create with a cancelled context should fail, got %v", err)
+	}
+	fmt.Fprintf(env, "Create rejected: %v\n", err)
+
+	// Test scenario 6: Audit trail
+	fmt.Fprintln(env, "\nScenario 6: Audit trail for task 1")
+	for _, e := range audit.Events(WatchFilter{TaskID: task.ID}) {
+		fmt.Fprintf(env, "%s %s by %s:", e.At.Format(time.RFC3339), e.Kind, e.Actor)
+		for _, c := range e.Diff {
+			fmt.Fprintf(env, " %s %q -> %q", c.Field, c.Before, c.After)
+		}
+		fmt.Fprintln(env)
+	}
+
+	// Test scenario 7: Plan work
+	fmt.Fprintln(env, "\nScenario 7: Plan work")
+	release := &Task{ID: "6", Title: "Tag release", Status: StatusPending, Priority: PriorityUrgent, BlockedBy: []string{"2", "3"}}
+	if err := service.Create(ctx, release); err != nil {
+		return fmt.Errorf("failed to create task: %w", err)
+	}
+	review, err := service.Get(ctx, "3")
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	review.Priority = PriorityHigh
+	review.Tags = []string{"review"}
+	if err := service.Update(ctx, review); err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	next, err := service.Next(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("failed to plan tasks: %w", err)
+	}
+	for i, t := range next {
+		fmt.Fprintf(env, "%d. %s [%s] blocked by %v\n", i+1, t.Title, t.Priority, t.BlockedBy)
+	}
+
+	// Making the review wait on the release would deadlock them
+	review.BlockedBy = []string{release.ID}
+	err = service.Update(ctx, review)
+	var cycleErr *DependencyCycleError
+	if !errors.As(err, &cycleErr) {
+		return fmt.Errorf("a dependency cycle should be rejected, got %v", err)
+	}
+	fmt.Fprintf(env, "Cycle rejected: %v\n", err)
 
 	return nil
 }
@@ -0,0 +1,161 @@
+package example5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+	"practice/examples/tabletest/tabletesttest"
+)
+
+func TestCreateTask(t *testing.T) {
+	tabletesttest.Run(t, createTaskTable(NewTaskService()))
+}
+
+// newListService returns a service holding tasks 1..n, created a minute
+// apart from demo.Epoch, with every third task completed
+func newListService(t *testing.T, n int) TaskService {
+	t.Helper()
+	service := NewTaskService(WithClock(demo.StepClock(demo.Epoch, time.Minute)))
+	for i := 1; i <= n; i++ {
+		status := StatusPending
+		if i%3 == 0 {
+			status = StatusCompleted
+		}
+		task := &Task{ID: fmt.Sprintf("%02d", i), Title: fmt.Sprintf("Task %c", 'a'+n-i), Status: status}
+		if err := service.Create(context.Background(), task); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	return service
+}
+
+func ids(tasks []*Task) []string {
+	result := make([]string, len(tasks))
+	for i, task := range tasks {
+		result[i] = task.ID
+	}
+	return result
+}
+
+func TestListFiltersAndSorts(t *testing.T) {
+	service := newListService(t, 6)
+	at := func(minutes int) time.Time { return demo.Epoch.Add(time.Duration(minutes) * time.Minute) }
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"default is oldest first", ListOptions{}, []string{"01", "02", "03", "04", "05", "06"}},
+		{"descending", ListOptions{Descending: true}, []string{"06", "05", "04", "03", "02", "01"}},
+		{"by title", ListOptions{SortBy: SortByTitle}, []string{"06", "05", "04", "03", "02", "01"}},
+		{"status", ListOptions{Status: StatusCompleted}, []string{"03", "06"}},
+		{"title substring ignores case", ListOptions{TitleContains: "TASK B"}, []string{"05"}},
+		{"created range", ListOptions{Created: TimeRange{From: at(1), To: at(3)}}, []string{"02", "03"}},
+		{"updated range open start", ListOptions{Updated: TimeRange{To: at(2)}}, []string{"01", "02"}},
+		{"combined", ListOptions{Status: StatusPending, SortBy: SortByUpdatedAt, Descending: true}, []string{"05", "04", "02", "01"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := service.List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if got := ids(page.Tasks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List = %v, want %v", got, tt.want)
+			}
+			if page.NextCursor != "" {
+				t.Errorf("NextCursor = %q, want empty without a limit", page.NextCursor)
+			}
+		})
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	service := newListService(t, 7)
+
+	var got []string
+	opts := ListOptions{SortBy: SortByTitle, Limit: 3}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination does not terminate")
+		}
+		page, err := service.List(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		got = append(got, ids(page.Tasks)...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if want := []string{"07", "06", "05", "04", "03", "02", "01"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pages = %v, want %v", got, want)
+	}
+}
+
+func TestListCursorStableAcrossChanges(t *testing.T) {
+	ctx := context.Background()
+	service := newListService(t, 6)
+
+	first, err := service.List(ctx, ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got := ids(first.Tasks); !reflect.DeepEqual(got, []string{"01", "02"}) {
+		t.Fatalf("first page = %v", got)
+	}
+
+	// Delete the last task seen and one not yet seen, and add a new one.
+	// The next page carries on after "02" without skipping or repeating.
+	for _, id := range []string{"02", "04"} {
+		if err := service.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	if err := service.Create(ctx, &Task{ID: "07", Title: "Late task", Status: StatusPending}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	rest, err := service.List(ctx, ListOptions{Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if got, want := ids(rest.Tasks), []string{"03", "05", "06", "07"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("second page = %v, want %v", got, want)
+	}
+}
+
+func TestListRejectsInvalidOptions(t *testing.T) {
+	service := newListService(t, 3)
+	page, err := service.List(context.Background(), ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want error
+	}{
+		{"unknown sort field", ListOptions{SortBy: "priority"}, ErrInvalidInput},
+		{"negative limit", ListOptions{Limit: -1}, ErrInvalidInput},
+		{"garbage cursor", ListOptions{Cursor: "not a cursor!"}, ErrInvalidCursor},
+		{"cursor for other filters", ListOptions{Status: StatusPending, Cursor: page.NextCursor}, ErrInvalidCursor},
+		{"cursor for other order", ListOptions{Descending: true, Cursor: page.NextCursor}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.List(context.Background(), tt.opts)
+			if !errors.Is(err, tt.want) || !errors.Is(err, ErrInvalidInput) {
+				t.Errorf("List error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
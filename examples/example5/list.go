@@ -0,0 +1,273 @@
+package example5
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was issued
+// for different list options. It wraps ErrInvalidInput.
+var ErrInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+
+// SortField is the task field List orders by
+type SortField string
+
+// Sortable fields
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+	SortByTitle     SortField = "title"
+)
+
+// TimeRange matches times in [From, To). A zero bound is open.
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// Contains reports whether t falls in the range
+func (r TimeRange) Contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !t.Before(r.To) {
+		return false
+	}
+	return true
+}
+
+// ListOptions filters, orders and pages the tasks List returns. The zero
+// value lists every task, oldest first.
+type ListOptions struct {
+	// Status matches exactly; empty matches every status
+	Status Status
+
+	// TitleContains matches a case-insensitive substring of the title
+	TitleContains string
+
+	Created TimeRange
+	Updated TimeRange
+
+	// Tags matches tasks carrying every one of these tags, in any case
+	Tags []string
+
+	// MinPriority matches tasks of at least this priority
+	MinPriority Priority
+
+	// Due matches due dates in the range. A bounded range never matches a
+	// task without a due date.
+	Due TimeRange
+
+	// Overdue matches tasks for which Task.Overdue is true at the time of
+	// the call
+	Overdue bool
+
+	// SortBy defaults to SortByCreatedAt. Ties are broken by ID.
+	SortBy     SortField
+	Descending bool
+
+	// Limit is the page size; 0 returns all remaining tasks
+	Limit int
+
+	// Cursor continues after the last task of a previous page. It is only
+	// valid with the same filters and order it was issued for.
+	Cursor string
+
+	// asOf is the time Overdue is judged at, read from the service clock
+	asOf time.Time
+}
+
+// TaskPage is one page of List results
+type TaskPage struct {
+	Tasks []*Task
+
+	// NextCursor fetches the following page; empty on the last page
+	NextCursor string
+}
+
+// cursor is the decoded form of a continuation token: the sort key and ID
+// of the last task returned. Resuming from a key rather than an offset
+// keeps pages stable while tasks are inserted or deleted.
+type cursor struct {
+	Options uint64    `json:"o"`
+	Time    time.Time `json:"t,omitempty"`
+	Title   string    `json:"n,omitempty"`
+	ID      string    `json:"i"`
+}
+
+// List returns the tasks matching opts, in order, one page at a time
+func (s *taskService) List(ctx context.Context, opts ListOptions) (*TaskPage, error) {
+	if err := checkContext(ctx, "list tasks"); err != nil {
+		return nil, err
+	}
+	after, err := opts.prepare(s.now)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tasks := make([]*Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		// A large scan stops as soon as the caller gives up
+		if err := checkContext(ctx, "list tasks"); err != nil {
+			return nil, err
+		}
+		if opts.matches(task) && (after == nil || opts.less(after, task)) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return opts.less(tasks[i], tasks[j])
+	})
+
+	page := opts.page(tasks)
+	for i, task := range page.Tasks {
+		page.Tasks[i] = task.clone()
+	}
+	return page, nil
+}
+
+// prepare applies defaults and validates opts, reading now only for an
+// Overdue query. It returns a stand-in for the last task of the previous
+// page, or nil on the first page.
+func (o *ListOptions) prepare(now func() time.Time) (*Task, error) {
+	if o.SortBy == "" {
+		o.SortBy = SortByCreatedAt
+	}
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(o.Tags))
+	for i, tag := range o.Tags {
+		tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	o.Tags = sortedSet(tags)
+	if o.Overdue {
+		o.asOf = now()
+	}
+	if o.Cursor == "" {
+		return nil, nil
+	}
+	return o.decodeCursor()
+}
+
+// page cuts the matching tasks after the cursor, already in order, down to
+// one page
+func (o ListOptions) page(tasks []*Task) *TaskPage {
+	page := &TaskPage{Tasks: tasks}
+	if o.Limit > 0 && len(tasks) > o.Limit {
+		page.Tasks = tasks[:o.Limit]
+		page.NextCursor = o.encodeCursor(page.Tasks[o.Limit-1])
+	}
+	return page
+}
+
+func (o ListOptions) validate() error {
+	switch o.SortBy {
+	case SortByCreatedAt, SortByUpdatedAt, SortByTitle:
+	default:
+		return fmt.Errorf("%w: cannot sort by %q", ErrInvalidInput, o.SortBy)
+	}
+	if o.Limit < 0 {
+		return fmt.Errorf("%w: limit cannot be negative", ErrInvalidInput)
+	}
+	if !o.MinPriority.Valid() {
+		return fmt.Errorf("%w: unknown priority %d", ErrInvalidInput, int(o.MinPriority))
+	}
+	return nil
+}
+
+func (o ListOptions) matches(task *Task) bool {
+	if o.Status != "" && task.Status != o.Status {
+		return false
+	}
+	if o.TitleContains != "" && !strings.Contains(strings.ToLower(task.Title), strings.ToLower(o.TitleContains)) {
+		return false
+	}
+	if !o.Created.Contains(task.CreatedAt) || !o.Updated.Contains(task.UpdatedAt) {
+		return false
+	}
+	for _, tag := range o.Tags {
+		if !slices.Contains(task.Tags, tag) {
+			return false
+		}
+	}
+	if task.Priority < o.MinPriority {
+		return false
+	}
+	if o.Due != (TimeRange{}) && (task.Due == nil || !o.Due.Contains(*task.Due)) {
+		return false
+	}
+	return !o.Overdue || task.Overdue(o.asOf)
+}
+
+// less orders tasks by the sort field, then by ID
+func (o ListOptions) less(a, b *Task) bool {
+	c := 0
+	switch o.SortBy {
+	case SortByCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case SortByUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case SortByTitle:
+		c = strings.Compare(a.Title, b.Title)
+	}
+	if c == 0 {
+		c = strings.Compare(a.ID, b.ID)
+	}
+	if o.Descending {
+		return c > 0
+	}
+	return c < 0
+}
+
+// fingerprint identifies the filters and order a cursor belongs to
+func (o ListOptions) fingerprint() uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%v\x00%d\x00%d\x00%d\x00%d\x00%s\x00%d\x00%d\x00%d\x00%v",
+		o.Status, o.TitleContains, o.SortBy, o.Descending,
+		o.Created.From.UnixNano(), o.Created.To.UnixNano(),
+		o.Updated.From.UnixNano(), o.Updated.To.UnixNano(),
+		strings.Join(o.Tags, "\x00"), o.MinPriority,
+		o.Due.From.UnixNano(), o.Due.To.UnixNano(), o.Overdue)
+	return h.Sum64()
+}
+
+func (o ListOptions) encodeCursor(last *Task) string {
+	c := cursor{Options: o.fingerprint(), ID: last.ID}
+	switch o.SortBy {
+	case SortByCreatedAt:
+		c.Time = last.CreatedAt
+	case SortByUpdatedAt:
+		c.Time = last.UpdatedAt
+	case SortByTitle:
+		c.Title = last.Title
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns a stand-in for the last task of the previous page,
+// carrying just the fields less compares
+func (o ListOptions) decodeCursor() (*Task, error) {
+	data, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Options != o.fingerprint() {
+		return nil, fmt.Errorf("%w: issued for different list options", ErrInvalidCursor)
+	}
+	return &Task{ID: c.ID, Title: c.Title, CreatedAt: c.Time, UpdatedAt: c.Time}, nil
+}
@@ -0,0 +1,823 @@
+// Code generated by mockgen from practice/examples/example5. DO NOT EDIT.
+
+// Package mocks holds test doubles for the interfaces of practice/examples/example5.
+package mocks
+
+import (
+	"context"
+
+	"practice/examples/example5"
+	"practice/examples/mock"
+)
+
+// AuditLog is a test double for example5.AuditLog
+type AuditLog struct {
+	recorder mock.Recorder
+
+	// AppendFunc, if set, handles Append calls that match no expectation
+	AppendFunc func(ctx context.Context, event example5.ChangeEvent) error
+}
+
+var _ example5.AuditLog = (*AuditLog)(nil)
+
+// NewAuditLog returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewAuditLog(t mock.TB) *AuditLog {
+	m := &AuditLog{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *AuditLog) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Append implements example5.AuditLog
+func (m *AuditLog) Append(ctx context.Context, event example5.ChangeEvent) error {
+	if results, ok := m.recorder.Called("Append", ctx, event); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.AppendFunc != nil {
+		return m.AppendFunc(ctx, event)
+	}
+	m.recorder.Unexpected("Append", ctx, event)
+	var r0 error
+	return r0
+}
+
+// AuditLogAppendCall is an expectation on AuditLog.Append
+type AuditLogAppendCall struct {
+	e *mock.Expectation
+}
+
+// ExpectAppend expects a call to Append whose arguments match the given
+// matchers or equal the given values
+func (m *AuditLog) ExpectAppend(ctx, event interface{}) AuditLogAppendCall {
+	return AuditLogAppendCall{m.recorder.Expect("Append", 1, ctx, event)}
+}
+
+// Return sets the values Append returns
+func (c AuditLogAppendCall) Return(r0 error) AuditLogAppendCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Append returns from its arguments
+func (c AuditLogAppendCall) Do(fn func(ctx context.Context, event example5.ChangeEvent) error) AuditLogAppendCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		event, _ := args[1].(example5.ChangeEvent)
+		r0 := fn(ctx, event)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c AuditLogAppendCall) Times(n int) AuditLogAppendCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c AuditLogAppendCall) AnyTimes() AuditLogAppendCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// AuditLogAppendArgs holds the arguments of one Append call
+type AuditLogAppendArgs struct {
+	Ctx   context.Context
+	Event example5.ChangeEvent
+}
+
+// AppendCalls returns the arguments of every Append call in order
+func (m *AuditLog) AppendCalls() []AuditLogAppendArgs {
+	var out []AuditLogAppendArgs
+	for _, call := range m.recorder.Calls("Append") {
+		var args AuditLogAppendArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Event, _ = call.Args[1].(example5.ChangeEvent)
+		out = append(out, args)
+	}
+	return out
+}
+
+// TaskService is a test double for example5.TaskService
+type TaskService struct {
+	recorder mock.Recorder
+
+	// CreateFunc, if set, handles Create calls that match no expectation
+	CreateFunc func(ctx context.Context, task *example5.Task) error
+
+	// DeleteFunc, if set, handles Delete calls that match no expectation
+	DeleteFunc func(ctx context.Context, id string) error
+
+	// GetFunc, if set, handles Get calls that match no expectation
+	GetFunc func(ctx context.Context, id string) (*example5.Task, error)
+
+	// ListFunc, if set, handles List calls that match no expectation
+	ListFunc func(ctx context.Context, opts example5.ListOptions) (*example5.TaskPage, error)
+
+	// NextFunc, if set, handles Next calls that match no expectation
+	NextFunc func(ctx context.Context, limit int) ([]*example5.Task, error)
+
+	// SearchFunc, if set, handles Search calls that match no expectation
+	SearchFunc func(ctx context.Context, query string, limit int) ([]example5.SearchResult, error)
+
+	// TransitionFunc, if set, handles Transition calls that match no expectation
+	TransitionFunc func(ctx context.Context, id string, to example5.Status) (*example5.Task, error)
+
+	// UpdateFunc, if set, handles Update calls that match no expectation
+	UpdateFunc func(ctx context.Context, task *example5.Task) error
+
+	// WatchFunc, if set, handles Watch calls that match no expectation
+	WatchFunc func(ctx context.Context, filter example5.WatchFilter) (<-chan example5.ChangeEvent, error)
+}
+
+var _ example5.TaskService = (*TaskService)(nil)
+
+// NewTaskService returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewTaskService(t mock.TB) *TaskService {
+	m := &TaskService{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *TaskService) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Create implements example5.TaskService
+func (m *TaskService) Create(ctx context.Context, task *example5.Task) error {
+	if results, ok := m.recorder.Called("Create", ctx, task); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.CreateFunc != nil {
+		return m.CreateFunc(ctx, task)
+	}
+	m.recorder.Unexpected("Create", ctx, task)
+	var r0 error
+	return r0
+}
+
+// TaskServiceCreateCall is an expectation on TaskService.Create
+type TaskServiceCreateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectCreate expects a call to Create whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectCreate(ctx, task interface{}) TaskServiceCreateCall {
+	return TaskServiceCreateCall{m.recorder.Expect("Create", 1, ctx, task)}
+}
+
+// Return sets the values Create returns
+func (c TaskServiceCreateCall) Return(r0 error) TaskServiceCreateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Create returns from its arguments
+func (c TaskServiceCreateCall) Do(fn func(ctx context.Context, task *example5.Task) error) TaskServiceCreateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		task, _ := args[1].(*example5.Task)
+		r0 := fn(ctx, task)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceCreateCall) Times(n int) TaskServiceCreateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceCreateCall) AnyTimes() TaskServiceCreateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceCreateArgs holds the arguments of one Create call
+type TaskServiceCreateArgs struct {
+	Ctx  context.Context
+	Task *example5.Task
+}
+
+// CreateCalls returns the arguments of every Create call in order
+func (m *TaskService) CreateCalls() []TaskServiceCreateArgs {
+	var out []TaskServiceCreateArgs
+	for _, call := range m.recorder.Calls("Create") {
+		var args TaskServiceCreateArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Task, _ = call.Args[1].(*example5.Task)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Delete implements example5.TaskService
+func (m *TaskService) Delete(ctx context.Context, id string) error {
+	if results, ok := m.recorder.Called("Delete", ctx, id); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, id)
+	}
+	m.recorder.Unexpected("Delete", ctx, id)
+	var r0 error
+	return r0
+}
+
+// TaskServiceDeleteCall is an expectation on TaskService.Delete
+type TaskServiceDeleteCall struct {
+	e *mock.Expectation
+}
+
+// ExpectDelete expects a call to Delete whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectDelete(ctx, id interface{}) TaskServiceDeleteCall {
+	return TaskServiceDeleteCall{m.recorder.Expect("Delete", 1, ctx, id)}
+}
+
+// Return sets the values Delete returns
+func (c TaskServiceDeleteCall) Return(r0 error) TaskServiceDeleteCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Delete returns from its arguments
+func (c TaskServiceDeleteCall) Do(fn func(ctx context.Context, id string) error) TaskServiceDeleteCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		id, _ := args[1].(string)
+		r0 := fn(ctx, id)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceDeleteCall) Times(n int) TaskServiceDeleteCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceDeleteCall) AnyTimes() TaskServiceDeleteCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceDeleteArgs holds the arguments of one Delete call
+type TaskServiceDeleteArgs struct {
+	Ctx context.Context
+	ID  string
+}
+
+// DeleteCalls returns the arguments of every Delete call in order
+func (m *TaskService) DeleteCalls() []TaskServiceDeleteArgs {
+	var out []TaskServiceDeleteArgs
+	for _, call := range m.recorder.Calls("Delete") {
+		var args TaskServiceDeleteArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.ID, _ = call.Args[1].(string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Get implements example5.TaskService
+func (m *TaskService) Get(ctx context.Context, id string) (*example5.Task, error) {
+	if results, ok := m.recorder.Called("Get", ctx, id); ok {
+		r0, _ := results[0].(*example5.Task)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.GetFunc != nil {
+		return m.GetFunc(ctx, id)
+	}
+	m.recorder.Unexpected("Get", ctx, id)
+	var r0 *example5.Task
+	var r1 error
+	return r0, r1
+}
+
+// TaskServiceGetCall is an expectation on TaskService.Get
+type TaskServiceGetCall struct {
+	e *mock.Expectation
+}
+
+// ExpectGet expects a call to Get whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectGet(ctx, id interface{}) TaskServiceGetCall {
+	return TaskServiceGetCall{m.recorder.Expect("Get", 2, ctx, id)}
+}
+
+// Return sets the values Get returns
+func (c TaskServiceGetCall) Return(r0 *example5.Task, r1 error) TaskServiceGetCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Get returns from its arguments
+func (c TaskServiceGetCall) Do(fn func(ctx context.Context, id string) (*example5.Task, error)) TaskServiceGetCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		id, _ := args[1].(string)
+		r0, r1 := fn(ctx, id)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceGetCall) Times(n int) TaskServiceGetCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceGetCall) AnyTimes() TaskServiceGetCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceGetArgs holds the arguments of one Get call
+type TaskServiceGetArgs struct {
+	Ctx context.Context
+	ID  string
+}
+
+// GetCalls returns the arguments of every Get call in order
+func (m *TaskService) GetCalls() []TaskServiceGetArgs {
+	var out []TaskServiceGetArgs
+	for _, call := range m.recorder.Calls("Get") {
+		var args TaskServiceGetArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.ID, _ = call.Args[1].(string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// List implements example5.TaskService
+func (m *TaskService) List(ctx context.Context, opts example5.ListOptions) (*example5.TaskPage, error) {
+	if results, ok := m.recorder.Called("List", ctx, opts); ok {
+		r0, _ := results[0].(*example5.TaskPage)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ListFunc != nil {
+		return m.ListFunc(ctx, opts)
+	}
+	m.recorder.Unexpected("List", ctx, opts)
+	var r0 *example5.TaskPage
+	var r1 error
+	return r0, r1
+}
+
+// TaskServiceListCall is an expectation on TaskService.List
+type TaskServiceListCall struct {
+	e *mock.Expectation
+}
+
+// ExpectList expects a call to List whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectList(ctx, opts interface{}) TaskServiceListCall {
+	return TaskServiceListCall{m.recorder.Expect("List", 2, ctx, opts)}
+}
+
+// Return sets the values List returns
+func (c TaskServiceListCall) Return(r0 *example5.TaskPage, r1 error) TaskServiceListCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values List returns from its arguments
+func (c TaskServiceListCall) Do(fn func(ctx context.Context, opts example5.ListOptions) (*example5.TaskPage, error)) TaskServiceListCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		opts, _ := args[1].(example5.ListOptions)
+		r0, r1 := fn(ctx, opts)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceListCall) Times(n int) TaskServiceListCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceListCall) AnyTimes() TaskServiceListCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceListArgs holds the arguments of one List call
+type TaskServiceListArgs struct {
+	Ctx  context.Context
+	Opts example5.ListOptions
+}
+
+// ListCalls returns the arguments of every List call in order
+func (m *TaskService) ListCalls() []TaskServiceListArgs {
+	var out []TaskServiceListArgs
+	for _, call := range m.recorder.Calls("List") {
+		var args TaskServiceListArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Opts, _ = call.Args[1].(example5.ListOptions)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Next implements example5.TaskService
+func (m *TaskService) Next(ctx context.Context, limit int) ([]*example5.Task, error) {
+	if results, ok := m.recorder.Called("Next", ctx, limit); ok {
+		r0, _ := results[0].([]*example5.Task)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.NextFunc != nil {
+		return m.NextFunc(ctx, limit)
+	}
+	m.recorder.Unexpected("Next", ctx, limit)
+	var r0 []*example5.Task
+	var r1 error
+	return r0, r1
+}
+
+// TaskServiceNextCall is an expectation on TaskService.Next
+type TaskServiceNextCall struct {
+	e *mock.Expectation
+}
+
+// ExpectNext expects a call to Next whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectNext(ctx, limit interface{}) TaskServiceNextCall {
+	return TaskServiceNextCall{m.recorder.Expect("Next", 2, ctx, limit)}
+}
+
+// Return sets the values Next returns
+func (c TaskServiceNextCall) Return(r0 []*example5.Task, r1 error) TaskServiceNextCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Next returns from its arguments
+func (c TaskServiceNextCall) Do(fn func(ctx context.Context, limit int) ([]*example5.Task, error)) TaskServiceNextCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		limit, _ := args[1].(int)
+		r0, r1 := fn(ctx, limit)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceNextCall) Times(n int) TaskServiceNextCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceNextCall) AnyTimes() TaskServiceNextCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceNextArgs holds the arguments of one Next call
+type TaskServiceNextArgs struct {
+	Ctx   context.Context
+	Limit int
+}
+
+// NextCalls returns the arguments of every Next call in order
+func (m *TaskService) NextCalls() []TaskServiceNextArgs {
+	var out []TaskServiceNextArgs
+	for _, call := range m.recorder.Calls("Next") {
+		var args TaskServiceNextArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Limit, _ = call.Args[1].(int)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Search implements example5.TaskService
+func (m *TaskService) Search(ctx context.Context, query string, limit int) ([]example5.SearchResult, error) {
+	if results, ok := m.recorder.Called("Search", ctx, query, limit); ok {
+		r0, _ := results[0].([]example5.SearchResult)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.SearchFunc != nil {
+		return m.SearchFunc(ctx, query, limit)
+	}
+	m.recorder.Unexpected("Search", ctx, query, limit)
+	var r0 []example5.SearchResult
+	var r1 error
+	return r0, r1
+}
+
+// TaskServiceSearchCall is an expectation on TaskService.Search
+type TaskServiceSearchCall struct {
+	e *mock.Expectation
+}
+
+// ExpectSearch expects a call to Search whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectSearch(ctx, query, limit interface{}) TaskServiceSearchCall {
+	return TaskServiceSearchCall{m.recorder.Expect("Search", 2, ctx, query, limit)}
+}
+
+// Return sets the values Search returns
+func (c TaskServiceSearchCall) Return(r0 []example5.SearchResult, r1 error) TaskServiceSearchCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Search returns from its arguments
+func (c TaskServiceSearchCall) Do(fn func(ctx context.Context, query string, limit int) ([]example5.SearchResult, error)) TaskServiceSearchCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		query, _ := args[1].(string)
+		limit, _ := args[2].(int)
+		r0, r1 := fn(ctx, query, limit)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceSearchCall) Times(n int) TaskServiceSearchCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceSearchCall) AnyTimes() TaskServiceSearchCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceSearchArgs holds the arguments of one Search call
+type TaskServiceSearchArgs struct {
+	Ctx   context.Context
+	Query string
+	Limit int
+}
+
+// SearchCalls returns the arguments of every Search call in order
+func (m *TaskService) SearchCalls() []TaskServiceSearchArgs {
+	var out []TaskServiceSearchArgs
+	for _, call := range m.recorder.Calls("Search") {
+		var args TaskServiceSearchArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Query, _ = call.Args[1].(string)
+		args.Limit, _ = call.Args[2].(int)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Transition implements example5.TaskService
+func (m *TaskService) Transition(ctx context.Context, id string, to example5.Status) (*example5.Task, error) {
+	if results, ok := m.recorder.Called("Transition", ctx, id, to); ok {
+		r0, _ := results[0].(*example5.Task)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.TransitionFunc != nil {
+		return m.TransitionFunc(ctx, id, to)
+	}
+	m.recorder.Unexpected("Transition", ctx, id, to)
+	var r0 *example5.Task
+	var r1 error
+	return r0, r1
+}
+
+// TaskServiceTransitionCall is an expectation on TaskService.Transition
+type TaskServiceTransitionCall struct {
+	e *mock.Expectation
+}
+
+// ExpectTransition expects a call to Transition whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectTransition(ctx, id, to interface{}) TaskServiceTransitionCall {
+	return TaskServiceTransitionCall{m.recorder.Expect("Transition", 2, ctx, id, to)}
+}
+
+// Return sets the values Transition returns
+func (c TaskServiceTransitionCall) Return(r0 *example5.Task, r1 error) TaskServiceTransitionCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Transition returns from its arguments
+func (c TaskServiceTransitionCall) Do(fn func(ctx context.Context, id string, to example5.Status) (*example5.Task, error)) TaskServiceTransitionCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		id, _ := args[1].(string)
+		to, _ := args[2].(example5.Status)
+		r0, r1 := fn(ctx, id, to)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceTransitionCall) Times(n int) TaskServiceTransitionCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceTransitionCall) AnyTimes() TaskServiceTransitionCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceTransitionArgs holds the arguments of one Transition call
+type TaskServiceTransitionArgs struct {
+	Ctx context.Context
+	ID  string
+	To  example5.Status
+}
+
+// TransitionCalls returns the arguments of every Transition call in order
+func (m *TaskService) TransitionCalls() []TaskServiceTransitionArgs {
+	var out []TaskServiceTransitionArgs
+	for _, call := range m.recorder.Calls("Transition") {
+		var args TaskServiceTransitionArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.ID, _ = call.Args[1].(string)
+		args.To, _ = call.Args[2].(example5.Status)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Update implements example5.TaskService
+func (m *TaskService) Update(ctx context.Context, task *example5.Task) error {
+	if results, ok := m.recorder.Called("Update", ctx, task); ok {
+		r0, _ := results[0].(error)
+		return r0
+	}
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, task)
+	}
+	m.recorder.Unexpected("Update", ctx, task)
+	var r0 error
+	return r0
+}
+
+// TaskServiceUpdateCall is an expectation on TaskService.Update
+type TaskServiceUpdateCall struct {
+	e *mock.Expectation
+}
+
+// ExpectUpdate expects a call to Update whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectUpdate(ctx, task interface{}) TaskServiceUpdateCall {
+	return TaskServiceUpdateCall{m.recorder.Expect("Update", 1, ctx, task)}
+}
+
+// Return sets the values Update returns
+func (c TaskServiceUpdateCall) Return(r0 error) TaskServiceUpdateCall {
+	c.e.Return(r0)
+	return c
+}
+
+// Do computes the values Update returns from its arguments
+func (c TaskServiceUpdateCall) Do(fn func(ctx context.Context, task *example5.Task) error) TaskServiceUpdateCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		task, _ := args[1].(*example5.Task)
+		r0 := fn(ctx, task)
+		return []interface{}{r0}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceUpdateCall) Times(n int) TaskServiceUpdateCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceUpdateCall) AnyTimes() TaskServiceUpdateCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceUpdateArgs holds the arguments of one Update call
+type TaskServiceUpdateArgs struct {
+	Ctx  context.Context
+	Task *example5.Task
+}
+
+// UpdateCalls returns the arguments of every Update call in order
+func (m *TaskService) UpdateCalls() []TaskServiceUpdateArgs {
+	var out []TaskServiceUpdateArgs
+	for _, call := range m.recorder.Calls("Update") {
+		var args TaskServiceUpdateArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Task, _ = call.Args[1].(*example5.Task)
+		out = append(out, args)
+	}
+	return out
+}
+
+// Watch implements example5.TaskService
+func (m *TaskService) Watch(ctx context.Context, filter example5.WatchFilter) (<-chan example5.ChangeEvent, error) {
+	if results, ok := m.recorder.Called("Watch", ctx, filter); ok {
+		r0, _ := results[0].(<-chan example5.ChangeEvent)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.WatchFunc != nil {
+		return m.WatchFunc(ctx, filter)
+	}
+	m.recorder.Unexpected("Watch", ctx, filter)
+	var r0 <-chan example5.ChangeEvent
+	var r1 error
+	return r0, r1
+}
+
+// TaskServiceWatchCall is an expectation on TaskService.Watch
+type TaskServiceWatchCall struct {
+	e *mock.Expectation
+}
+
+// ExpectWatch expects a call to Watch whose arguments match the given
+// matchers or equal the given values
+func (m *TaskService) ExpectWatch(ctx, filter interface{}) TaskServiceWatchCall {
+	return TaskServiceWatchCall{m.recorder.Expect("Watch", 2, ctx, filter)}
+}
+
+// Return sets the values Watch returns
+func (c TaskServiceWatchCall) Return(r0 <-chan example5.ChangeEvent, r1 error) TaskServiceWatchCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Watch returns from its arguments
+func (c TaskServiceWatchCall) Do(fn func(ctx context.Context, filter example5.WatchFilter) (<-chan example5.ChangeEvent, error)) TaskServiceWatchCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		filter, _ := args[1].(example5.WatchFilter)
+		r0, r1 := fn(ctx, filter)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c TaskServiceWatchCall) Times(n int) TaskServiceWatchCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c TaskServiceWatchCall) AnyTimes() TaskServiceWatchCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// TaskServiceWatchArgs holds the arguments of one Watch call
+type TaskServiceWatchArgs struct {
+	Ctx    context.Context
+	Filter example5.WatchFilter
+}
+
+// WatchCalls returns the arguments of every Watch call in order
+func (m *TaskService) WatchCalls() []TaskServiceWatchArgs {
+	var out []TaskServiceWatchArgs
+	for _, call := range m.recorder.Calls("Watch") {
+		var args TaskServiceWatchArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Filter, _ = call.Args[1].(example5.WatchFilter)
+		out = append(out, args)
+	}
+	return out
+}
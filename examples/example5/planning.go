@@ -0,0 +1,312 @@
+package example5
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrDependencyCycle is wrapped by every DependencyCycleError. It wraps
+// ErrInvalidInput.
+var ErrDependencyCycle = fmt.Errorf("%w: dependency cycle", ErrInvalidInput)
+
+// Priority ranks tasks for Next; higher is more urgent. It is written as
+// its name in JSON.
+type Priority int
+
+// Priorities, least urgent first. The zero value is PriorityNone.
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+// ParsePriority returns the priority with the given name
+func ParsePriority(name string) (Priority, error) {
+	if i := slices.Index(priorityNames, name); i >= 0 {
+		return Priority(i), nil
+	}
+	return PriorityNone, fmt.Errorf("%w: unknown priority %q", ErrInvalidInput, name)
+}
+
+// Valid reports whether p is one of the defined priorities
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityUrgent
+}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+// MarshalText writes the priority's name
+func (p Priority) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("%w: unknown priority %d", ErrInvalidInput, int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText reads a priority's name
+func (p *Priority) UnmarshalText(text []byte) error {
+	parsed, err := ParsePriority(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// Overdue reports whether t has a due date before now and is not final
+func (t *Task) Overdue(now time.Time) bool {
+	return t.Due != nil && t.Due.Before(now) && !t.Status.Final()
+}
+
+// validatePlanning checks the fields added for planning work
+func validatePlanning(task *Task) error {
+	if !task.Priority.Valid() {
+		return fmt.Errorf("%w: unknown priority %d", ErrInvalidInput, int(task.Priority))
+	}
+	for _, tag := range task.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || strings.ContainsFunc(tag, func(r rune) bool {
+			return unicode.IsSpace(r) || unicode.IsControl(r) || r == ','
+		}) {
+			return fmt.Errorf("%w: tag %q must be a single word", ErrInvalidInput, tag)
+		}
+	}
+	for _, id := range task.BlockedBy {
+		if id == "" {
+			return fmt.Errorf("%w: dependency IDs cannot be empty", ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// normalizePlanning puts a validated task's planning fields in the form
+// services store: tags lowercased, deduplicated and sorted, dependencies
+// deduplicated and sorted, and the due date in UTC. Empty lists become nil.
+func normalizePlanning(task *Task) {
+	tags := make([]string, len(task.Tags))
+	for i, tag := range task.Tags {
+		tags[i] = strings.ToLower(strings.TrimSpace(tag))
+	}
+	task.Tags = sortedSet(tags)
+	task.BlockedBy = sortedSet(slices.Clone(task.BlockedBy))
+	if task.Due != nil {
+		due := task.Due.UTC()
+		task.Due = &due
+	}
+}
+
+// sortedSet sorts s in place and drops duplicates, returning nil if s is
+// empty
+func sortedSet(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	slices.Sort(s)
+	return slices.Compact(s)
+}
+
+// unblock returns a copy of dependent without its dependency on the
+// deleted task id, stamped as updated by ctx's actor at now
+func unblock(ctx context.Context, dependent *Task, id string, now time.Time) *Task {
+	unblocked := dependent.clone()
+	unblocked.BlockedBy = sortedSet(slices.DeleteFunc(unblocked.BlockedBy, func(dep string) bool { return dep == id }))
+	unblocked.UpdatedAt = now
+	stamp(ctx, unblocked)
+	return unblocked
+}
+
+// DependencyCycleError reports a dependency that would make a task wait,
+// directly or indirectly, on itself
+type DependencyCycleError struct {
+	// Path starts and ends with the task being saved; each task in it is
+	// blocked by the next
+	Path []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrDependencyCycle, strings.Join(e.Path, " -> "))
+}
+
+// Unwrap lets errors.Is match ErrDependencyCycle
+func (e *DependencyCycleError) Unwrap() error {
+	return ErrDependencyCycle
+}
+
+// checkDependencies checks that every task in task.BlockedBy exists and
+// that none of them already waits on task. blockers returns the
+// dependencies of a stored task, or an error wrapping ErrNotFound.
+func checkDependencies(task *Task, blockers func(id string) ([]string, error)) error {
+	visited := make(map[string]bool)
+	var path []string
+
+	// reaches reports whether task.ID can be reached from id, leaving the
+	// way there in path
+	var reaches func(id string) (bool, error)
+	reaches = func(id string) (bool, error) {
+		path = append(path, id)
+		if id == task.ID {
+			return true, nil
+		}
+		if !visited[id] {
+			visited[id] = true
+			deps, err := blockers(id)
+			if err != nil {
+				return false, err
+			}
+			for _, dep := range deps {
+				if found, err := reaches(dep); found || err != nil {
+					return found, err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false, nil
+	}
+
+	for _, dep := range task.BlockedBy {
+		if dep != task.ID {
+			if _, err := blockers(dep); errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%w: task %s is blocked by unknown task %s", ErrInvalidInput, task.ID, dep)
+			} else if err != nil {
+				return err
+			}
+		}
+		path = []string{task.ID}
+		found, err := reaches(dep)
+		if err != nil {
+			return err
+		}
+		if found {
+			return &DependencyCycleError{Path: path}
+		}
+	}
+	return nil
+}
+
+// workOrder returns the tasks of all that are not final, ordered so that
+// every task comes after the unfinished tasks blocking it. Among the tasks
+// free to start, it picks the most urgent first: statuses other than
+// blocked before blocked, then higher priority, earlier due date (tasks
+// without one last), earlier creation and lower ID. It stops after limit
+// tasks unless limit is 0.
+func workOrder(all []*Task, limit int) []*Task {
+	open := make(map[string]*Task)
+	for _, task := range all {
+		if !task.Status.Final() {
+			open[task.ID] = task
+		}
+	}
+
+	waiting := make(map[string]int)
+	dependents := make(map[string][]*Task)
+	ready := &taskHeap{}
+	for _, task := range open {
+		for _, dep := range task.BlockedBy {
+			if _, ok := open[dep]; ok {
+				waiting[task.ID]++
+				dependents[dep] = append(dependents[dep], task)
+			}
+		}
+		if waiting[task.ID] == 0 {
+			ready.tasks = append(ready.tasks, task)
+		}
+	}
+	heap.Init(ready)
+
+	var order []*Task
+	for ready.Len() > 0 && (limit == 0 || len(order) < limit) {
+		task := heap.Pop(ready).(*Task)
+		order = append(order, task)
+		for _, dependent := range dependents[task.ID] {
+			if waiting[dependent.ID]--; waiting[dependent.ID] == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+	return order
+}
+
+// taskHeap orders the tasks free to start for workOrder
+type taskHeap struct {
+	tasks []*Task
+}
+
+func (h *taskHeap) Len() int { return len(h.tasks) }
+
+func (h *taskHeap) Less(i, j int) bool {
+	a, b := h.tasks[i], h.tasks[j]
+	if ab, bb := a.Status == StatusBlocked, b.Status == StatusBlocked; ab != bb {
+		return bb
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if (a.Due == nil) != (b.Due == nil) {
+		return b.Due == nil
+	}
+	if a.Due != nil && !a.Due.Equal(*b.Due) {
+		return a.Due.Before(*b.Due)
+	}
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+func (h *taskHeap) Swap(i, j int) { h.tasks[i], h.tasks[j] = h.tasks[j], h.tasks[i] }
+
+func (h *taskHeap) Push(x any) { h.tasks = append(h.tasks, x.(*Task)) }
+
+func (h *taskHeap) Pop() any {
+	last := h.tasks[len(h.tasks)-1]
+	h.tasks = h.tasks[:len(h.tasks)-1]
+	return last
+}
+
+// Next returns up to limit unfinished tasks, or all of them if limit is 0,
+// in the order they can be worked on
+func (s *taskService) Next(ctx context.Context, limit int) ([]*Task, error) {
+	if err := checkContext(ctx, "plan tasks"); err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit cannot be negative", ErrInvalidInput)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make([]*Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		all = append(all, task)
+	}
+	order := workOrder(all, limit)
+	for i, task := range order {
+		order[i] = task.clone()
+	}
+	return order, nil
+}
+
+// blockers looks up the dependencies of a stored task for
+// checkDependencies; s.mu must be held
+func (s *taskService) blockers(id string) ([]string, error) {
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: task with ID %s", ErrNotFound, id)
+	}
+	return task.BlockedBy, nil
+}
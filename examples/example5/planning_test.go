@@ -0,0 +1,37 @@
+package example5
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestPriorityJSON(t *testing.T) {
+	data, err := json.Marshal(struct{ P Priority }{PriorityUrgent})
+	if err != nil || string(data) != `{"P":"urgent"}` {
+		t.Fatalf("Marshal = %s, %v", data, err)
+	}
+
+	var v struct{ P Priority }
+	if err := json.Unmarshal([]byte(`{"P":"low"}`), &v); err != nil || v.P != PriorityLow {
+		t.Errorf("Unmarshal = %v, %v, want low", v.P, err)
+	}
+	if err := json.Unmarshal([]byte(`{"P":"someday"}`), &v); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Unmarshal unknown priority error = %v, want %v", err, ErrInvalidInput)
+	}
+	if _, err := json.Marshal(struct{ P Priority }{Priority(7)}); err == nil {
+		t.Error("Marshal of an unknown priority succeeded")
+	}
+}
+
+func TestStatusFinal(t *testing.T) {
+	for s := range transitions {
+		want := s == StatusCompleted || s == StatusCancelled
+		if s.Final() != want {
+			t.Errorf("%s.Final() = %v, want %v", s, s.Final(), want)
+		}
+	}
+	if Status("unknown").Final() {
+		t.Error("an unknown status is final")
+	}
+}
@@ -0,0 +1,324 @@
+package example5
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// SearchResult is a task found by Search
+type SearchResult struct {
+	Task *Task `json:"task"`
+
+	// Score is the task's BM25 relevance to the query; higher is better.
+	// Scores only compare results of the same search.
+	Score float64 `json:"score"`
+}
+
+// BM25 parameters. titleWeight counts a match in the title as that many
+// matches in the description.
+const (
+	bm25K1      = 1.2
+	bm25B       = 0.75
+	titleWeight = 2
+)
+
+// tokenize splits text into case-folded words: runs of letters and digits
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = strings.Map(foldCase, word)
+	}
+	return words
+}
+
+// foldCase maps every rune of a case-folding orbit to the same rune, so
+// that "ſ" matches "s" and the Kelvin sign matches "k"
+func foldCase(r rune) rune {
+	return unicode.ToLower(unicode.ToUpper(r))
+}
+
+// queryClause is one part of a search query. A task matches the query if
+// it matches every clause.
+type queryClause struct {
+	// words holds one word, or the words of a phrase in order
+	words []string
+
+	// prefix makes the single word match every word starting with it
+	prefix bool
+}
+
+// parseQuery reads a query: words separated by spaces, where "word*"
+// matches words starting with "word" and "quoted words" match only next to
+// each other, in order, within the title or the description
+func parseQuery(query string) ([]queryClause, error) {
+	var clauses []queryClause
+	add := func(text string, prefix bool) {
+		words := tokenize(text)
+		switch {
+		case len(words) == 0:
+		case len(words) == 1:
+			clauses = append(clauses, queryClause{words: words, prefix: prefix})
+		default:
+			// A word the tokenizer splits, like "e-mail", is a phrase
+			clauses = append(clauses, queryClause{words: words})
+		}
+	}
+
+	rest := query
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+		if rest[0] == '"' {
+			phrase, after, ok := strings.Cut(rest[1:], `"`)
+			if !ok {
+				return nil, fmt.Errorf("%w: unterminated phrase in query %q", ErrInvalidInput, query)
+			}
+			add(phrase, false)
+			rest = after
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(rest)
+		}
+		term := rest[:end]
+		add(strings.TrimSuffix(term, "*"), strings.HasSuffix(term, "*"))
+		rest = rest[end:]
+	}
+
+	if len(clauses) == 0 {
+		return nil, fmt.Errorf("%w: query %q has no words to search for", ErrInvalidInput, query)
+	}
+	return clauses, nil
+}
+
+// searchIndex is an inverted index over the titles and descriptions of
+// tasks. It is safe for concurrent use.
+type searchIndex struct {
+	mu sync.RWMutex
+
+	// postings maps each word to the tasks containing it and the
+	// positions it has in each
+	postings map[string]map[string][]int
+
+	// words holds the keys of postings, sorted, for prefix queries
+	words []string
+
+	docs     map[string]searchDoc
+	totalLen int
+}
+
+// searchDoc is what the index knows about one task. Title words take the
+// first positions; description words follow after a gap, so that no
+// phrase spans the two.
+type searchDoc struct {
+	words    []string
+	length   int
+	titleLen int
+}
+
+// searchHit is a task ID with its score
+type searchHit struct {
+	id    string
+	score float64
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[string][]int),
+		docs:     make(map[string]searchDoc),
+	}
+}
+
+// put indexes a task, replacing what was indexed for it before
+func (x *searchIndex) put(task *Task) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(task.ID)
+
+	title, description := tokenize(task.Title), tokenize(task.Description)
+	doc := searchDoc{length: len(title) + len(description), titleLen: len(title)}
+	addWord := func(word string, pos int) {
+		docs, ok := x.postings[word]
+		if !ok {
+			docs = make(map[string][]int)
+			x.postings[word] = docs
+			i, _ := slices.BinarySearch(x.words, word)
+			x.words = slices.Insert(x.words, i, word)
+		}
+		if _, ok := docs[task.ID]; !ok {
+			doc.words = append(doc.words, word)
+		}
+		docs[task.ID] = append(docs[task.ID], pos)
+	}
+	for i, word := range title {
+		addWord(word, i)
+	}
+	for i, word := range description {
+		addWord(word, len(title)+1+i)
+	}
+
+	x.docs[task.ID] = doc
+	x.totalLen += doc.length
+}
+
+// remove drops a task from the index
+func (x *searchIndex) remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.removeLocked(id)
+}
+
+func (x *searchIndex) removeLocked(id string) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for _, word := range doc.words {
+		docs := x.postings[word]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(x.postings, word)
+			i, _ := slices.BinarySearch(x.words, word)
+			x.words = slices.Delete(x.words, i, i+1)
+		}
+	}
+	delete(x.docs, id)
+	x.totalLen -= doc.length
+}
+
+// search returns the IDs of up to limit tasks matching every clause, or
+// all of them if limit is 0, best first. Ties go to the lower ID.
+func (x *searchIndex) search(clauses []queryClause, limit int) []searchHit {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.docs) == 0 {
+		return nil
+	}
+	avgLen := float64(x.totalLen) / float64(len(x.docs))
+
+	var hits map[string]float64
+	for _, clause := range clauses {
+		freqs := x.frequencies(clause)
+		idf := math.Log(1 + (float64(len(x.docs))-float64(len(freqs))+0.5)/(float64(len(freqs))+0.5))
+
+		next := make(map[string]float64)
+		for id, tf := range freqs {
+			score, ok := hits[id]
+			if hits != nil && !ok {
+				continue
+			}
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(x.docs[id].length)/avgLen)
+			next[id] = score + idf*tf*(bm25K1+1)/(tf+norm)
+		}
+		hits = next
+	}
+
+	results := make([]searchHit, 0, len(hits))
+	for id, score := range hits {
+		results = append(results, searchHit{id: id, score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].id < results[j].id
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// frequencies returns how often each task matches clause, counting title
+// matches titleWeight times; x.mu must be held
+func (x *searchIndex) frequencies(clause queryClause) map[string]float64 {
+	freqs := make(map[string]float64)
+	weight := func(id string, pos int) float64 {
+		if pos < x.docs[id].titleLen {
+			return titleWeight
+		}
+		return 1
+	}
+
+	switch {
+	case clause.prefix:
+		start, _ := slices.BinarySearch(x.words, clause.words[0])
+		for _, word := range x.words[start:] {
+			if !strings.HasPrefix(word, clause.words[0]) {
+				break
+			}
+			for id, positions := range x.postings[word] {
+				for _, pos := range positions {
+					freqs[id] += weight(id, pos)
+				}
+			}
+		}
+
+	case len(clause.words) == 1:
+		for id, positions := range x.postings[clause.words[0]] {
+			for _, pos := range positions {
+				freqs[id] += weight(id, pos)
+			}
+		}
+
+	default:
+		for id, starts := range x.postings[clause.words[0]] {
+			for _, pos := range starts {
+				if x.phraseAt(clause.words[1:], id, pos+1) {
+					freqs[id] += weight(id, pos)
+				}
+			}
+		}
+	}
+	return freqs
+}
+
+// phraseAt reports whether task id has words in order starting at
+// position pos; x.mu must be held
+func (x *searchIndex) phraseAt(words []string, id string, pos int) bool {
+	for i, word := range words {
+		if _, found := slices.BinarySearch(x.postings[word][id], pos+i); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSearch validates the arguments of Search and parses the query
+func checkSearch(ctx context.Context, query string, limit int) ([]queryClause, error) {
+	if err := checkContext(ctx, "search tasks"); err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit cannot be negative", ErrInvalidInput)
+	}
+	return parseQuery(query)
+}
+
+// Search returns the tasks matching query, most relevant first
+func (s *taskService) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	clauses, err := checkSearch(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	hits := s.index.search(clauses, limit)
+	results := make([]SearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = SearchResult{Task: s.tasks[hit.id].clone(), Score: hit.score}
+	}
+	return results, nil
+}
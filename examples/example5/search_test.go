@@ -0,0 +1,45 @@
+package example5
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Fix the LOGIN-page, ſtatus 404 in Café\tMenu")
+	want := []string{"fix", "the", "login", "page", "status", "404", "in", "café", "menu"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	got, err := parseQuery(`Deploy doc* "Release  Notes" e-mail ** "` + "\t" + `"`)
+	if err != nil {
+		t.Fatalf("parseQuery() error = %v", err)
+	}
+	want := []queryClause{
+		{words: []string{"deploy"}},
+		{words: []string{"doc"}, prefix: true},
+		{words: []string{"release", "notes"}},
+		{words: []string{"e", "mail"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseQuery() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSearchIndexForgetsRemovedWords(t *testing.T) {
+	index := newSearchIndex()
+	index.put(&Task{ID: "1", Title: "alpha beta"})
+	index.put(&Task{ID: "2", Title: "beta"})
+	index.put(&Task{ID: "1", Title: "gamma"})
+	index.remove("2")
+
+	if want := []string{"gamma"}; !reflect.DeepEqual(index.words, want) {
+		t.Errorf("words = %v, want %v", index.words, want)
+	}
+	if index.totalLen != 1 || len(index.postings) != 1 {
+		t.Errorf("totalLen = %d, postings = %v", index.totalLen, index.postings)
+	}
+}
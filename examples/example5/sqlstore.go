@@ -0,0 +1,921 @@
+package example5
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"practice/examples/demo"
+
+	// Registers the pure-Go "sqlite" driver
+	_ "modernc.org/sqlite"
+)
+
+// migration is one step of the schema. Versions start at 1 and increase by
+// one; a database records the versions it has applied in schema_migrations.
+type migration struct {
+	version int
+	name    string
+	stmts   []string
+}
+
+// migrations is the schema history. Never edit an applied migration;
+// append a new one instead.
+var migrations = []migration{
+	{1, "create tasks", []string{`
+		CREATE TABLE tasks (
+			id          TEXT PRIMARY KEY,
+			title       TEXT NOT NULL,
+			description TEXT NOT NULL DEFAULT '',
+			status      TEXT NOT NULL,
+			created_at  INTEGER NOT NULL,
+			updated_at  INTEGER NOT NULL
+		)`,
+	}},
+	{2, "create task history", []string{`
+		CREATE TABLE task_history (
+			task_id     TEXT NOT NULL,
+			seq         INTEGER NOT NULL,
+			from_status TEXT NOT NULL,
+			to_status   TEXT NOT NULL,
+			at          INTEGER NOT NULL,
+			PRIMARY KEY (task_id, seq)
+		)`,
+	}},
+	{3, "index list orders", []string{
+		`CREATE INDEX tasks_created_at ON tasks (created_at, id)`,
+		`CREATE INDEX tasks_updated_at ON tasks (updated_at, id)`,
+		`CREATE INDEX tasks_title ON tasks (title, id)`,
+	}},
+	{4, "record actors and request IDs", []string{
+		`ALTER TABLE tasks ADD COLUMN created_by TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE tasks ADD COLUMN updated_by TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE tasks ADD COLUMN request_id TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE task_history ADD COLUMN changed_by TEXT NOT NULL DEFAULT ''`,
+	}},
+	{5, "add planning fields", []string{
+		`ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE tasks ADD COLUMN due_at INTEGER`,
+		`CREATE INDEX tasks_due_at ON tasks (due_at)`,
+		`CREATE TABLE task_tags (
+			task_id TEXT NOT NULL,
+			tag     TEXT NOT NULL,
+			PRIMARY KEY (task_id, tag)
+		)`,
+		`CREATE INDEX task_tags_tag ON task_tags (tag, task_id)`,
+		`CREATE TABLE task_dependencies (
+			task_id    TEXT NOT NULL,
+			blocked_by TEXT NOT NULL,
+			PRIMARY KEY (task_id, blocked_by)
+		)`,
+		`CREATE INDEX task_dependencies_blocked_by ON task_dependencies (blocked_by)`,
+	}},
+}
+
+// sqlTaskService implements TaskService on top of database/sql. Times are
+// stored as Unix nanoseconds in UTC.
+type sqlTaskService struct {
+	db   *sql.DB
+	now  func() time.Time
+	feed *feed
+
+	// index covers the tasks in db when the service was created and the
+	// changes made through it since
+	index *searchIndex
+
+	// mu serializes changes so events are published in commit order and
+	// the index changes with the rows
+	mu sync.Mutex
+}
+
+// OpenSQLite opens the SQLite database at path, creating it if needed.
+// ":memory:" gives a private in-memory database. The pool is limited to a
+// single connection: SQLite serializes writers anyway, and an in-memory
+// database exists only on the connection that created it.
+func OpenSQLite(path string) (*sql.DB, error) {
+	// A file: URI with the path escaped, so a '?' or '#' in it is not
+	// taken for the start of the driver's parameters
+	dsn := url.URL{
+		Scheme:   "file",
+		Opaque:   (&url.URL{Path: path}).EscapedPath(),
+		RawQuery: url.Values{"_pragma": {"busy_timeout(5000)"}}.Encode(),
+	}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// NewSQLTaskService creates a task service storing tasks in db, bringing
+// the schema up to date first and indexing the stored tasks for Search.
+// The caller keeps ownership of db.
+func NewSQLTaskService(ctx context.Context, db *sql.DB, opts ...Option) (TaskService, error) {
+	if err := Migrate(ctx, db); err != nil {
+		return nil, err
+	}
+	index, err := loadSearchIndex(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	cfg := newConfig(opts)
+	return &sqlTaskService{db: db, now: cfg.now, feed: newFeed(cfg.auditLog), index: index}, nil
+}
+
+// loadSearchIndex indexes the tasks stored in db
+func loadSearchIndex(ctx context.Context, db *sql.DB) (*searchIndex, error) {
+	rows, err := db.QueryContext(ctx, `SELECT id, title, description FROM tasks`)
+	if err != nil {
+		return nil, dbError(ctx, "index tasks", err)
+	}
+	defer rows.Close()
+	index := newSearchIndex()
+	for rows.Next() {
+		var task Task
+		if err := rows.Scan(&task.ID, &task.Title, &task.Description); err != nil {
+			return nil, dbError(ctx, "index tasks", err)
+		}
+		index.put(&task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "index tasks", err)
+	}
+	return index, nil
+}
+
+// Migrate applies the migrations db has not seen yet, each in its own
+// transaction
+func Migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at INTEGER NOT NULL
+	)`); err != nil {
+		return dbError(ctx, "create schema_migrations", err)
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return dbError(ctx, "read schema version", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			for _, stmt := range m.stmts {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`,
+				m.version, m.name, time.Now().UnixNano())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing if it returns nil
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(ctx, "begin transaction", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return dbError(ctx, "commit", err)
+	}
+	return nil
+}
+
+// dbError wraps an error from the database. When ctx is done the driver's
+// error is often its own "interrupted" error, so ctx.Err() is reported
+// instead, letting callers match context.Canceled and
+// context.DeadlineExceeded.
+func dbError(ctx context.Context, action string, err error) error {
+	if ctxErr := checkContext(ctx, action); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// querier is what *sql.DB and *sql.Tx have in common
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const taskColumns = `id, title, description, status, created_at, updated_at, created_by, updated_by, request_id, priority, due_at`
+
+// selectTasks reads the task columns followed by the task's tags and
+// dependencies, each joined with listSeparator
+const selectTasks = `SELECT ` + taskColumns + `,
+	(SELECT group_concat(tag, char(31)) FROM task_tags WHERE task_id = tasks.id),
+	(SELECT group_concat(blocked_by, char(31)) FROM task_dependencies WHERE task_id = tasks.id)
+	FROM tasks`
+
+// listSeparator is the ASCII unit separator, which cannot appear in a tag
+// and is not expected in an ID
+const listSeparator = "\x1f"
+
+func scanTask(row interface{ Scan(...any) error }) (*Task, error) {
+	var task Task
+	var created, updated int64
+	var due sql.NullInt64
+	var tags, blockedBy sql.NullString
+	if err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &created, &updated,
+		&task.CreatedBy, &task.UpdatedBy, &task.RequestID, &task.Priority, &due, &tags, &blockedBy); err != nil {
+		return nil, err
+	}
+	task.CreatedAt = time.Unix(0, created).UTC()
+	task.UpdatedAt = time.Unix(0, updated).UTC()
+	if due.Valid {
+		at := time.Unix(0, due.Int64).UTC()
+		task.Due = &at
+	}
+	if tags.Valid {
+		task.Tags = sortedSet(strings.Split(tags.String, listSeparator))
+	}
+	if blockedBy.Valid {
+		task.BlockedBy = sortedSet(strings.Split(blockedBy.String, listSeparator))
+	}
+	return &task, nil
+}
+
+// dueColumn is the stored form of a due date
+func dueColumn(task *Task) any {
+	if task.Due == nil {
+		return nil
+	}
+	return task.Due.UnixNano()
+}
+
+// saveRelations replaces the stored tags and dependencies of task
+func saveRelations(ctx context.Context, tx *sql.Tx, task *Task) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id = ?`, task.ID); err != nil {
+		return dbError(ctx, "save tags", err)
+	}
+	for _, tag := range task.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_tags (task_id, tag) VALUES (?, ?)`, task.ID, tag); err != nil {
+			return dbError(ctx, "save tags", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM task_dependencies WHERE task_id = ?`, task.ID); err != nil {
+		return dbError(ctx, "save dependencies", err)
+	}
+	for _, dep := range task.BlockedBy {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_dependencies (task_id, blocked_by) VALUES (?, ?)`, task.ID, dep); err != nil {
+			return dbError(ctx, "save dependencies", err)
+		}
+	}
+	return nil
+}
+
+// blockers looks up the dependencies of a stored task for
+// checkDependencies
+func blockers(ctx context.Context, q querier) func(id string) ([]string, error) {
+	return func(id string) ([]string, error) {
+		var exists bool
+		if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ?)`, id).Scan(&exists); err != nil {
+			return nil, dbError(ctx, "check dependencies", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w: task with ID %s", ErrNotFound, id)
+		}
+
+		rows, err := q.QueryContext(ctx, `SELECT blocked_by FROM task_dependencies WHERE task_id = ?`, id)
+		if err != nil {
+			return nil, dbError(ctx, "check dependencies", err)
+		}
+		defer rows.Close()
+		var deps []string
+		for rows.Next() {
+			var dep string
+			if err := rows.Scan(&dep); err != nil {
+				return nil, dbError(ctx, "check dependencies", err)
+			}
+			deps = append(deps, dep)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, dbError(ctx, "check dependencies", err)
+		}
+		return deps, nil
+	}
+}
+
+// getTask loads a task and its history
+func getTask(ctx context.Context, q querier, id string) (*Task, error) {
+	task, err := scanTask(q.QueryRowContext(ctx, selectTasks+` WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: task with ID %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, dbError(ctx, "get task "+id, err)
+	}
+	if err := loadHistory(ctx, q, []*Task{task}); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// loadHistory fills in the history of tasks
+func loadHistory(ctx context.Context, q querier, tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	byID := make(map[string]*Task, len(tasks))
+	args := make([]any, len(tasks))
+	for i, task := range tasks {
+		byID[task.ID] = task
+		args[i] = task.ID
+	}
+
+	rows, err := q.QueryContext(ctx, `SELECT task_id, from_status, to_status, at, changed_by FROM task_history
+		WHERE task_id IN (?`+strings.Repeat(", ?", len(tasks)-1)+`) ORDER BY task_id, seq`, args...)
+	if err != nil {
+		return dbError(ctx, "load history", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var change StatusChange
+		var at int64
+		if err := rows.Scan(&id, &change.From, &change.To, &at, &change.By); err != nil {
+			return dbError(ctx, "load history", err)
+		}
+		change.At = time.Unix(0, at).UTC()
+		byID[id].History = append(byID[id].History, change)
+	}
+	if err := rows.Err(); err != nil {
+		return dbError(ctx, "load history", err)
+	}
+	return nil
+}
+
+// appendHistory stores the changes after the first seen of task's history
+func appendHistory(ctx context.Context, tx *sql.Tx, task *Task, seen int) error {
+	for i := seen; i < len(task.History); i++ {
+		change := task.History[i]
+		if _, err := tx.ExecContext(ctx, `INSERT INTO task_history (task_id, seq, from_status, to_status, at, changed_by) VALUES (?, ?, ?, ?, ?, ?)`,
+			task.ID, i+1, change.From, change.To, change.At.UnixNano(), change.By); err != nil {
+			return dbError(ctx, "record history", err)
+		}
+	}
+	return nil
+}
+
+// Create creates a new task and sets its timestamps
+func (s *sqlTaskService) Create(ctx context.Context, task *Task) error {
+	if err := checkContext(ctx, "create task"); err != nil {
+		return err
+	}
+	if err := validateTask(task); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	created := task.clone()
+	normalizePlanning(created)
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	created.CreatedBy = ActorFrom(ctx)
+	stamp(ctx, created)
+	created.History = nil
+	event := newChangeEvent(ctx, ChangeCreated, nil, created, now)
+
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tasks WHERE id = ?)`, task.ID).Scan(&exists); err != nil {
+			return dbError(ctx, "create task", err)
+		}
+		if exists {
+			return fmt.Errorf("%w: task with ID %s", ErrAlreadyExists, task.ID)
+		}
+		if err := checkDependencies(created, blockers(ctx, tx)); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tasks (`+taskColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			created.ID, created.Title, created.Description, created.Status, now.UnixNano(), now.UnixNano(),
+			created.CreatedBy, created.UpdatedBy, created.RequestID, created.Priority, dueColumn(created)); err != nil {
+			return dbError(ctx, "create task", err)
+		}
+		if err := saveRelations(ctx, tx, created); err != nil {
+			return err
+		}
+		return s.feed.record(ctx, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.index.put(created)
+	s.feed.publish(event)
+	*task = *created
+	return nil
+}
+
+// Get retrieves a task by ID
+func (s *sqlTaskService) Get(ctx context.Context, id string) (*Task, error) {
+	if err := checkContext(ctx, "get task"); err != nil {
+		return nil, err
+	}
+	return getTask(ctx, s.db, id)
+}
+
+// Update updates an existing task. A status change must be allowed by the
+// transition table and is recorded in the history.
+func (s *sqlTaskService) Update(ctx context.Context, task *Task) error {
+	if err := checkContext(ctx, "update task"); err != nil {
+		return err
+	}
+	if err := validateTask(task); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var updated *Task
+	var event ChangeEvent
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		stored, err := getTask(ctx, tx, task.ID)
+		if err != nil {
+			return err
+		}
+
+		updated = task.clone()
+		normalizePlanning(updated)
+		if err := checkDependencies(updated, blockers(ctx, tx)); err != nil {
+			return err
+		}
+		updated.Status = stored.Status
+		updated.CreatedAt = stored.CreatedAt
+		updated.CreatedBy = stored.CreatedBy
+		updated.History = slices.Clone(stored.History)
+		now := s.now()
+		if task.Status != stored.Status {
+			if err := changeStatus(ctx, updated, task.Status, now); err != nil {
+				return err
+			}
+		}
+		updated.UpdatedAt = now
+		stamp(ctx, updated)
+
+		if _, err := tx.ExecContext(ctx, `UPDATE tasks SET title = ?, description = ?, status = ?, updated_at = ?, updated_by = ?, request_id = ?, priority = ?, due_at = ? WHERE id = ?`,
+			updated.Title, updated.Description, updated.Status, now.UnixNano(), updated.UpdatedBy, updated.RequestID,
+			updated.Priority, dueColumn(updated), updated.ID); err != nil {
+			return dbError(ctx, "update task", err)
+		}
+		if err := saveRelations(ctx, tx, updated); err != nil {
+			return err
+		}
+		if err := appendHistory(ctx, tx, updated, len(stored.History)); err != nil {
+			return err
+		}
+		event = newChangeEvent(ctx, ChangeUpdated, stored, updated, now)
+		return s.feed.record(ctx, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.index.put(updated)
+	s.feed.publish(event)
+	*task = *updated
+	return nil
+}
+
+// Delete deletes a task by ID. Tasks blocked by it lose the dependency,
+// each as an update.
+func (s *sqlTaskService) Delete(ctx context.Context, id string) error {
+	if err := checkContext(ctx, "delete task"); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []ChangeEvent
+	var unblocked []*Task
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		stored, err := getTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		events = []ChangeEvent{newChangeEvent(ctx, ChangeDeleted, stored, nil, now)}
+
+		dependents, err := dependentsOf(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		for _, depID := range dependents {
+			dependent, err := getTask(ctx, tx, depID)
+			if err != nil {
+				return err
+			}
+			updated := unblock(ctx, dependent, id, now)
+			if _, err := tx.ExecContext(ctx, `UPDATE tasks SET updated_at = ?, updated_by = ?, request_id = ? WHERE id = ?`,
+				now.UnixNano(), updated.UpdatedBy, updated.RequestID, updated.ID); err != nil {
+				return dbError(ctx, "unblock task", err)
+			}
+			unblocked = append(unblocked, updated)
+			events = append(events, newChangeEvent(ctx, ChangeUpdated, dependent, updated, now))
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tasks WHERE id = ?`, id); err != nil {
+			return dbError(ctx, "delete task", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_history WHERE task_id = ?`, id); err != nil {
+			return dbError(ctx, "delete task history", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_tags WHERE task_id = ?`, id); err != nil {
+			return dbError(ctx, "delete task tags", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM task_dependencies WHERE task_id = ? OR blocked_by = ?`, id, id); err != nil {
+			return dbError(ctx, "delete task dependencies", err)
+		}
+		for _, event := range events {
+			if err := s.feed.record(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.index.remove(id)
+	for _, updated := range unblocked {
+		s.index.put(updated)
+	}
+	for _, event := range events {
+		s.feed.publish(event)
+	}
+	return nil
+}
+
+// dependentsOf returns the IDs of the tasks blocked by id, in order
+func dependentsOf(ctx context.Context, q querier, id string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `SELECT task_id FROM task_dependencies WHERE blocked_by = ? ORDER BY task_id`, id)
+	if err != nil {
+		return nil, dbError(ctx, "find dependent tasks", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var dep string
+		if err := rows.Scan(&dep); err != nil {
+			return nil, dbError(ctx, "find dependent tasks", err)
+		}
+		ids = append(ids, dep)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "find dependent tasks", err)
+	}
+	return ids, nil
+}
+
+// Watch subscribes to changes matching filter until ctx is done
+func (s *sqlTaskService) Watch(ctx context.Context, filter WatchFilter) (<-chan ChangeEvent, error) {
+	return s.feed.watch(ctx, filter)
+}
+
+// Transition moves a task to another state, recording the change in its
+// history. It returns a *TransitionError if the table does not allow it.
+func (s *sqlTaskService) Transition(ctx context.Context, id string, to Status) (*Task, error) {
+	if err := checkContext(ctx, "transition task"); err != nil {
+		return nil, err
+	}
+	if !to.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, to)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var task *Task
+	var event ChangeEvent
+	err := inTx(ctx, s.db, func(tx *sql.Tx) error {
+		stored, err := getTask(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		task = stored.clone()
+		seen := len(task.History)
+		now := s.now()
+		if err := changeStatus(ctx, task, to, now); err != nil {
+			return err
+		}
+		task.UpdatedAt = now
+		stamp(ctx, task)
+
+		if _, err := tx.ExecContext(ctx, `UPDATE tasks SET status = ?, updated_at = ?, updated_by = ?, request_id = ? WHERE id = ?`,
+			task.Status, now.UnixNano(), task.UpdatedBy, task.RequestID, id); err != nil {
+			return dbError(ctx, "update task", err)
+		}
+		if err := appendHistory(ctx, tx, task, seen); err != nil {
+			return err
+		}
+		event = newChangeEvent(ctx, ChangeUpdated, stored, task, now)
+		return s.feed.record(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.feed.publish(event)
+	return task, nil
+}
+
+// Next returns up to limit unfinished tasks, or all of them if limit is 0,
+// in the order they can be worked on
+func (s *sqlTaskService) Next(ctx context.Context, limit int) ([]*Task, error) {
+	if err := checkContext(ctx, "plan tasks"); err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("%w: limit cannot be negative", ErrInvalidInput)
+	}
+
+	final := finalStatuses()
+	args := make([]any, len(final))
+	for i, status := range final {
+		args[i] = status
+	}
+	rows, err := s.db.QueryContext(ctx, selectTasks+` WHERE status NOT IN (?`+strings.Repeat(", ?", len(final)-1)+`)`, args...)
+	if err != nil {
+		return nil, dbError(ctx, "plan tasks", err)
+	}
+	var open []*Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, dbError(ctx, "plan tasks", err)
+		}
+		open = append(open, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "plan tasks", err)
+	}
+
+	order := workOrder(open, limit)
+	if err := loadHistory(ctx, s.db, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Search returns the tasks matching query, most relevant first. It holds
+// s.mu so that no change lands between reading the index and loading the
+// tasks it found.
+func (s *sqlTaskService) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	clauses, err := checkSearch(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hits := s.index.search(clauses, limit)
+	if len(hits) == 0 {
+		return []SearchResult{}, nil
+	}
+	args := make([]any, len(hits))
+	for i, hit := range hits {
+		args[i] = hit.id
+	}
+	rows, err := s.db.QueryContext(ctx, selectTasks+` WHERE id IN (?`+strings.Repeat(", ?", len(hits)-1)+`)`, args...)
+	if err != nil {
+		return nil, dbError(ctx, "search tasks", err)
+	}
+	byID := make(map[string]*Task, len(hits))
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, dbError(ctx, "search tasks", err)
+		}
+		byID[task.ID] = task
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "search tasks", err)
+	}
+
+	// Another process sharing the database may have deleted a task
+	results := make([]SearchResult, 0, len(hits))
+	tasks := make([]*Task, 0, len(hits))
+	for _, hit := range hits {
+		if task, ok := byID[hit.id]; ok {
+			results = append(results, SearchResult{Task: task, Score: hit.score})
+			tasks = append(tasks, task)
+		}
+	}
+	if err := loadHistory(ctx, s.db, tasks); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// sortColumns maps sort fields to the column holding them
+var sortColumns = map[SortField]string{
+	SortByCreatedAt: "created_at",
+	SortByUpdatedAt: "updated_at",
+	SortByTitle:     "title",
+}
+
+// List returns the tasks matching opts, in order, one page at a time. The
+// database does the filtering, ordering and cursor seek; the title filter
+// is applied while reading so it folds case exactly like the in-memory
+// service.
+func (s *sqlTaskService) List(ctx context.Context, opts ListOptions) (*TaskPage, error) {
+	if err := checkContext(ctx, "list tasks"); err != nil {
+		return nil, err
+	}
+	after, err := opts.prepare(s.now)
+	if err != nil {
+		return nil, err
+	}
+
+	var where []string
+	var args []any
+	if opts.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, opts.Status)
+	}
+	if len(opts.Tags) > 0 {
+		where = append(where, `id IN (SELECT task_id FROM task_tags WHERE tag IN (?`+strings.Repeat(", ?", len(opts.Tags)-1)+`)
+			GROUP BY task_id HAVING COUNT(*) = ?)`)
+		for _, tag := range opts.Tags {
+			args = append(args, tag)
+		}
+		args = append(args, len(opts.Tags))
+	}
+	if opts.MinPriority > PriorityNone {
+		where = append(where, "priority >= ?")
+		args = append(args, opts.MinPriority)
+	}
+	if opts.Overdue {
+		final := finalStatuses()
+		where = append(where, `due_at < ? AND status NOT IN (?`+strings.Repeat(", ?", len(final)-1)+`)`)
+		args = append(args, opts.asOf.UnixNano())
+		for _, status := range final {
+			args = append(args, status)
+		}
+	}
+	ranges := []struct {
+		column string
+		r      TimeRange
+	}{{"created_at", opts.Created}, {"updated_at", opts.Updated}, {"due_at", opts.Due}}
+	for _, rc := range ranges {
+		if !rc.r.From.IsZero() {
+			where = append(where, rc.column+" >= ?")
+			args = append(args, rc.r.From.UnixNano())
+		}
+		if !rc.r.To.IsZero() {
+			where = append(where, rc.column+" < ?")
+			args = append(args, rc.r.To.UnixNano())
+		}
+	}
+
+	column, op, dir := sortColumns[opts.SortBy], ">", "ASC"
+	if opts.Descending {
+		op, dir = "<", "DESC"
+	}
+	if after != nil {
+		var key any = after.Title
+		if opts.SortBy != SortByTitle {
+			key = after.CreatedAt.UnixNano()
+		}
+		where = append(where, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, op, column, op))
+		args = append(args, key, key, after.ID)
+	}
+
+	query := selectTasks
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", column, dir, dir)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(ctx, "list tasks", err)
+	}
+	tasks := make([]*Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			rows.Close()
+			return nil, dbError(ctx, "list tasks", err)
+		}
+		if !opts.matches(task) {
+			continue
+		}
+		tasks = append(tasks, task)
+		// One more than a page tells whether there is a next page
+		if opts.Limit > 0 && len(tasks) > opts.Limit {
+			break
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, "list tasks", err)
+	}
+
+	page := opts.page(tasks)
+	if err := loadHistory(ctx, s.db, page.Tasks); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// RunPersistence demonstrates the SQL task service: tasks written before
+// the database is closed are still there after it is reopened
+func RunPersistence(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	ctx := context.Background()
+
+	dir, err := os.MkdirTemp("", "example5-sqlite-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tasks.db")
+
+	fmt.Fprintln(env, "\nFirst run: creating tasks")
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return err
+	}
+	service, err := NewSQLTaskService(ctx, db, WithClock(env.Now))
+	if err != nil {
+		db.Close()
+		return err
+	}
+	for i, title := range []string{"Design schema", "Write migrations", "Ship it"} {
+		task := &Task{ID: fmt.Sprint(i + 1), Title: title, Status: StatusPending}
+		if err := service.Create(ctx, task); err != nil {
+			db.Close()
+			return fmt.Errorf("failed to create task: %w", err)
+		}
+		fmt.Fprintf(env, "Created task %s: %s\n", task.ID, task.Title)
+	}
+	if _, err := service.Transition(ctx, "1", StatusCompleted); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to complete task: %w", err)
+	}
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+
+	fmt.Fprintln(env, "\nSecond run: reopening the database")
+	db, err = OpenSQLite(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	service, err = NewSQLTaskService(ctx, db, WithClock(env.Now))
+	if err != nil {
+		return err
+	}
+
+	var version int
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	fmt.Fprintf(env, "Schema version: %d\n", version)
+
+	page, err := service.List(ctx, ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list tasks: %w", err)
+	}
+	for _, task := range page.Tasks {
+		fmt.Fprintf(env, "Task %s: %s [%s], %d status changes\n", task.ID, task.Title, task.Status, len(task.History))
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package example5_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"practice/examples/example5"
+	"practice/examples/example5/tasktest"
+)
+
+func TestMemoryTaskService(t *testing.T) {
+	tasktest.TestTaskService(t, func(t *testing.T, opts ...example5.Option) example5.TaskService {
+		return example5.NewTaskService(opts...)
+	})
+}
+
+func TestSQLTaskService(t *testing.T) {
+	tasktest.TestTaskService(t, func(t *testing.T, opts ...example5.Option) example5.TaskService {
+		return newSQLService(t, openSQLite(t, ":memory:"), opts...)
+	})
+}
+
+func openSQLite(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := example5.OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newSQLService(t *testing.T, db *sql.DB, opts ...example5.Option) example5.TaskService {
+	t.Helper()
+	service, err := example5.NewSQLTaskService(context.Background(), db, opts...)
+	if err != nil {
+		t.Fatalf("NewSQLTaskService() error = %v", err)
+	}
+	return service
+}
+
+func TestSQLTaskServiceSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tasks.db")
+
+	first := openSQLite(t, path)
+	service := newSQLService(t, first)
+	if err := service.Create(ctx, &example5.Task{ID: "1", Title: "Persist me", Status: example5.StatusPending}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := service.Transition(ctx, "1", example5.StatusInProgress); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Reopening runs the migrations again, which must be a no-op
+	reopened := newSQLService(t, openSQLite(t, path))
+	task, err := reopened.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get() after restart error = %v", err)
+	}
+	if task.Title != "Persist me" || task.Status != example5.StatusInProgress || len(task.History) != 1 {
+		t.Errorf("task after restart = %+v", task)
+	}
+}
+
+func TestOpenSQLiteEscapesPath(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "odd dir?x=1#frag%20", "tasks.db")
+	if err := os.Mkdir(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	service := newSQLService(t, openSQLite(t, path))
+	if err := service.Create(ctx, &example5.Task{ID: "1", Title: "Stored", Status: example5.StatusPending}); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at %s: %v", path, err)
+	}
+}
+
+func TestMigrateRecordsVersions(t *testing.T) {
+	db := openSQLite(t, ":memory:")
+	for i := 0; i < 2; i++ {
+		if err := example5.Migrate(context.Background(), db); err != nil {
+			t.Fatalf("Migrate() run %d error = %v", i+1, err)
+		}
+	}
+
+	var versions, latest int
+	if err := db.QueryRow(`SELECT COUNT(*), MAX(version) FROM schema_migrations`).Scan(&versions, &latest); err != nil {
+		t.Fatalf("reading schema_migrations: %v", err)
+	}
+	if versions != latest || latest < 1 {
+		t.Errorf("schema_migrations has %d rows up to version %d", versions, latest)
+	}
+}
+
+func TestSQLTaskServiceHonorsCancellation(t *testing.T) {
+	service := newSQLService(t, openSQLite(t, ":memory:"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := service.Create(ctx, &example5.Task{ID: "1", Title: "Too late", Status: example5.StatusPending}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := service.Get(ctx, "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := service.List(ctx, example5.ListOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("List() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
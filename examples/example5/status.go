@@ -0,0 +1,142 @@
+package example5
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrInvalidTransition is wrapped by every TransitionError
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// Status is the state of a task
+type Status string
+
+// Task states
+const (
+	StatusPending    Status = "pending"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusCompleted  Status = "completed"
+	StatusCancelled  Status = "cancelled"
+)
+
+// transitions lists the states each state may move to. Completed and
+// cancelled tasks are final.
+var transitions = map[Status][]Status{
+	StatusPending:    {StatusInProgress, StatusBlocked, StatusCompleted, StatusCancelled},
+	StatusInProgress: {StatusPending, StatusBlocked, StatusCompleted, StatusCancelled},
+	StatusBlocked:    {StatusPending, StatusInProgress, StatusCancelled},
+	StatusCompleted:  {},
+	StatusCancelled:  {},
+}
+
+// Valid reports whether s is one of the defined states
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// Final reports whether s is a state a task cannot leave. A final task no
+// longer blocks the tasks that depend on it.
+func (s Status) Final() bool {
+	next, ok := transitions[s]
+	return ok && len(next) == 0
+}
+
+// finalStatuses lists the final states
+func finalStatuses() []Status {
+	var final []Status
+	for s := range transitions {
+		if s.Final() {
+			final = append(final, s)
+		}
+	}
+	slices.Sort(final)
+	return final
+}
+
+// CanTransition reports whether a task in state s may move to state to
+func (s Status) CanTransition(to Status) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError reports a status change the transition table forbids
+type TransitionError struct {
+	TaskID string
+	From   Status
+	To     Status
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%v: task %s cannot move from %s to %s", ErrInvalidTransition, e.TaskID, e.From, e.To)
+}
+
+// Unwrap lets errors.Is match ErrInvalidTransition
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// StatusChange is one entry of a task's history
+type StatusChange struct {
+	From Status    `json:"from"`
+	To   Status    `json:"to"`
+	At   time.Time `json:"at"`
+
+	// By is the actor from the context of the change
+	By string `json:"by,omitempty"`
+}
+
+// Transition moves a task to another state, recording the change in its
+// history. It returns a *TransitionError if the table does not allow it.
+func (s *taskService) Transition(ctx context.Context, id string, to Status) (*Task, error) {
+	if err := checkContext(ctx, "transition task"); err != nil {
+		return nil, err
+	}
+	if !to.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, to)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, exists := s.tasks[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: task with ID %s", ErrNotFound, id)
+	}
+
+	task := stored.clone()
+	now := s.now()
+	if err := changeStatus(ctx, task, to, now); err != nil {
+		return nil, err
+	}
+	task.UpdatedAt = now
+	stamp(ctx, task)
+
+	event := newChangeEvent(ctx, ChangeUpdated, stored, task, now)
+	if err := s.feed.record(ctx, event); err != nil {
+		return nil, err
+	}
+	s.tasks[id] = task
+	s.feed.publish(event)
+	return task.clone(), nil
+}
+
+// changeStatus checks the move from task's current state to another
+// against the transition table and applies it on behalf of the actor in ctx
+func changeStatus(ctx context.Context, task *Task, to Status, at time.Time) error {
+	from := task.Status
+	if !from.CanTransition(to) {
+		return &TransitionError{TaskID: task.ID, From: from, To: to}
+	}
+
+	task.Status = to
+	task.History = append(task.History, StatusChange{From: from, To: to, At: at, By: ActorFrom(ctx)})
+	return nil
+}
@@ -0,0 +1,139 @@
+package example5
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+)
+
+func TestTransitionTable(t *testing.T) {
+	tests := []struct {
+		from, to Status
+		allowed  bool
+	}{
+		{StatusPending, StatusInProgress, true},
+		{StatusPending, StatusCompleted, true},
+		{StatusInProgress, StatusBlocked, true},
+		{StatusBlocked, StatusInProgress, true},
+		{StatusBlocked, StatusCompleted, false},
+		{StatusCompleted, StatusPending, false},
+		{StatusCancelled, StatusInProgress, false},
+		{StatusPending, StatusPending, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			ctx := context.Background()
+			service := NewTaskService()
+			if err := service.Create(ctx, &Task{ID: "1", Title: "Task", Status: tt.from}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			task, err := service.Transition(ctx, "1", tt.to)
+			if tt.allowed {
+				if err != nil {
+					t.Fatalf("Transition: %v", err)
+				}
+				if task.Status != tt.to {
+					t.Errorf("Status = %s, want %s", task.Status, tt.to)
+				}
+				return
+			}
+
+			var transitionErr *TransitionError
+			if !errors.As(err, &transitionErr) || !errors.Is(err, ErrInvalidTransition) {
+				t.Fatalf("Transition error = %v, want *TransitionError", err)
+			}
+			want := TransitionError{TaskID: "1", From: tt.from, To: tt.to}
+			if *transitionErr != want {
+				t.Errorf("error = %+v, want %+v", *transitionErr, want)
+			}
+			if stored, _ := service.Get(ctx, "1"); stored.Status != tt.from || len(stored.History) != 0 {
+				t.Errorf("rejected transition changed the task: %+v", stored)
+			}
+		})
+	}
+}
+
+func TestTransitionRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	service := NewTaskService(WithClock(demo.StepClock(demo.Epoch, time.Minute)))
+	if err := service.Create(ctx, &Task{ID: "1", Title: "Task", Status: StatusPending}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	for _, to := range []Status{StatusInProgress, StatusBlocked, StatusInProgress} {
+		if _, err := service.Transition(ctx, "1", to); err != nil {
+			t.Fatalf("Transition to %s: %v", to, err)
+		}
+	}
+
+	// Update goes through the same table; a change to other fields only is
+	// not a transition
+	task, err := service.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	task.Description = "Almost there"
+	if err := service.Update(ctx, task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	task.Status = StatusCompleted
+	if err := service.Update(ctx, task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	at := func(minutes int) time.Time { return demo.Epoch.Add(time.Duration(minutes) * time.Minute) }
+	want := []StatusChange{
+		{From: StatusPending, To: StatusInProgress, At: at(1)},
+		{From: StatusInProgress, To: StatusBlocked, At: at(2)},
+		{From: StatusBlocked, To: StatusInProgress, At: at(3)},
+		{From: StatusInProgress, To: StatusCompleted, At: at(5)},
+	}
+	got, err := service.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !reflect.DeepEqual(got.History, want) {
+		t.Errorf("History = %+v, want %+v", got.History, want)
+	}
+	if !got.UpdatedAt.Equal(at(5)) || got.Description != "Almost there" {
+		t.Errorf("task = %+v", got)
+	}
+
+	task.Status = StatusPending
+	if err := service.Update(ctx, task); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Update of completed task error = %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestStatusValidation(t *testing.T) {
+	ctx := context.Background()
+	service := NewTaskService()
+
+	if err := service.Create(ctx, &Task{ID: "1", Title: "Task", Status: "banana"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Create with unknown status error = %v, want ErrInvalidInput", err)
+	}
+	if err := service.Create(ctx, &Task{ID: "1", Title: "Task", Status: StatusPending}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := service.Transition(ctx, "1", "banana"); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("Transition to unknown status error = %v, want ErrInvalidInput", err)
+	}
+	if _, err := service.Transition(ctx, "2", StatusCompleted); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Transition of missing task error = %v, want ErrNotFound", err)
+	}
+
+	// Tasks handed out are copies, so changing one bypasses nothing
+	task, err := service.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	task.Status = StatusCancelled
+	if stored, _ := service.Get(ctx, "1"); stored.Status != StatusPending {
+		t.Errorf("stored Status = %s after changing a copy, want %s", stored.Status, StatusPending)
+	}
+}
@@ -0,0 +1,386 @@
+package taskapi
+
+import (
+	"encoding"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"practice/examples/example5"
+)
+
+// route is one operation of the API. The same table registers the
+// handlers and describes them in the OpenAPI document, so the two cannot
+// drift apart.
+type route struct {
+	method      string
+	path        string
+	operationID string
+	summary     string
+	params      []Parameter
+
+	// request and response are the Go types of the bodies; nil for none
+	request  reflect.Type
+	response reflect.Type
+
+	// status is the success status; errors are the failure statuses
+	status int
+	errors []int
+
+	handle func(h *handler, w http.ResponseWriter, r *http.Request) error
+}
+
+var (
+	taskType    = reflect.TypeFor[example5.Task]()
+	requestType = reflect.TypeFor[TaskRequest]()
+)
+
+var routes = []route{
+	{
+		method: http.MethodGet, path: "/v1/tasks",
+		operationID: "listTasks", summary: "List tasks, one page at a time",
+		params: []Parameter{
+			queryParam("status", "Only tasks in this status", reflect.TypeFor[example5.Status]()),
+			queryParam("search", "Only tasks whose title contains this text, ignoring case", reflect.TypeFor[string]()),
+			queryParam("tag", "Only tasks with every one of these tags", reflect.TypeFor[[]string]()),
+			queryParam("min_priority", "Only tasks of at least this priority", reflect.TypeFor[example5.Priority]()),
+			queryParam("overdue", "Only unfinished tasks past their due date", reflect.TypeFor[bool]()),
+			queryParam("sort", "Field to order by; created_at by default", reflect.TypeFor[example5.SortField]()),
+			{Name: "order", In: "query", Description: "Sort direction", Schema: &Schema{Type: "string", Enum: []string{"asc", "desc"}}},
+			{Name: "limit", In: "query", Description: fmt.Sprintf("Page size, %d by default", DefaultLimit),
+				Schema: &Schema{Type: "integer", Minimum: ptr(1), Maximum: ptr(MaxLimit)}},
+			queryParam("cursor", "next_cursor from the previous page", reflect.TypeFor[string]()),
+		},
+		response: reflect.TypeFor[TaskList](), status: http.StatusOK,
+		errors: []int{http.StatusBadRequest},
+		handle: (*handler).listTasks,
+	},
+	{
+		method: http.MethodPost, path: "/v1/tasks",
+		operationID: "createTask", summary: "Create a task",
+		request: requestType, response: taskType, status: http.StatusCreated,
+		errors: []int{http.StatusBadRequest, http.StatusConflict},
+		handle: (*handler).createTask,
+	},
+	{
+		method: http.MethodGet, path: "/v1/tasks/{id}",
+		operationID: "getTask", summary: "Fetch a task",
+		params: []Parameter{
+			headerParam("If-None-Match", "Answer 304 Not Modified if the task still has this ETag"),
+		},
+		response: taskType, status: http.StatusOK,
+		errors: []int{http.StatusNotFound},
+		handle: (*handler).getTask,
+	},
+	{
+		method: http.MethodPut, path: "/v1/tasks/{id}",
+		operationID: "updateTask", summary: "Replace a task's fields",
+		params: []Parameter{
+			func() Parameter {
+				p := headerParam("If-Match", "ETag of the task as last read")
+				p.Required = true
+				return p
+			}(),
+		},
+		request: requestType, response: taskType, status: http.StatusOK,
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict,
+			http.StatusPreconditionFailed, http.StatusPreconditionRequired},
+		handle: (*handler).updateTask,
+	},
+	{
+		method: http.MethodDelete, path: "/v1/tasks/{id}",
+		operationID: "deleteTask", summary: "Delete a task",
+		params: []Parameter{
+			headerParam("If-Match", "Only delete the task if it still has this ETag"),
+		},
+		status: http.StatusNoContent,
+		errors: []int{http.StatusNotFound, http.StatusPreconditionFailed},
+		handle: (*handler).deleteTask,
+	},
+	{
+		method: http.MethodPost, path: "/v1/tasks/{id}/transitions",
+		operationID: "transitionTask", summary: "Move a task to another status",
+		params: []Parameter{
+			headerParam("If-Match", "Only change the task if it still has this ETag"),
+		},
+		request: reflect.TypeFor[TransitionRequest](), response: taskType, status: http.StatusOK,
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed},
+		handle: (*handler).transitionTask,
+	},
+	{
+		method: http.MethodGet, path: "/v1/search",
+		operationID: "searchTasks", summary: "Find tasks by the words in their title or description",
+		params: []Parameter{
+			func() Parameter {
+				p := queryParam("q", `Words that must all match, regardless of case; "word*" matches a prefix and "quoted words" a phrase`, reflect.TypeFor[string]())
+				p.Required = true
+				return p
+			}(),
+			{Name: "limit", In: "query", Description: fmt.Sprintf("Most results to return, %d by default", DefaultLimit),
+				Schema: &Schema{Type: "integer", Minimum: ptr(1), Maximum: ptr(MaxLimit)}},
+		},
+		response: reflect.TypeFor[SearchResults](), status: http.StatusOK,
+		errors: []int{http.StatusBadRequest},
+		handle: (*handler).search,
+	},
+}
+
+// openAPIPath serves the document. It is registered apart from routes,
+// which the document is built from.
+const openAPIPath = "/" + Version + "/openapi.json"
+
+// Document is an OpenAPI 3.0 document, as far as this API needs one
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the schemas operations refer to
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is one method on one path
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes what an operation accepts
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one status of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType gives the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is an OpenAPI 3.0 schema object
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Enum       []string           `json:"enum,omitempty"`
+	Minimum    *int               `json:"minimum,omitempty"`
+	Maximum    *int               `json:"maximum,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+}
+
+// Spec returns the OpenAPI document served at /v1/openapi.json. It is
+// built once and must not be modified.
+func Spec() *Document {
+	return spec()
+}
+
+var spec = sync.OnceValue(buildSpec)
+
+func buildSpec() *Document {
+	g := newSchemaGenerator()
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: "Tasks API", Version: Version},
+		Paths:   make(map[string]map[string]Operation),
+	}
+
+	for _, rt := range routes {
+		op := Operation{
+			OperationID: rt.operationID,
+			Summary:     rt.summary,
+			Responses:   make(map[string]Response),
+		}
+		if strings.Contains(rt.path, "{id}") {
+			op.Parameters = append(op.Parameters, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+		op.Parameters = append(op.Parameters, rt.params...)
+		op.Parameters = append(op.Parameters, headerParam("X-Request-ID", "Recorded on the tasks the request changes; generated if missing"))
+		if rt.request != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(g.schema(rt.request))}
+		}
+
+		success := Response{Description: http.StatusText(rt.status)}
+		if rt.response != nil {
+			success.Content = jsonContent(g.schema(rt.response))
+		}
+		if rt.response == taskType {
+			success.Headers = map[string]Header{"ETag": {Description: "Version of the task for If-Match", Schema: &Schema{Type: "string"}}}
+		}
+		op.Responses[strconv.Itoa(rt.status)] = success
+		if rt.operationID == "getTask" {
+			op.Responses[strconv.Itoa(http.StatusNotModified)] = Response{Description: http.StatusText(http.StatusNotModified)}
+		}
+
+		errorSchema := g.schema(reflect.TypeFor[ErrorResponse]())
+		for _, status := range append(rt.errors, http.StatusInternalServerError) {
+			op.Responses[strconv.Itoa(status)] = Response{Description: http.StatusText(status), Content: jsonContent(errorSchema)}
+		}
+
+		if doc.Paths[rt.path] == nil {
+			doc.Paths[rt.path] = make(map[string]Operation)
+		}
+		doc.Paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	doc.Paths[openAPIPath] = map[string]Operation{"get": {
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Responses: map[string]Response{
+			strconv.Itoa(http.StatusOK): {Description: http.StatusText(http.StatusOK), Content: jsonContent(&Schema{Type: "object"})},
+		},
+	}}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func jsonContent(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
+
+func queryParam(name, description string, t reflect.Type) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: newSchemaGenerator().schema(t)}
+}
+
+func headerParam(name, description string) Parameter {
+	return Parameter{Name: name, In: "header", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+func ptr(n int) *int {
+	return &n
+}
+
+// enums lists the values of the string types that have a fixed set
+var enums = map[reflect.Type][]string{
+	reflect.TypeFor[example5.Status](): {
+		string(example5.StatusPending), string(example5.StatusInProgress), string(example5.StatusBlocked),
+		string(example5.StatusCompleted), string(example5.StatusCancelled),
+	},
+	reflect.TypeFor[example5.Priority](): func() []string {
+		var names []string
+		for p := example5.PriorityNone; p.Valid(); p++ {
+			names = append(names, p.String())
+		}
+		return names
+	}(),
+	reflect.TypeFor[example5.SortField](): {
+		string(example5.SortByCreatedAt), string(example5.SortByUpdatedAt), string(example5.SortByTitle),
+	},
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// schemaGenerator derives schemas from Go types the way encoding/json
+// writes them. Named structs become components referred to by $ref.
+type schemaGenerator struct {
+	schemas map[string]*Schema
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{schemas: make(map[string]*Schema)}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) *Schema {
+	if values, ok := enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t.Kind() != reflect.Pointer && t.Implements(textMarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.Struct:
+		return g.object(t)
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	panic(fmt.Sprintf("taskapi: no schema for %s", t))
+}
+
+// object registers the schema of a struct under its name and returns a
+// reference to it. Fields without omitempty are required.
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+	if _, ok := g.schemas[t.Name()]; ok {
+		return ref
+	}
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.schemas[t.Name()] = s
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return ref
+}
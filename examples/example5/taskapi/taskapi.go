@@ -0,0 +1,469 @@
+// Package taskapi serves example5's TaskService as a versioned REST API
+// under /v1, with an OpenAPI 3 document generated from the Go types of its
+// requests and responses.
+package taskapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"practice/examples/example5"
+)
+
+// Version prefixes every path of the API
+const Version = "v1"
+
+// Errors the API adds to the service's
+var (
+	ErrMethodNotAllowed     = errors.New("method not allowed")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+)
+
+// Page sizes for GET /v1/tasks
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// maxBodyBytes bounds request bodies
+const maxBodyBytes = 1 << 20
+
+// TaskRequest is the body of POST /v1/tasks and PUT /v1/tasks/{id}. On
+// create, an empty ID is generated and an empty status means pending; on
+// update, the ID must be empty or match the path and an empty status keeps
+// the current one.
+type TaskRequest struct {
+	ID          string            `json:"id,omitempty"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	Status      example5.Status   `json:"status,omitempty"`
+	Priority    example5.Priority `json:"priority,omitempty"`
+	Due         *time.Time        `json:"due,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	BlockedBy   []string          `json:"blocked_by,omitempty"`
+}
+
+// TransitionRequest is the body of POST /v1/tasks/{id}/transitions
+type TransitionRequest struct {
+	Status example5.Status `json:"status"`
+}
+
+// TaskList is one page of GET /v1/tasks
+type TaskList struct {
+	Tasks []*example5.Task `json:"tasks"`
+
+	// NextCursor is passed as ?cursor= to fetch the following page; it is
+	// empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchResults is the body of GET /v1/search, best match first
+type SearchResults struct {
+	Results []example5.SearchResult `json:"results"`
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody says what went wrong. Code is stable and meant for programs;
+// Message is meant for people.
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// config holds the settings Options change
+type config struct {
+	newID func() string
+}
+
+// Option configures the handler
+type Option func(*config)
+
+// WithIDs replaces the UUID generator used for tasks created without an ID
+func WithIDs(newID func() string) Option {
+	return func(c *config) {
+		c.newID = newID
+	}
+}
+
+// handler serves the API. Writes are serialized so that checking If-Match
+// and making the change happen as one step for requests through the same
+// handler.
+type handler struct {
+	service example5.TaskService
+	newID   func() string
+	writeMu sync.Mutex
+}
+
+// NewHandler exposes service over REST:
+//
+//	GET    /v1/tasks                    list tasks, 200
+//	POST   /v1/tasks                    create a task, 201
+//	GET    /v1/tasks/{id}               fetch a task, 200 or 304
+//	PUT    /v1/tasks/{id}               replace a task's fields, 200
+//	DELETE /v1/tasks/{id}               delete a task, 204
+//	POST   /v1/tasks/{id}/transitions   change a task's status, 200
+//	GET    /v1/search?q=QUERY           search titles and descriptions, 200
+//	GET    /v1/openapi.json             the OpenAPI document, 200
+//
+// Responses carrying a task have an ETag. PUT requires a matching If-Match
+// header; DELETE and transitions check it when it is sent. Errors are
+// returned as an ErrorResponse. An X-Request-ID header is recorded on the
+// tasks a request changes and echoed back, or generated if missing.
+func NewHandler(service example5.TaskService, opts ...Option) http.Handler {
+	cfg := config{newID: uuid.NewString}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	h := &handler{service: service, newID: cfg.newID}
+
+	mux := http.NewServeMux()
+	allowed := map[string][]string{openAPIPath: {http.MethodGet}}
+	var paths []string
+	for _, rt := range routes {
+		mux.HandleFunc(rt.method+" "+rt.path, h.serve(rt.handle))
+		if allowed[rt.path] == nil {
+			paths = append(paths, rt.path)
+		}
+		allowed[rt.path] = append(allowed[rt.path], rt.method)
+	}
+	mux.HandleFunc(http.MethodGet+" "+openAPIPath, h.serve((*handler).openAPI))
+
+	// The patterns above are more specific than these, so these only see
+	// methods a path does not support and paths the API does not have
+	for _, path := range append(paths, openAPIPath) {
+		mux.HandleFunc(path, methodNotAllowed(allowed[path]))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, fmt.Errorf("%w: no route for %s %s", example5.ErrNotFound, r.Method, r.URL.Path))
+	})
+	return mux
+}
+
+// methodNotAllowed answers requests for a path with a method it does not
+// support
+func methodNotAllowed(methods []string) http.HandlerFunc {
+	allow := strings.Join(methods, ", ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allow)
+		writeError(w, fmt.Errorf("%w: %s does not support %s; use %s", ErrMethodNotAllowed, r.URL.Path, r.Method, allow))
+	}
+}
+
+// serve adapts a handler method that returns an error, writing the error
+// envelope when it fails
+func (h *handler) serve(fn func(h *handler, w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = h.newID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		r = r.WithContext(example5.WithRequestID(r.Context(), requestID))
+
+		if err := fn(h, w, r); err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+func (h *handler) listTasks(w http.ResponseWriter, r *http.Request) error {
+	opts, err := listOptions(r)
+	if err != nil {
+		return err
+	}
+	page, err := h.service.List(r.Context(), opts)
+	if err != nil {
+		return err
+	}
+	list := TaskList{Tasks: page.Tasks, NextCursor: page.NextCursor}
+	if list.Tasks == nil {
+		list.Tasks = []*example5.Task{}
+	}
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+// listOptions reads the query parameters of GET /v1/tasks
+func listOptions(r *http.Request) (example5.ListOptions, error) {
+	q := r.URL.Query()
+	opts := example5.ListOptions{
+		Status:        example5.Status(q.Get("status")),
+		TitleContains: q.Get("search"),
+		Tags:          q["tag"],
+		SortBy:        example5.SortField(q.Get("sort")),
+		Cursor:        q.Get("cursor"),
+		Limit:         DefaultLimit,
+	}
+
+	var err error
+	if s := q.Get("min_priority"); s != "" {
+		if opts.MinPriority, err = example5.ParsePriority(s); err != nil {
+			return opts, err
+		}
+	}
+	if s := q.Get("overdue"); s != "" {
+		if opts.Overdue, err = strconv.ParseBool(s); err != nil {
+			return opts, fmt.Errorf("%w: overdue must be true or false", example5.ErrInvalidInput)
+		}
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, fmt.Errorf("%w: order must be asc or desc", example5.ErrInvalidInput)
+	}
+	if s := q.Get("limit"); s != "" {
+		if opts.Limit, err = strconv.Atoi(s); err != nil || opts.Limit < 1 || opts.Limit > MaxLimit {
+			return opts, fmt.Errorf("%w: limit must be between 1 and %d", example5.ErrInvalidInput, MaxLimit)
+		}
+	}
+	return opts, nil
+}
+
+func (h *handler) createTask(w http.ResponseWriter, r *http.Request) error {
+	var req TaskRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		return err
+	}
+	task := req.task()
+	if task.ID == "" {
+		task.ID = h.newID()
+	}
+	if task.Status == "" {
+		task.Status = example5.StatusPending
+	}
+
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	if err := h.service.Create(r.Context(), task); err != nil {
+		return err
+	}
+	w.Header().Set("Location", "/"+Version+"/tasks/"+task.ID)
+	writeTask(w, http.StatusCreated, task)
+	return nil
+}
+
+func (h *handler) getTask(w http.ResponseWriter, r *http.Request) error {
+	task, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), ETag(task)) {
+		w.Header().Set("ETag", ETag(task))
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	writeTask(w, http.StatusOK, task)
+	return nil
+}
+
+func (h *handler) updateTask(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	var req TaskRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		return err
+	}
+	if req.ID != "" && req.ID != id {
+		return fmt.Errorf("%w: body ID %s does not match path ID %s", example5.ErrInvalidInput, req.ID, id)
+	}
+	if r.Header.Get("If-Match") == "" {
+		return fmt.Errorf("%w: PUT needs an If-Match header with the task's ETag", ErrPreconditionRequired)
+	}
+
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	current, err := h.checkIfMatch(r, id)
+	if err != nil {
+		return err
+	}
+	task := req.task()
+	task.ID = id
+	if task.Status == "" {
+		task.Status = current.Status
+	}
+	if err := h.service.Update(r.Context(), task); err != nil {
+		return err
+	}
+	writeTask(w, http.StatusOK, task)
+	return nil
+}
+
+func (h *handler) deleteTask(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	if _, err := h.checkIfMatch(r, id); err != nil {
+		return err
+	}
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *handler) transitionTask(w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	var req TransitionRequest
+	if err := decodeBody(w, r, &req); err != nil {
+		return err
+	}
+
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	if _, err := h.checkIfMatch(r, id); err != nil {
+		return err
+	}
+	task, err := h.service.Transition(r.Context(), id, req.Status)
+	if err != nil {
+		return err
+	}
+	writeTask(w, http.StatusOK, task)
+	return nil
+}
+
+func (h *handler) search(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	limit := DefaultLimit
+	if s := q.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > MaxLimit {
+			return fmt.Errorf("%w: limit must be between 1 and %d", example5.ErrInvalidInput, MaxLimit)
+		}
+	}
+	results, err := h.service.Search(r.Context(), q.Get("q"), limit)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, SearchResults{Results: results})
+	return nil
+}
+
+func (h *handler) openAPI(w http.ResponseWriter, r *http.Request) error {
+	writeJSON(w, http.StatusOK, Spec())
+	return nil
+}
+
+// checkIfMatch loads the task and, if the request has an If-Match header,
+// checks it against the task's ETag; h.writeMu must be held
+func (h *handler) checkIfMatch(r *http.Request, id string) (*example5.Task, error) {
+	current, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if header := r.Header.Get("If-Match"); header != "" && !etagMatches(header, ETag(current)) {
+		return nil, fmt.Errorf("%w: task %s has changed since it was read", ErrPreconditionFailed, id)
+	}
+	return current, nil
+}
+
+// task converts the request to the task it describes
+func (req TaskRequest) task() *example5.Task {
+	return &example5.Task{
+		ID:          req.ID,
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		Priority:    req.Priority,
+		Due:         req.Due,
+		Tags:        req.Tags,
+		BlockedBy:   req.BlockedBy,
+	}
+}
+
+// ETag returns the entity tag of a task's current representation. Any
+// change to the task changes it.
+func ETag(task *example5.Task) string {
+	data, _ := json.Marshal(task)
+	h := fnv.New64a()
+	h.Write(data)
+	return fmt.Sprintf(`"%016x"`, h.Sum64())
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists
+// etag or is "*"
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBody reads a JSON request body into v, rejecting unknown fields
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: malformed request body: %v", example5.ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// errorKinds maps errors to a status and code, most specific first
+var errorKinds = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{example5.ErrDependencyCycle, http.StatusBadRequest, "dependency_cycle"},
+	{example5.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{example5.ErrInvalidInput, http.StatusBadRequest, "invalid_input"},
+	{example5.ErrNotFound, http.StatusNotFound, "not_found"},
+	{ErrMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+	{example5.ErrAlreadyExists, http.StatusConflict, "already_exists"},
+	{example5.ErrInvalidTransition, http.StatusConflict, "invalid_transition"},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{ErrPreconditionRequired, http.StatusPreconditionRequired, "precondition_required"},
+}
+
+// classify maps an error to its status and code; anything unknown is a 500
+func classify(err error) (int, string) {
+	for _, kind := range errorKinds {
+		if errors.Is(err, kind.err) {
+			return kind.status, kind.code
+		}
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, code := classify(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		// Don't leak internals such as file paths
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+// writeTask writes a task with its ETag
+func writeTask(w http.ResponseWriter, status int, task *example5.Task) {
+	w.Header().Set("ETag", ETag(task))
+	writeJSON(w, status, task)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
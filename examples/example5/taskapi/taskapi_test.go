@@ -0,0 +1,341 @@
+package taskapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+	"practice/examples/example5"
+	"practice/examples/example5/taskapi"
+)
+
+// client sends requests to a handler over an in-memory service whose
+// clock advances a minute per reading and whose generated IDs count up
+type client struct {
+	t       *testing.T
+	handler http.Handler
+}
+
+func newClient(t *testing.T) *client {
+	service := example5.NewTaskService(example5.WithClock(demo.StepClock(demo.Epoch, time.Minute)))
+	next := 0
+	ids := func() string {
+		next++
+		return fmt.Sprintf("id-%d", next)
+	}
+	return &client{t: t, handler: taskapi.NewHandler(service, taskapi.WithIDs(ids))}
+}
+
+// do sends a request with an optional JSON body and headers given as
+// name, value pairs
+func (c *client) do(method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = strings.NewReader(string(data))
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads the response body into v, failing if the status is not
+// want
+func decode(t *testing.T, rec *httptest.ResponseRecorder, want int, v any) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, want, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+}
+
+// errorCode returns the code of an error envelope, failing if the status
+// is not want
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder, want int) string {
+	t.Helper()
+	var resp taskapi.ErrorResponse
+	decode(t, rec, want, &resp)
+	if resp.Error.Message == "" {
+		t.Errorf("error envelope %s has no message", rec.Body)
+	}
+	return resp.Error.Code
+}
+
+func TestCRUD(t *testing.T) {
+	c := newClient(t)
+
+	rec := c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{Title: "Write docs", Priority: example5.PriorityHigh, Tags: []string{"Docs"}},
+		"X-Request-ID", "req-1")
+	var created example5.Task
+	decode(t, rec, http.StatusCreated, &created)
+	if created.ID != "id-1" || created.Status != example5.StatusPending || created.Tags[0] != "docs" {
+		t.Errorf("created %+v", created)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/v1/tasks/id-1" {
+		t.Errorf("Location = %q", loc)
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want it echoed", got)
+	}
+	etag := rec.Header().Get("ETag")
+
+	rec = c.do(http.MethodGet, "/v1/tasks/id-1", nil)
+	var fetched example5.Task
+	decode(t, rec, http.StatusOK, &fetched)
+	if fetched.CreatedAt != created.CreatedAt || rec.Header().Get("ETag") != etag {
+		t.Errorf("fetched %+v with ETag %s, want the created task with ETag %s", fetched, rec.Header().Get("ETag"), etag)
+	}
+
+	rec = c.do(http.MethodPut, "/v1/tasks/id-1", taskapi.TaskRequest{Title: "Write better docs"}, "If-Match", etag)
+	var updated example5.Task
+	decode(t, rec, http.StatusOK, &updated)
+	if updated.Title != "Write better docs" || updated.Status != example5.StatusPending || updated.Priority != example5.PriorityNone {
+		t.Errorf("updated %+v, want the new fields and the status kept", updated)
+	}
+	etag = rec.Header().Get("ETag")
+
+	rec = c.do(http.MethodPost, "/v1/tasks/id-1/transitions", taskapi.TransitionRequest{Status: example5.StatusInProgress})
+	var moved example5.Task
+	decode(t, rec, http.StatusOK, &moved)
+	if moved.Status != example5.StatusInProgress || rec.Header().Get("ETag") == etag {
+		t.Errorf("after transition %+v with ETag %s", moved, rec.Header().Get("ETag"))
+	}
+
+	if rec = c.do(http.MethodDelete, "/v1/tasks/id-1", nil); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if code := errorCode(t, c.do(http.MethodGet, "/v1/tasks/id-1", nil), http.StatusNotFound); code != "not_found" {
+		t.Errorf("GET after DELETE code = %q", code)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	c := newClient(t)
+	rec := c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "a", Title: "Draft"})
+	stale := rec.Header().Get("ETag")
+
+	if rec := c.do(http.MethodGet, "/v1/tasks/a", nil, "If-None-Match", stale); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("GET If-None-Match = %d %q, want an empty 304", rec.Code, rec.Body)
+	}
+
+	if code := errorCode(t, c.do(http.MethodPut, "/v1/tasks/a", taskapi.TaskRequest{Title: "Final"}), http.StatusPreconditionRequired); code != "precondition_required" {
+		t.Errorf("PUT without If-Match code = %q", code)
+	}
+	c.do(http.MethodPut, "/v1/tasks/a", taskapi.TaskRequest{Title: "Final"}, "If-Match", stale)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+	}{
+		{"PUT", http.MethodPut, "/v1/tasks/a", taskapi.TaskRequest{Title: "Lost update"}},
+		{"transition", http.MethodPost, "/v1/tasks/a/transitions", taskapi.TransitionRequest{Status: example5.StatusCancelled}},
+		{"DELETE", http.MethodDelete, "/v1/tasks/a", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := errorCode(t, c.do(tt.method, tt.path, tt.body, "If-Match", stale), http.StatusPreconditionFailed); code != "precondition_failed" {
+				t.Errorf("code = %q", code)
+			}
+		})
+	}
+
+	var task example5.Task
+	decode(t, c.do(http.MethodGet, "/v1/tasks/a", nil, "If-None-Match", stale), http.StatusOK, &task)
+	if task.Title != "Final" || task.Status != example5.StatusPending {
+		t.Errorf("after stale writes task = %+v, want only the fresh update", task)
+	}
+	if rec := c.do(http.MethodDelete, "/v1/tasks/a", nil, "If-Match", "*"); rec.Code != http.StatusNoContent {
+		t.Errorf("DELETE If-Match: * status = %d", rec.Code)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+	c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "a", Title: "First"})
+	c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "b", Title: "Second", BlockedBy: []string{"a"}})
+	c.do(http.MethodPost, "/v1/tasks/a/transitions", taskapi.TransitionRequest{Status: example5.StatusCompleted})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing title", http.MethodPost, "/v1/tasks", taskapi.TaskRequest{}, http.StatusBadRequest, "invalid_input"},
+		{"unknown field", http.MethodPost, "/v1/tasks", map[string]string{"title": "x", "owner": "bob"}, http.StatusBadRequest, "invalid_input"},
+		{"unknown priority", http.MethodPost, "/v1/tasks", map[string]string{"title": "x", "priority": "someday"}, http.StatusBadRequest, "invalid_input"},
+		{"duplicate ID", http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "a", Title: "Again"}, http.StatusConflict, "already_exists"},
+		{"dependency cycle", http.MethodPut, "/v1/tasks/a", taskapi.TaskRequest{Title: "First", BlockedBy: []string{"b"}}, http.StatusBadRequest, "dependency_cycle"},
+		{"mismatched ID", http.MethodPut, "/v1/tasks/b", taskapi.TaskRequest{ID: "a", Title: "x"}, http.StatusBadRequest, "invalid_input"},
+		{"reopen", http.MethodPost, "/v1/tasks/a/transitions", taskapi.TransitionRequest{Status: example5.StatusPending}, http.StatusConflict, "invalid_transition"},
+		{"unknown task", http.MethodPost, "/v1/tasks/zzz/transitions", taskapi.TransitionRequest{Status: example5.StatusCompleted}, http.StatusNotFound, "not_found"},
+		{"unknown route", http.MethodGet, "/v2/tasks", nil, http.StatusNotFound, "not_found"},
+		{"bad limit", http.MethodGet, "/v1/tasks?limit=0", nil, http.StatusBadRequest, "invalid_input"},
+		{"bad order", http.MethodGet, "/v1/tasks?order=up", nil, http.StatusBadRequest, "invalid_input"},
+		{"bad cursor", http.MethodGet, "/v1/tasks?cursor=nope", nil, http.StatusBadRequest, "invalid_cursor"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.method == http.MethodPut {
+				headers = []string{"If-Match", "*"}
+			}
+			if code := errorCode(t, c.do(tt.method, tt.path, tt.body, headers...), tt.wantStatus); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+
+	rec := c.do(http.MethodPatch, "/v1/tasks/a", nil)
+	if code := errorCode(t, rec, http.StatusMethodNotAllowed); code != "method_not_allowed" {
+		t.Errorf("PATCH code = %q", code)
+	}
+	if allow := rec.Header().Get("Allow"); allow != "GET, PUT, DELETE" {
+		t.Errorf("PATCH Allow = %q", allow)
+	}
+}
+
+func TestList(t *testing.T) {
+	c := newClient(t)
+	for i, title := range []string{"Alpha", "Beta", "Gamma", "Delta", "Epsilon"} {
+		req := taskapi.TaskRequest{Title: title}
+		if i%2 == 0 {
+			req.Tags = []string{"even"}
+			req.Priority = example5.PriorityHigh
+		}
+		c.do(http.MethodPost, "/v1/tasks", req)
+	}
+
+	var titles []string
+	path := "/v1/tasks?sort=title&order=desc&limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination did not end")
+		}
+		var list taskapi.TaskList
+		decode(t, c.do(http.MethodGet, path, nil), http.StatusOK, &list)
+		for _, task := range list.Tasks {
+			titles = append(titles, task.Title)
+		}
+		path = ""
+		if list.NextCursor != "" {
+			path = "/v1/tasks?sort=title&order=desc&limit=2&cursor=" + list.NextCursor
+		}
+	}
+	if want := []string{"Gamma", "Epsilon", "Delta", "Beta", "Alpha"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("pages listed %v, want %v", titles, want)
+	}
+
+	var list taskapi.TaskList
+	decode(t, c.do(http.MethodGet, "/v1/tasks?tag=even&min_priority=high&search=a", nil), http.StatusOK, &list)
+	if len(list.Tasks) != 2 || list.NextCursor != "" {
+		t.Errorf("filtered list = %+v, want Alpha and Gamma", list)
+	}
+
+	rec := c.do(http.MethodGet, "/v1/tasks?status=blocked", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"tasks":[]}` {
+		t.Errorf("empty list = %d %s, want an empty array", rec.Code, rec.Body)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := newClient(t)
+	c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "a", Title: "Write API docs"})
+	c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "b", Title: "Review docs", Description: "Check the API reference"})
+	c.do(http.MethodPost, "/v1/tasks", taskapi.TaskRequest{ID: "c", Title: "Deploy"})
+
+	var found taskapi.SearchResults
+	decode(t, c.do(http.MethodGet, "/v1/search?q=api+doc*", nil), http.StatusOK, &found)
+	var ids []string
+	for _, result := range found.Results {
+		ids = append(ids, result.Task.ID)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("search found %v, want %v", ids, want)
+	}
+
+	rec := c.do(http.MethodGet, "/v1/search?q=missing", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"results":[]}` {
+		t.Errorf("search without matches = %d %s, want an empty array", rec.Code, rec.Body)
+	}
+	if code := errorCode(t, c.do(http.MethodGet, "/v1/search", nil), http.StatusBadRequest); code != "invalid_input" {
+		t.Errorf("search without q code = %q", code)
+	}
+	if code := errorCode(t, c.do(http.MethodGet, "/v1/search?q=api&limit=none", nil), http.StatusBadRequest); code != "invalid_input" {
+		t.Errorf("search with a bad limit code = %q", code)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	c := newClient(t)
+	var doc map[string]any
+	decode(t, c.do(http.MethodGet, "/v1/openapi.json", nil), http.StatusOK, &doc)
+	if doc["openapi"] != "3.0.3" {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+
+	spec := taskapi.Spec()
+	for path, ops := range map[string][]string{
+		"/v1/tasks":                  {"get", "post"},
+		"/v1/tasks/{id}":             {"get", "put", "delete"},
+		"/v1/tasks/{id}/transitions": {"post"},
+		"/v1/openapi.json":           {"get"},
+	} {
+		for _, method := range ops {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("document has no %s %s", method, path)
+			}
+		}
+	}
+
+	// Every field the API writes for a task is described
+	task := spec.Components.Schemas["Task"]
+	if task == nil {
+		t.Fatal("document has no Task schema")
+	}
+	typ := reflect.TypeFor[example5.Task]()
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if _, ok := task.Properties[name]; !ok {
+			t.Errorf("Task schema has no property %q", name)
+		}
+	}
+
+	if got := task.Properties["due"]; got.Type != "string" || got.Format != "date-time" || !got.Nullable {
+		t.Errorf("due schema = %+v, want a nullable date-time", got)
+	}
+	if got := task.Properties["priority"].Enum; !reflect.DeepEqual(got, []string{"none", "low", "medium", "high", "urgent"}) {
+		t.Errorf("priority enum = %v", got)
+	}
+	if got := task.Properties["history"]; got.Type != "array" || got.Items.Ref != "#/components/schemas/StatusChange" {
+		t.Errorf("history schema = %+v, want an array of StatusChange", got)
+	}
+	if got := spec.Components.Schemas["TaskRequest"].Required; !reflect.DeepEqual(got, []string{"title"}) {
+		t.Errorf("TaskRequest requires %v, want only title", got)
+	}
+	put := spec.Paths["/v1/tasks/{id}"]["put"]
+	if _, ok := put.Responses["412"]; !ok {
+		t.Errorf("PUT responses %v do not document 412", put.Responses)
+	}
+}
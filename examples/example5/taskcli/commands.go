@@ -0,0 +1,425 @@
+package taskcli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"practice/examples/example5"
+)
+
+// timeLayout is how times are printed for people
+const timeLayout = "2006-01-02 15:04"
+
+// session is one command's open data file and output settings
+type session struct {
+	db      *sql.DB
+	service example5.TaskService
+	out     io.Writer
+	json    bool
+}
+
+func openSession(ctx context.Context, cfg Config, file string, asJSON bool) (*session, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	db, err := example5.OpenSQLite(file)
+	if err != nil {
+		return nil, err
+	}
+	service, err := example5.NewSQLTaskService(ctx, db, cfg.ServiceOptions...)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &session{db: db, service: service, out: cfg.Stdout, json: asJSON}, nil
+}
+
+func (s *session) close() {
+	s.db.Close()
+}
+
+// planningFlags are the flags add and update share for planning fields
+type planningFlags struct {
+	priority  *string
+	due       *string
+	tags      *string
+	blockedBy *string
+}
+
+func newPlanningFlags(fs *flag.FlagSet) planningFlags {
+	return planningFlags{
+		priority:  fs.String("priority", "none", "Priority: none, low, medium, high or urgent"),
+		due:       fs.String("due", "", `Due date as "2006-01-02" (end of day), "2006-01-02 15:04" or RFC 3339; "none" clears it`),
+		tags:      fs.String("tags", "", "Comma-separated tags, replacing any the task has"),
+		blockedBy: fs.String("blocked-by", "", "Comma-separated IDs of the tasks that must finish first"),
+	}
+}
+
+// apply copies the flags set on the command line to task
+func (p planningFlags) apply(fs *flag.FlagSet, task *example5.Task) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch f.Name {
+		case "priority":
+			task.Priority, err = example5.ParsePriority(*p.priority)
+		case "due":
+			task.Due, err = parseDue(*p.due)
+		case "tags":
+			task.Tags = splitList(*p.tags)
+		case "blocked-by":
+			task.BlockedBy = splitList(*p.blockedBy)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+	return nil
+}
+
+// parseDue reads a due date in local time. A bare date means the end of
+// that day; "" and "none" mean no due date.
+func parseDue(s string) (*time.Time, error) {
+	if s == "" || s == "none" {
+		return nil, nil
+	}
+	if day, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		due := day.Add(24*time.Hour - time.Minute)
+		return &due, nil
+	}
+	for _, layout := range []string{timeLayout, time.RFC3339} {
+		if due, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &due, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot read due date %q", s)
+}
+
+// splitList splits a comma-separated flag, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func addCommand(fs *flag.FlagSet) runFunc {
+	description := fs.String("description", "", "Task description")
+	planning := newPlanningFlags(fs)
+	return func(ctx context.Context, s *session, args []string) error {
+		title := strings.Join(args, " ")
+		if title == "" {
+			return fmt.Errorf("%w: add needs a title", ErrUsage)
+		}
+		template := &example5.Task{Title: title, Description: *description, Status: example5.StatusPending}
+		if err := planning.apply(fs, template); err != nil {
+			return err
+		}
+
+		// Another process may take the same ID between nextID and Create
+		for attempt := 0; ; attempt++ {
+			id, err := s.nextID(ctx)
+			if err != nil {
+				return err
+			}
+			task := *template
+			task.ID = id
+			err = s.service.Create(ctx, &task)
+			if errors.Is(err, example5.ErrAlreadyExists) && attempt < 3 {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			return s.printTask(&task)
+		}
+	}
+}
+
+// nextID returns one more than the largest numeric task ID, so tasks get
+// short IDs that are easy to type
+func (s *session) nextID(ctx context.Context) (string, error) {
+	page, err := s.service.List(ctx, example5.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	highest := 0
+	for _, task := range page.Tasks {
+		if n, err := strconv.Atoi(task.ID); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return strconv.Itoa(highest + 1), nil
+}
+
+func listCommand(fs *flag.FlagSet) runFunc {
+	status := fs.String("status", "", "Only list tasks in this status")
+	search := fs.String("search", "", "Only list tasks whose title contains this text")
+	sortBy := fs.String("sort", string(example5.SortByCreatedAt), "Order by created_at, updated_at or title")
+	descending := fs.Bool("desc", false, "Reverse the order")
+	tags := fs.String("tag", "", "Only list tasks with all of these comma-separated tags")
+	minPriority := fs.String("min-priority", "none", "Only list tasks of at least this priority")
+	overdue := fs.Bool("overdue", false, "Only list unfinished tasks past their due date")
+	limit := fs.Int("limit", 0, "List at most this many tasks (0 lists all)")
+	return func(ctx context.Context, s *session, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("%w: list takes no arguments", ErrUsage)
+		}
+		priority, err := example5.ParsePriority(*minPriority)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUsage, err)
+		}
+		page, err := s.service.List(ctx, example5.ListOptions{
+			Status:        example5.Status(*status),
+			TitleContains: *search,
+			Tags:          splitList(*tags),
+			MinPriority:   priority,
+			Overdue:       *overdue,
+			SortBy:        example5.SortField(*sortBy),
+			Descending:    *descending,
+			Limit:         *limit,
+		})
+		if err != nil {
+			return err
+		}
+		return s.printTasks(page.Tasks...)
+	}
+}
+
+func nextCommand(fs *flag.FlagSet) runFunc {
+	limit := fs.Int("limit", 0, "Show at most this many tasks (0 shows all)")
+	return func(ctx context.Context, s *session, args []string) error {
+		if len(args) > 0 {
+			return fmt.Errorf("%w: next takes no arguments", ErrUsage)
+		}
+		tasks, err := s.service.Next(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		return s.printTasks(tasks...)
+	}
+}
+
+func searchCommand(fs *flag.FlagSet) runFunc {
+	limit := fs.Int("limit", 0, "Show at most this many tasks (0 shows all)")
+	return func(ctx context.Context, s *session, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("%w: search needs a query: words, prefix* or \"a phrase\"", ErrUsage)
+		}
+		results, err := s.service.Search(ctx, strings.Join(args, " "), *limit)
+		if err != nil {
+			return err
+		}
+		// JSON keeps the scores and is always an array
+		if s.json {
+			return s.printJSON(results)
+		}
+		tasks := make([]*example5.Task, len(results))
+		for i, result := range results {
+			tasks[i] = result.Task
+		}
+		return s.printTasks(tasks...)
+	}
+}
+
+func showCommand(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, s *session, args []string) error {
+		id, err := oneID("show", args)
+		if err != nil {
+			return err
+		}
+		task, err := s.service.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if s.json {
+			return s.printJSON(task)
+		}
+		return s.printDetail(task)
+	}
+}
+
+func updateCommand(fs *flag.FlagSet) runFunc {
+	title := fs.String("title", "", "New title")
+	description := fs.String("description", "", "New description")
+	status := fs.String("status", "", "New status: pending, in_progress, blocked, completed or cancelled")
+	planning := newPlanningFlags(fs)
+	return func(ctx context.Context, s *session, args []string) error {
+		id, err := oneID("update", args)
+		if err != nil {
+			return err
+		}
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		delete(set, "file")
+		delete(set, "json")
+		if len(set) == 0 {
+			return fmt.Errorf("%w: update needs a flag naming what to change", ErrUsage)
+		}
+
+		task, err := s.service.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if set["title"] {
+			task.Title = *title
+		}
+		if set["description"] {
+			task.Description = *description
+		}
+		if set["status"] {
+			task.Status = example5.Status(*status)
+		}
+		if err := planning.apply(fs, task); err != nil {
+			return err
+		}
+		if err := s.service.Update(ctx, task); err != nil {
+			return err
+		}
+		return s.printTask(task)
+	}
+}
+
+func doneCommand(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, s *session, args []string) error {
+		id, err := oneID("done", args)
+		if err != nil {
+			return err
+		}
+		task, err := s.service.Transition(ctx, id, example5.StatusCompleted)
+		if err != nil {
+			return err
+		}
+		return s.printTask(task)
+	}
+}
+
+func removeCommand(fs *flag.FlagSet) runFunc {
+	return func(ctx context.Context, s *session, args []string) error {
+		id, err := oneID("rm", args)
+		if err != nil {
+			return err
+		}
+		task, err := s.service.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.service.Delete(ctx, id); err != nil {
+			return err
+		}
+		if s.json {
+			return s.printJSON(task)
+		}
+		_, err = fmt.Fprintf(s.out, "Deleted task %s: %s\n", task.ID, task.Title)
+		return err
+	}
+}
+
+// oneID returns the single task ID a command was given
+func oneID(name string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%w: %s needs exactly one task ID", ErrUsage, name)
+	}
+	return args[0], nil
+}
+
+// printTask writes the task changed by add, update or done as a one-row
+// table, or as a JSON object
+func (s *session) printTask(task *example5.Task) error {
+	if s.json {
+		return s.printJSON(task)
+	}
+	return s.printTasks(task)
+}
+
+// printTasks writes tasks as a table, or as a JSON array however many
+// there are
+func (s *session) printTasks(tasks ...*example5.Task) error {
+	if s.json {
+		if tasks == nil {
+			tasks = []*example5.Task{}
+		}
+		return s.printJSON(tasks)
+	}
+	if len(tasks) == 0 {
+		_, err := fmt.Fprintln(s.out, "No tasks.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tPRIORITY\tDUE\tTITLE\tUPDATED")
+	for _, task := range tasks {
+		due := "-"
+		if task.Due != nil {
+			due = formatTime(*task.Due)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", task.ID, task.Status, task.Priority, due, task.Title, formatTime(task.UpdatedAt))
+	}
+	return tw.Flush()
+}
+
+// printDetail writes every field of task, one per line, then its history
+func (s *session) printDetail(task *example5.Task) error {
+	tw := tabwriter.NewWriter(s.out, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", task.ID)
+	fmt.Fprintf(tw, "Title:\t%s\n", task.Title)
+	fmt.Fprintf(tw, "Description:\t%s\n", task.Description)
+	fmt.Fprintf(tw, "Status:\t%s\n", task.Status)
+	fmt.Fprintf(tw, "Priority:\t%s\n", task.Priority)
+	if task.Due != nil {
+		fmt.Fprintf(tw, "Due:\t%s\n", formatTime(*task.Due))
+	}
+	if len(task.Tags) > 0 {
+		fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(task.Tags, ", "))
+	}
+	if len(task.BlockedBy) > 0 {
+		fmt.Fprintf(tw, "Blocked by:\t%s\n", strings.Join(task.BlockedBy, ", "))
+	}
+	fmt.Fprintf(tw, "Created:\t%s%s\n", formatTime(task.CreatedAt), by(task.CreatedBy))
+	fmt.Fprintf(tw, "Updated:\t%s%s\n", formatTime(task.UpdatedAt), by(task.UpdatedBy))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(task.History) == 0 {
+		return nil
+	}
+	fmt.Fprintln(s.out, "History:")
+	for _, change := range task.History {
+		fmt.Fprintf(s.out, "  %s  %s -> %s%s\n", formatTime(change.At), change.From, change.To, by(change.By))
+	}
+	return nil
+}
+
+func (s *session) printJSON(v any) error {
+	enc := json.NewEncoder(s.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format(timeLayout)
+}
+
+func by(actor string) string {
+	if actor == "" {
+		return ""
+	}
+	return " by " + actor
+}
@@ -0,0 +1,169 @@
+// Package taskcli implements `practice tasks`, a command-line task manager
+// over example5's TaskService that keeps its tasks in a local SQLite file.
+package taskcli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"practice/examples/example5"
+)
+
+// ErrUsage reports a command line the tool cannot run. The message says
+// what was wrong; callers usually exit with status 2.
+var ErrUsage = errors.New("usage error")
+
+// DataFileEnv names the environment variable that overrides the default
+// data file
+const DataFileEnv = "PRACTICE_TASKS_FILE"
+
+// Config is where the tool reads and writes
+type Config struct {
+	// Stdout receives tables and JSON, Stderr usage messages
+	Stdout, Stderr io.Writer
+
+	// DataFile is the SQLite file used unless a command's -file flag says
+	// otherwise. Missing parent directories are created.
+	DataFile string
+
+	// Actor is recorded as the author of every change
+	Actor string
+
+	// ServiceOptions configure the task service, such as its clock
+	ServiceOptions []example5.Option
+}
+
+// DefaultDataFile returns $PRACTICE_TASKS_FILE if it is set, and otherwise
+// tasks.db in a practice directory under the user's config directory
+func DefaultDataFile() (string, error) {
+	if path := os.Getenv(DataFileEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the config directory: %w", err)
+	}
+	return filepath.Join(dir, "practice", "tasks.db"), nil
+}
+
+// runFunc runs a command against an open session with its positional
+// arguments
+type runFunc func(ctx context.Context, s *session, args []string) error
+
+// command is one subcommand of `practice tasks`. setup registers the
+// command's own flags and returns the function that runs it.
+type command struct {
+	name    string
+	args    string
+	summary string
+	setup   func(fs *flag.FlagSet) runFunc
+}
+
+var commands = []command{
+	{"add", "[flags] TITLE...", "Create a pending task", addCommand},
+	{"list", "[flags]", "List tasks, oldest first", listCommand},
+	{"next", "[flags]", "List unfinished tasks in the order to work on them", nextCommand},
+	{"search", "[flags] QUERY...", "Find tasks by words in their title or description", searchCommand},
+	{"show", "[flags] ID", "Show a task and its status history", showCommand},
+	{"update", "[flags] ID", "Change a task's title, description or status", updateCommand},
+	{"done", "[flags] ID", "Mark a task completed", doneCommand},
+	{"rm", "[flags] ID", "Delete a task", removeCommand},
+}
+
+// Usage writes the list of commands to w
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: practice tasks COMMAND [flags] [args]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-7s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nEvery command takes -file to choose the data file and -json to print JSON.")
+	fmt.Fprintln(w, "Run `practice tasks COMMAND -h` for a command's flags.")
+}
+
+// Run runs the command named by args[0]. It returns flag.ErrHelp when help
+// was asked for, and an error wrapping ErrUsage after printing usage to
+// cfg.Stderr when args are malformed.
+func Run(ctx context.Context, cfg Config, args []string) error {
+	if len(args) == 0 {
+		Usage(cfg.Stderr)
+		return fmt.Errorf("%w: no command given", ErrUsage)
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		Usage(cfg.Stdout)
+		return flag.ErrHelp
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.execute(ctx, cfg, args[1:])
+		}
+	}
+	Usage(cfg.Stderr)
+	return fmt.Errorf("%w: unknown command %q", ErrUsage, args[0])
+}
+
+// execute parses the command's flags, opens the data file and runs it
+func (cmd command) execute(ctx context.Context, cfg Config, args []string) error {
+	fs := flag.NewFlagSet("tasks "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(cfg.Stderr)
+	file := fs.String("file", cfg.DataFile, "SQLite data file, also set by $"+DataFileEnv)
+	asJSON := fs.Bool("json", false, "Print JSON instead of a table")
+	run := cmd.setup(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: practice tasks %s %s\n%s.\n\nFlags:\n", cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+	if *file == "" {
+		return fmt.Errorf("%w: no data file; pass -file or set %s", ErrUsage, DataFileEnv)
+	}
+
+	s, err := openSession(ctx, cfg, *file, *asJSON)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	if err := run(example5.WithActor(ctx, cfg.Actor), s, positional); err != nil {
+		if errors.Is(err, ErrUsage) {
+			fs.Usage()
+		}
+		return err
+	}
+	return nil
+}
+
+// parseInterspersed parses flags wherever they appear among args, so
+// `show 3 -json` works as well as `show -json 3`. Everything after "--" is
+// positional. It returns the positional arguments in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
@@ -0,0 +1,301 @@
+package taskcli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+	"practice/examples/example5"
+	"practice/examples/example5/taskcli"
+)
+
+// tool runs commands against one data file with a clock that starts at
+// demo.Epoch and advances a minute per reading
+type tool struct {
+	t   *testing.T
+	cfg taskcli.Config
+}
+
+func newTool(t *testing.T) *tool {
+	return &tool{t: t, cfg: taskcli.Config{
+		DataFile:       filepath.Join(t.TempDir(), "data", "tasks.db"),
+		Actor:          "alice",
+		ServiceOptions: []example5.Option{example5.WithClock(demo.StepClock(demo.Epoch, time.Minute))},
+	}}
+}
+
+// run returns what the command printed to stdout and stderr
+func (c *tool) run(args ...string) (stdout, stderr string, err error) {
+	var out, errOut bytes.Buffer
+	cfg := c.cfg
+	cfg.Stdout, cfg.Stderr = &out, &errOut
+	err = taskcli.Run(context.Background(), cfg, args)
+	return out.String(), errOut.String(), err
+}
+
+// mustRun returns stdout, failing the test if the command fails
+func (c *tool) mustRun(args ...string) string {
+	c.t.Helper()
+	out, stderr, err := c.run(args...)
+	if err != nil {
+		c.t.Fatalf("%s: %v\n%s", strings.Join(args, " "), err, stderr)
+	}
+	return out
+}
+
+// at is the clock's nth reading as the tool prints it
+func at(n int) string {
+	return demo.Epoch.Add(time.Duration(n) * time.Minute).Local().Format("2006-01-02 15:04")
+}
+
+func TestWorkflow(t *testing.T) {
+	c := newTool(t)
+	c.mustRun("add", "Write", "docs", "-description", "For the CLI")
+	c.mustRun("add", "Review code")
+	c.mustRun("update", "2", "-status", "in_progress")
+	c.mustRun("done", "1")
+
+	want := "ID  STATUS       PRIORITY  DUE  TITLE        UPDATED\n" +
+		"1   completed    none      -    Write docs   " + at(3) + "\n" +
+		"2   in_progress  none      -    Review code  " + at(2) + "\n"
+	if got := c.mustRun("list"); got != want {
+		t.Errorf("list printed\n%s\nwant\n%s", got, want)
+	}
+	if got := c.mustRun("list", "--status=pending"); got != "No tasks.\n" {
+		t.Errorf("list --status=pending printed %q, want no tasks", got)
+	}
+
+	want = "ID:          1\n" +
+		"Title:       Write docs\n" +
+		"Description: For the CLI\n" +
+		"Status:      completed\n" +
+		"Priority:    none\n" +
+		"Created:     " + at(0) + " by alice\n" +
+		"Updated:     " + at(3) + " by alice\n" +
+		"History:\n" +
+		"  " + at(3) + "  pending -> completed by alice\n"
+	if got := c.mustRun("show", "1"); got != want {
+		t.Errorf("show printed\n%s\nwant\n%s", got, want)
+	}
+
+	if got := c.mustRun("rm", "2"); got != "Deleted task 2: Review code\n" {
+		t.Errorf("rm printed %q", got)
+	}
+	if _, _, err := c.run("show", "2"); !errors.Is(err, example5.ErrNotFound) {
+		t.Errorf("show after rm error = %v, want %v", err, example5.ErrNotFound)
+	}
+
+	// IDs keep counting from the highest one left
+	if got := c.mustRun("add", "-json", "Deploy"); !strings.Contains(got, `"id": "2"`) {
+		t.Errorf("add printed %s, want ID 2", got)
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	c := newTool(t)
+	c.mustRun("add", "First")
+	c.mustRun("add", "Second")
+
+	var tasks []example5.Task
+	if err := json.Unmarshal([]byte(c.mustRun("list", "-json", "-sort", "title", "-desc")), &tasks); err != nil {
+		t.Fatalf("list -json: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "Second" || tasks[1].CreatedBy != "alice" {
+		t.Errorf("list -json = %+v", tasks)
+	}
+
+	// Flags may follow the ID
+	var task example5.Task
+	if err := json.Unmarshal([]byte(c.mustRun("show", "1", "-json")), &task); err != nil {
+		t.Fatalf("show -json: %v", err)
+	}
+	if task.ID != "1" || task.Status != example5.StatusPending {
+		t.Errorf("show -json = %+v", task)
+	}
+
+	if got := c.mustRun("list", "-json", "-status", "blocked"); got != "[]\n" {
+		t.Errorf("empty list -json printed %q, want []", got)
+	}
+
+	// One result is still an array
+	for _, args := range [][]string{{"list", "-json", "-limit", "1"}, {"next", "-json", "-limit", "1"}} {
+		tasks = nil
+		if err := json.Unmarshal([]byte(c.mustRun(args...)), &tasks); err != nil {
+			t.Fatalf("%s: %v", strings.Join(args, " "), err)
+		}
+		if len(tasks) != 1 {
+			t.Errorf("%s = %+v, want one task", strings.Join(args, " "), tasks)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	c := newTool(t)
+	c.mustRun("add", "Draft", "-description", "Keep me")
+	c.mustRun("update", "1", "-title", "Final")
+
+	var task example5.Task
+	if err := json.Unmarshal([]byte(c.mustRun("show", "-json", "1")), &task); err != nil {
+		t.Fatalf("show -json: %v", err)
+	}
+	if task.Title != "Final" || task.Description != "Keep me" {
+		t.Errorf("after update task = %+v, want new title and the old description", task)
+	}
+
+	c.mustRun("done", "1")
+	if _, _, err := c.run("update", "1", "-status", "pending"); !errors.Is(err, example5.ErrInvalidTransition) {
+		t.Errorf("reopening error = %v, want %v", err, example5.ErrInvalidTransition)
+	}
+}
+
+func TestPlanning(t *testing.T) {
+	c := newTool(t)
+	c.mustRun("add", "Design", "-priority", "low", "-tags", "Backend, api")
+	c.mustRun("add", "Build", "-priority", "urgent", "-blocked-by", "1")
+	c.mustRun("add", "Write docs", "-priority", "medium", "-due", "2024-01-01T08:00:00Z", "-tags", "docs")
+
+	var next []example5.Task
+	if err := json.Unmarshal([]byte(c.mustRun("next", "-json")), &next); err != nil {
+		t.Fatalf("next -json: %v", err)
+	}
+	var order []string
+	for _, task := range next {
+		order = append(order, task.ID)
+	}
+	if want := []string{"3", "1", "2"}; strings.Join(order, " ") != strings.Join(want, " ") {
+		t.Errorf("next order = %v, want %v", order, want)
+	}
+
+	// The due date is before the clock, which starts at demo.Epoch
+	if got := c.mustRun("list", "-overdue"); !strings.Contains(got, "Write docs") || strings.Contains(got, "Design") {
+		t.Errorf("list -overdue printed\n%s", got)
+	}
+	if got := c.mustRun("list", "-tag", "backend,API"); !strings.Contains(got, "Design") || strings.Contains(got, "Build") {
+		t.Errorf("list -tag printed\n%s", got)
+	}
+	if got := c.mustRun("list", "-min-priority", "medium"); strings.Contains(got, "Design") {
+		t.Errorf("list -min-priority printed\n%s", got)
+	}
+
+	show := c.mustRun("show", "1")
+	if !strings.Contains(show, "Priority:    low\n") || !strings.Contains(show, "Tags:        api, backend\n") {
+		t.Errorf("show printed\n%s", show)
+	}
+
+	c.mustRun("update", "3", "-due", "none")
+	if got := c.mustRun("list", "-overdue"); got != "No tasks.\n" {
+		t.Errorf("after clearing the due date list -overdue printed %q", got)
+	}
+
+	if _, _, err := c.run("update", "1", "-blocked-by", "2"); !errors.Is(err, example5.ErrDependencyCycle) {
+		t.Errorf("cycle error = %v, want %v", err, example5.ErrDependencyCycle)
+	}
+	if _, _, err := c.run("add", "Bad", "-priority", "someday"); !errors.Is(err, taskcli.ErrUsage) {
+		t.Errorf("unknown priority error = %v, want %v", err, taskcli.ErrUsage)
+	}
+	if _, _, err := c.run("add", "Bad", "-due", "tomorrow"); !errors.Is(err, taskcli.ErrUsage) {
+		t.Errorf("unreadable due date error = %v, want %v", err, taskcli.ErrUsage)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := newTool(t)
+	c.mustRun("add", "Write API docs", "-description", "Cover every endpoint")
+	c.mustRun("add", "Review docs")
+	c.mustRun("add", "Deploy")
+
+	if got := c.mustRun("search", "docs"); !strings.Contains(got, "Write API docs") || !strings.Contains(got, "Review docs") || strings.Contains(got, "Deploy") {
+		t.Errorf("search docs printed\n%s", got)
+	}
+	if got := c.mustRun("search", `"api docs"`, "endpoint*"); !strings.Contains(got, "Write API docs") || strings.Contains(got, "Review") {
+		t.Errorf("search for a phrase and a prefix printed\n%s", got)
+	}
+	if got := c.mustRun("search", "missing"); got != "No tasks.\n" {
+		t.Errorf("search missing printed %q", got)
+	}
+
+	var results []example5.SearchResult
+	if err := json.Unmarshal([]byte(c.mustRun("search", "-json", "-limit", "1", "docs")), &results); err != nil {
+		t.Fatalf("search -json: %v", err)
+	}
+	if len(results) != 1 || results[0].Task == nil || results[0].Score <= 0 {
+		t.Errorf("search -json -limit 1 = %+v", results)
+	}
+
+	if _, _, err := c.run("search"); !errors.Is(err, taskcli.ErrUsage) {
+		t.Errorf("search without a query error = %v, want %v", err, taskcli.ErrUsage)
+	}
+	if _, _, err := c.run("search", `"unterminated`); !errors.Is(err, example5.ErrInvalidInput) {
+		t.Errorf("search with a bad query error = %v, want %v", err, example5.ErrInvalidInput)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"bogus"}},
+		{"unknown flag", []string{"list", "-bogus"}},
+		{"add without title", []string{"add"}},
+		{"show without ID", []string{"show"}},
+		{"done with two IDs", []string{"done", "1", "2"}},
+		{"update without changes", []string{"update", "1"}},
+		{"list with arguments", []string{"list", "pending"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, err := newTool(t).run(tt.args...)
+			if !errors.Is(err, taskcli.ErrUsage) {
+				t.Fatalf("error = %v, want %v", err, taskcli.ErrUsage)
+			}
+			if !strings.Contains(stderr, "Usage: practice tasks") {
+				t.Errorf("stderr = %q, want usage", stderr)
+			}
+		})
+	}
+}
+
+func TestHelp(t *testing.T) {
+	c := newTool(t)
+	out, _, err := c.run("help")
+	if !errors.Is(err, flag.ErrHelp) || !strings.Contains(out, "rm      Delete a task") {
+		t.Errorf("help = %v, printed %q", err, out)
+	}
+	if _, stderr, err := c.run("add", "-h"); !errors.Is(err, flag.ErrHelp) || !strings.Contains(stderr, "-description") {
+		t.Errorf("add -h = %v, printed %q", err, stderr)
+	}
+}
+
+func TestDataFilePersists(t *testing.T) {
+	c := newTool(t)
+	c.mustRun("add", "Survives")
+
+	other := newTool(t)
+	other.cfg.DataFile = c.cfg.DataFile
+	if got := other.mustRun("list"); !strings.Contains(got, "Survives") {
+		t.Errorf("second run listed %q", got)
+	}
+
+	// -file overrides the configured data file
+	elsewhere := filepath.Join(t.TempDir(), "other.db")
+	if got := c.mustRun("list", "-file", elsewhere); got != "No tasks.\n" {
+		t.Errorf("list -file printed %q, want no tasks", got)
+	}
+}
+
+func TestDefaultDataFile(t *testing.T) {
+	t.Setenv(taskcli.DataFileEnv, "/tmp/custom.db")
+	if got, err := taskcli.DefaultDataFile(); err != nil || got != "/tmp/custom.db" {
+		t.Errorf("DefaultDataFile() = %q, %v, want the environment override", got, err)
+	}
+}
@@ -0,0 +1,1167 @@
+// Package tasktest provides a conformance suite for example5.TaskService
+// implementations, so the in-memory and SQL services are held to the same
+// behavior.
+package tasktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"practice/examples/demo"
+	"practice/examples/example5"
+)
+
+// Factory returns a new, empty service using opts. Cleanup should be
+// registered with t.Cleanup.
+type Factory func(t *testing.T, opts ...example5.Option) example5.TaskService
+
+// TestTaskService runs the conformance suite against services made by
+// newService. Every subtest gets a fresh service whose clock starts at
+// demo.Epoch and advances a minute per reading.
+func TestTaskService(t *testing.T, newService Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, service example5.TaskService)
+	}{
+		{"CreateThenGet", testCreateThenGet},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"CreateInvalid", testCreateInvalid},
+		{"GetMissing", testGetMissing},
+		{"Update", testUpdate},
+		{"UpdateMissing", testUpdateMissing},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"ReturnsCopies", testReturnsCopies},
+		{"Transition", testTransition},
+		{"TransitionRejected", testTransitionRejected},
+		{"ListFilters", testListFilters},
+		{"ListPagination", testListPagination},
+		{"ListInvalidCursor", testListInvalidCursor},
+		{"RecordsActor", testRecordsActor},
+		{"CancelledContext", testCancelledContext},
+		{"DeadlineExceeded", testDeadlineExceeded},
+		{"PlanningFields", testPlanningFields},
+		{"PlanningInvalid", testPlanningInvalid},
+		{"ListPlanningFilters", testListPlanningFilters},
+		{"ListOverdue", testListOverdue},
+		{"Dependencies", testDependencies},
+		{"DependencyCycle", testDependencyCycle},
+		{"Next", testNext},
+		{"Search", testSearch},
+		{"SearchRanking", testSearchRanking},
+		{"SearchFollowsChanges", testSearchFollowsChanges},
+		{"SearchInvalid", testSearchInvalid},
+		{"SearchConcurrentWrites", testSearchConcurrentWrites},
+		{"Watch", testWatch},
+		{"WatchFilter", testWatchFilter},
+		{"WatchStopsOnCancel", testWatchStopsOnCancel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newService(t, example5.WithClock(demo.StepClock(demo.Epoch, time.Minute))))
+		})
+	}
+
+	t.Run("AuditLog", func(t *testing.T) {
+		log := example5.NewMemoryAuditLog()
+		service := newService(t, example5.WithClock(demo.StepClock(demo.Epoch, time.Minute)), example5.WithAuditLog(log))
+		testAuditLog(t, service, log)
+	})
+	t.Run("DeleteDropsDependency", func(t *testing.T) {
+		log := example5.NewMemoryAuditLog()
+		service := newService(t, example5.WithClock(demo.StepClock(demo.Epoch, time.Minute)), example5.WithAuditLog(log))
+		testDeleteDropsDependency(t, service, log)
+	})
+	t.Run("AuditLogFailure", func(t *testing.T) {
+		service := newService(t, example5.WithAuditLog(failingLog{}))
+		testAuditLogFailure(t, service)
+	})
+}
+
+// at is the clock's nth reading
+func at(n int) time.Time {
+	return demo.Epoch.Add(time.Duration(n) * time.Minute)
+}
+
+func newTask(id int) *example5.Task {
+	return &example5.Task{
+		ID:          fmt.Sprintf("%02d", id),
+		Title:       fmt.Sprintf("Task %d", id),
+		Description: "Conformance test task",
+		Status:      example5.StatusPending,
+	}
+}
+
+func mustCreate(t *testing.T, service example5.TaskService, task *example5.Task) {
+	t.Helper()
+	if err := service.Create(context.Background(), task); err != nil {
+		t.Fatalf("Create(%s) error = %v", task.ID, err)
+	}
+}
+
+func mustGet(t *testing.T, service example5.TaskService, id string) *example5.Task {
+	t.Helper()
+	task, err := service.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", id, err)
+	}
+	return task
+}
+
+func assertEqual(t *testing.T, got, want *example5.Task) {
+	t.Helper()
+	if got.ID != want.ID || got.Title != want.Title || got.Description != want.Description ||
+		got.Status != want.Status || !got.CreatedAt.Equal(want.CreatedAt) || !got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("got task %+v, want %+v", got, want)
+	}
+}
+
+func ids(tasks []*example5.Task) []string {
+	result := make([]string, len(tasks))
+	for i, task := range tasks {
+		result[i] = task.ID
+	}
+	return result
+}
+
+func testCreateThenGet(t *testing.T, service example5.TaskService) {
+	want := newTask(1)
+	mustCreate(t, service, want)
+	if !want.CreatedAt.Equal(at(0)) || !want.UpdatedAt.Equal(at(0)) {
+		t.Errorf("Create set times %v, %v, want %v", want.CreatedAt, want.UpdatedAt, at(0))
+	}
+	assertEqual(t, mustGet(t, service, want.ID), want)
+}
+
+func testCreateDuplicate(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+
+	dup := newTask(1)
+	dup.Title = "Duplicate"
+	if err := service.Create(context.Background(), dup); !errors.Is(err, example5.ErrAlreadyExists) {
+		t.Fatalf("Create(duplicate) error = %v, want %v", err, example5.ErrAlreadyExists)
+	}
+	if got := mustGet(t, service, "01"); got.Title != "Task 1" {
+		t.Errorf("duplicate overwrote the task: %+v", got)
+	}
+}
+
+func testCreateInvalid(t *testing.T, service example5.TaskService) {
+	for _, task := range []*example5.Task{
+		{ID: "1", Status: example5.StatusPending},
+		{ID: "2", Title: "No status"},
+		{ID: "3", Title: "Unknown status", Status: "banana"},
+	} {
+		if err := service.Create(context.Background(), task); !errors.Is(err, example5.ErrInvalidInput) {
+			t.Errorf("Create(%+v) error = %v, want %v", task, err, example5.ErrInvalidInput)
+		}
+	}
+	page, err := service.List(context.Background(), example5.ListOptions{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(page.Tasks) != 0 {
+		t.Errorf("invalid tasks were stored: %v", ids(page.Tasks))
+	}
+}
+
+func testGetMissing(t *testing.T, service example5.TaskService) {
+	if _, err := service.Get(context.Background(), "missing"); !errors.Is(err, example5.ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want %v", err, example5.ErrNotFound)
+	}
+}
+
+func testUpdate(t *testing.T, service example5.TaskService) {
+	task := newTask(1)
+	mustCreate(t, service, task)
+
+	// A fresh value without timestamps keeps the stored creation time
+	update := newTask(1)
+	update.Title = "Renamed"
+	if err := service.Update(context.Background(), update); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	want := newTask(1)
+	want.Title = "Renamed"
+	want.CreatedAt = at(0)
+	want.UpdatedAt = at(1)
+	assertEqual(t, update, want)
+	assertEqual(t, mustGet(t, service, "01"), want)
+}
+
+func testUpdateMissing(t *testing.T, service example5.TaskService) {
+	if err := service.Update(context.Background(), newTask(1)); !errors.Is(err, example5.ErrNotFound) {
+		t.Fatalf("Update(missing) error = %v, want %v", err, example5.ErrNotFound)
+	}
+
+	mustCreate(t, service, newTask(1))
+	invalid := newTask(1)
+	invalid.Title = ""
+	if err := service.Update(context.Background(), invalid); !errors.Is(err, example5.ErrInvalidInput) {
+		t.Fatalf("Update(invalid) error = %v, want %v", err, example5.ErrInvalidInput)
+	}
+}
+
+func testDelete(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+	if _, err := service.Transition(context.Background(), "01", example5.StatusInProgress); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	if err := service.Delete(context.Background(), "01"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := service.Get(context.Background(), "01"); !errors.Is(err, example5.ErrNotFound) {
+		t.Fatalf("Get(deleted) error = %v, want %v", err, example5.ErrNotFound)
+	}
+
+	// The ID can be reused and starts without history
+	mustCreate(t, service, newTask(1))
+	if got := mustGet(t, service, "01"); len(got.History) != 0 {
+		t.Errorf("recreated task has history %+v", got.History)
+	}
+}
+
+func testDeleteMissing(t *testing.T, service example5.TaskService) {
+	if err := service.Delete(context.Background(), "missing"); !errors.Is(err, example5.ErrNotFound) {
+		t.Fatalf("Delete(missing) error = %v, want %v", err, example5.ErrNotFound)
+	}
+}
+
+func testReturnsCopies(t *testing.T, service example5.TaskService) {
+	task := newTask(1)
+	mustCreate(t, service, task)
+	task.Title = "Changed after Create"
+
+	got := mustGet(t, service, "01")
+	got.Status = example5.StatusCancelled
+	if again := mustGet(t, service, "01"); again.Title != "Task 1" || again.Status != example5.StatusPending {
+		t.Errorf("stored task changed through a returned value: %+v", again)
+	}
+}
+
+func testTransition(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+
+	for _, to := range []example5.Status{example5.StatusInProgress, example5.StatusBlocked, example5.StatusInProgress} {
+		if _, err := service.Transition(context.Background(), "01", to); err != nil {
+			t.Fatalf("Transition(%s) error = %v", to, err)
+		}
+	}
+	task := mustGet(t, service, "01")
+	task.Status = example5.StatusCompleted
+	if err := service.Update(context.Background(), task); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	want := []example5.StatusChange{
+		{From: example5.StatusPending, To: example5.StatusInProgress, At: at(1)},
+		{From: example5.StatusInProgress, To: example5.StatusBlocked, At: at(2)},
+		{From: example5.StatusBlocked, To: example5.StatusInProgress, At: at(3)},
+		{From: example5.StatusInProgress, To: example5.StatusCompleted, At: at(4)},
+	}
+	got := mustGet(t, service, "01")
+	if len(got.History) != len(want) {
+		t.Fatalf("History = %+v, want %+v", got.History, want)
+	}
+	for i := range want {
+		if got.History[i].From != want[i].From || got.History[i].To != want[i].To || !got.History[i].At.Equal(want[i].At) {
+			t.Errorf("History[%d] = %+v, want %+v", i, got.History[i], want[i])
+		}
+	}
+	if got.Status != example5.StatusCompleted || !got.UpdatedAt.Equal(at(4)) {
+		t.Errorf("task = %+v", got)
+	}
+}
+
+func testTransitionRejected(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+	if _, err := service.Transition(context.Background(), "01", example5.StatusCancelled); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	_, err := service.Transition(context.Background(), "01", example5.StatusPending)
+	var transitionErr *example5.TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("Transition(cancelled -> pending) error = %v, want *TransitionError", err)
+	}
+	if transitionErr.From != example5.StatusCancelled || transitionErr.To != example5.StatusPending {
+		t.Errorf("error = %+v", transitionErr)
+	}
+
+	if _, err := service.Transition(context.Background(), "missing", example5.StatusCompleted); !errors.Is(err, example5.ErrNotFound) {
+		t.Errorf("Transition(missing) error = %v, want %v", err, example5.ErrNotFound)
+	}
+	if _, err := service.Transition(context.Background(), "01", "banana"); !errors.Is(err, example5.ErrInvalidInput) {
+		t.Errorf("Transition(banana) error = %v, want %v", err, example5.ErrInvalidInput)
+	}
+	if got := mustGet(t, service, "01"); got.Status != example5.StatusCancelled || len(got.History) != 1 {
+		t.Errorf("rejected transitions changed the task: %+v", got)
+	}
+}
+
+// createSix creates tasks 01..06 at readings 0..5, titled so that title
+// order is the reverse of creation order, with 03 and 06 completed
+func createSix(t *testing.T, service example5.TaskService) {
+	t.Helper()
+	for i := 1; i <= 6; i++ {
+		task := newTask(i)
+		task.Title = fmt.Sprintf("Task %c", 'a'+6-i)
+		if i%3 == 0 {
+			task.Status = example5.StatusCompleted
+		}
+		mustCreate(t, service, task)
+	}
+}
+
+func testListFilters(t *testing.T, service example5.TaskService) {
+	createSix(t, service)
+
+	tests := []struct {
+		name string
+		opts example5.ListOptions
+		want []string
+	}{
+		{"all", example5.ListOptions{}, []string{"01", "02", "03", "04", "05", "06"}},
+		{"newest first", example5.ListOptions{Descending: true}, []string{"06", "05", "04", "03", "02", "01"}},
+		{"by title", example5.ListOptions{SortBy: example5.SortByTitle}, []string{"06", "05", "04", "03", "02", "01"}},
+		{"status", example5.ListOptions{Status: example5.StatusCompleted}, []string{"03", "06"}},
+		{"title", example5.ListOptions{TitleContains: "TASK E"}, []string{"02"}},
+		{"created", example5.ListOptions{Created: example5.TimeRange{From: at(1), To: at(3)}}, []string{"02", "03"}},
+		{"updated", example5.ListOptions{Updated: example5.TimeRange{From: at(4)}, SortBy: example5.SortByUpdatedAt, Descending: true}, []string{"06", "05"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := service.List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if got := ids(page.Tasks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testListPagination(t *testing.T, service example5.TaskService) {
+	ctx := context.Background()
+	createSix(t, service)
+
+	opts := example5.ListOptions{SortBy: example5.SortByTitle, Limit: 2}
+	first, err := service.List(ctx, opts)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := ids(first.Tasks); !reflect.DeepEqual(got, []string{"06", "05"}) || first.NextCursor == "" {
+		t.Fatalf("first page = %v, cursor %q", got, first.NextCursor)
+	}
+
+	// Changes around the cursor neither skip nor repeat tasks
+	for _, id := range []string{"05", "03"} {
+		if err := service.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete(%s) error = %v", id, err)
+		}
+	}
+	late := newTask(7)
+	late.Title = "Task z"
+	mustCreate(t, service, late)
+
+	var rest []string
+	opts.Cursor = first.NextCursor
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("pagination does not terminate")
+		}
+		page, err := service.List(ctx, opts)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		rest = append(rest, ids(page.Tasks)...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if want := []string{"04", "02", "01", "07"}; !reflect.DeepEqual(rest, want) {
+		t.Errorf("remaining pages = %v, want %v", rest, want)
+	}
+}
+
+func testListInvalidCursor(t *testing.T, service example5.TaskService) {
+	createSix(t, service)
+	page, err := service.List(context.Background(), example5.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	for _, opts := range []example5.ListOptions{
+		{Cursor: "garbage"},
+		{Cursor: page.NextCursor, Descending: true},
+		{Cursor: page.NextCursor, Status: example5.StatusPending},
+	} {
+		if _, err := service.List(context.Background(), opts); !errors.Is(err, example5.ErrInvalidCursor) {
+			t.Errorf("List(%+v) error = %v, want %v", opts, err, example5.ErrInvalidCursor)
+		}
+	}
+}
+
+func testRecordsActor(t *testing.T, service example5.TaskService) {
+	alice := example5.WithRequestID(example5.WithActor(context.Background(), "alice"), "req-1")
+	bob := example5.WithRequestID(example5.WithActor(context.Background(), "bob"), "req-2")
+
+	task := newTask(1)
+	if err := service.Create(alice, task); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if task.CreatedBy != "alice" || task.UpdatedBy != "alice" || task.RequestID != "req-1" {
+		t.Errorf("Create recorded %q/%q/%q, want alice/alice/req-1", task.CreatedBy, task.UpdatedBy, task.RequestID)
+	}
+
+	// Values supplied by the caller are not trusted
+	task.CreatedBy = "mallory"
+	task.Title = "Renamed by bob"
+	if err := service.Update(bob, task); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := service.Transition(bob, "01", example5.StatusInProgress); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	got := mustGet(t, service, "01")
+	if got.CreatedBy != "alice" || got.UpdatedBy != "bob" || got.RequestID != "req-2" {
+		t.Errorf("stored %q/%q/%q, want alice/bob/req-2", got.CreatedBy, got.UpdatedBy, got.RequestID)
+	}
+	if len(got.History) != 1 || got.History[0].By != "bob" {
+		t.Errorf("History = %+v, want one change by bob", got.History)
+	}
+}
+
+// testCancelledContext checks that no method does any work once ctx is
+// cancelled, and that the error still matches context.Canceled
+func testCancelledContext(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	update := newTask(1)
+	update.Title = "Too late"
+	calls := map[string]error{
+		"Create": service.Create(ctx, newTask(2)),
+		"Update": service.Update(ctx, update),
+		"Delete": service.Delete(ctx, "01"),
+	}
+	_, calls["Get"] = service.Get(ctx, "01")
+	_, calls["List"] = service.List(ctx, example5.ListOptions{})
+	_, calls["Transition"] = service.Transition(ctx, "01", example5.StatusCompleted)
+	_, calls["Search"] = service.Search(ctx, "task", 0)
+
+	for method, err := range calls {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s() error = %v, want %v", method, err, context.Canceled)
+		}
+	}
+
+	got := mustGet(t, service, "01")
+	if got.Title != "Task 1" || got.Status != example5.StatusPending {
+		t.Errorf("cancelled calls changed the task: %+v", got)
+	}
+	if _, err := service.Get(context.Background(), "02"); !errors.Is(err, example5.ErrNotFound) {
+		t.Errorf("cancelled Create stored the task: Get() error = %v", err)
+	}
+}
+
+func testDeadlineExceeded(t *testing.T, service example5.TaskService) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	err := service.Create(ctx, newTask(1))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Create() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// receive returns the next event on events, failing the test if none
+// arrives promptly
+func receive(t *testing.T, events <-chan example5.ChangeEvent) example5.ChangeEvent {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("watch channel closed")
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return example5.ChangeEvent{}
+}
+
+func mustWatch(t *testing.T, service example5.TaskService, filter example5.WatchFilter) <-chan example5.ChangeEvent {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	events, err := service.Watch(ctx, filter)
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	return events
+}
+
+// makeChanges creates, renames, starts and deletes task 01 as alice
+func makeChanges(t *testing.T, service example5.TaskService) {
+	t.Helper()
+	ctx := example5.WithRequestID(example5.WithActor(context.Background(), "alice"), "req-1")
+	task := newTask(1)
+	if err := service.Create(ctx, task); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	task.Title = "Renamed"
+	if err := service.Update(ctx, task); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := service.Transition(ctx, "01", example5.StatusInProgress); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	if err := service.Delete(ctx, "01"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+}
+
+// assertChanges checks events against the changes made by makeChanges
+func assertChanges(t *testing.T, events []example5.ChangeEvent) {
+	t.Helper()
+	want := []struct {
+		kind example5.ChangeKind
+		diff []string
+		at   time.Time
+	}{
+		{example5.ChangeCreated, []string{"title", "description", "status"}, at(0)},
+		{example5.ChangeUpdated, []string{"title"}, at(1)},
+		{example5.ChangeUpdated, []string{"status"}, at(2)},
+		{example5.ChangeDeleted, []string{"title", "description", "status"}, at(3)},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		e := events[i]
+		var fields []string
+		for _, c := range e.Diff {
+			fields = append(fields, c.Field)
+		}
+		if e.Kind != w.kind || e.TaskID != "01" || !reflect.DeepEqual(fields, w.diff) || !e.At.Equal(w.at) {
+			t.Errorf("event %d = %s %s %v at %v, want %s 01 %v at %v", i, e.Kind, e.TaskID, fields, e.At, w.kind, w.diff, w.at)
+		}
+		if e.Actor != "alice" || e.RequestID != "req-1" {
+			t.Errorf("event %d by %q in %q, want alice in req-1", i, e.Actor, e.RequestID)
+		}
+		if (e.Before == nil) != (w.kind == example5.ChangeCreated) || (e.After == nil) != (w.kind == example5.ChangeDeleted) {
+			t.Errorf("event %d has Before %v and After %v", i, e.Before, e.After)
+		}
+	}
+
+	if events[1].Diff[0].Before != "Task 1" || events[1].Diff[0].After != "Renamed" {
+		t.Errorf("rename diff = %+v", events[1].Diff[0])
+	}
+	if after := events[2].After; after == nil || after.Status != example5.StatusInProgress || len(after.History) != 1 {
+		t.Errorf("transition snapshot = %+v, want in_progress with one history entry", after)
+	}
+}
+
+func testWatch(t *testing.T, service example5.TaskService) {
+	events := mustWatch(t, service, example5.WatchFilter{})
+	makeChanges(t, service)
+
+	got := make([]example5.ChangeEvent, 4)
+	for i := range got {
+		got[i] = receive(t, events)
+	}
+	assertChanges(t, got)
+}
+
+func testWatchFilter(t *testing.T, service example5.TaskService) {
+	events := mustWatch(t, service, example5.WatchFilter{TaskID: "02", Kinds: []example5.ChangeKind{example5.ChangeUpdated}})
+
+	mustCreate(t, service, newTask(1))
+	mustCreate(t, service, newTask(2))
+	for _, id := range []string{"01", "02"} {
+		if _, err := service.Transition(context.Background(), id, example5.StatusBlocked); err != nil {
+			t.Fatalf("Transition(%s) error = %v", id, err)
+		}
+	}
+
+	if e := receive(t, events); e.TaskID != "02" || e.Kind != example5.ChangeUpdated {
+		t.Errorf("got %s of %s, want update of 02", e.Kind, e.TaskID)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %s of %s", e.Kind, e.TaskID)
+	default:
+	}
+}
+
+func testWatchStopsOnCancel(t *testing.T, service example5.TaskService) {
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := service.Watch(ctx, example5.WatchFilter{})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("got an event after cancel, want the channel closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+	mustCreate(t, service, newTask(1))
+
+	if _, err := service.Watch(ctx, example5.WatchFilter{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Watch() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func testAuditLog(t *testing.T, service example5.TaskService, log *example5.MemoryAuditLog) {
+	makeChanges(t, service)
+	assertChanges(t, log.Events(example5.WatchFilter{}))
+
+	// Failed changes are not logged
+	if err := service.Delete(context.Background(), "01"); !errors.Is(err, example5.ErrNotFound) {
+		t.Fatalf("Delete() error = %v, want %v", err, example5.ErrNotFound)
+	}
+	if got := log.Events(example5.WatchFilter{}); len(got) != 4 {
+		t.Errorf("log has %d events after a failed delete, want 4", len(got))
+	}
+}
+
+// errLogFull is returned by failingLog
+var errLogFull = errors.New("audit log full")
+
+// failingLog refuses every event
+type failingLog struct{}
+
+func (failingLog) Append(context.Context, example5.ChangeEvent) error {
+	return errLogFull
+}
+
+// testAuditLogFailure checks that a change the audit log refuses is not made
+func testAuditLogFailure(t *testing.T, service example5.TaskService) {
+	events := mustWatch(t, service, example5.WatchFilter{})
+
+	err := service.Create(context.Background(), newTask(1))
+	if !errors.Is(err, errLogFull) {
+		t.Fatalf("Create() error = %v, want %v", err, errLogFull)
+	}
+	if _, err := service.Get(context.Background(), "01"); !errors.Is(err, example5.ErrNotFound) {
+		t.Errorf("unlogged Create stored the task: Get() error = %v", err)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unlogged change published %s of %s", e.Kind, e.TaskID)
+	default:
+	}
+}
+
+func testPlanningFields(t *testing.T, service example5.TaskService) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	due := time.Date(2024, 2, 1, 18, 0, 0, 0, tokyo)
+	task := newTask(1)
+	task.Priority = example5.PriorityHigh
+	task.Due = &due
+	task.Tags = []string{"Go", " backend", "go"}
+	mustCreate(t, service, task)
+
+	got := mustGet(t, service, "01")
+	if got.Priority != example5.PriorityHigh {
+		t.Errorf("Priority = %v, want high", got.Priority)
+	}
+	if got.Due == nil || !got.Due.Equal(due) || got.Due.Location() != time.UTC {
+		t.Errorf("Due = %v, want %v in UTC", got.Due, due)
+	}
+	if want := []string{"backend", "go"}; !reflect.DeepEqual(got.Tags, want) {
+		t.Errorf("Tags = %q, want %q", got.Tags, want)
+	}
+
+	// Clearing the fields stores them as unset
+	got.Priority = example5.PriorityNone
+	got.Due = nil
+	got.Tags = nil
+	if err := service.Update(context.Background(), got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got = mustGet(t, service, "01")
+	if got.Priority != example5.PriorityNone || got.Due != nil || got.Tags != nil {
+		t.Errorf("after clearing got %v/%v/%q, want none/nil/nil", got.Priority, got.Due, got.Tags)
+	}
+}
+
+func testPlanningInvalid(t *testing.T, service example5.TaskService) {
+	tests := []struct {
+		name   string
+		modify func(task *example5.Task)
+	}{
+		{"unknown priority", func(task *example5.Task) { task.Priority = 9 }},
+		{"empty tag", func(task *example5.Task) { task.Tags = []string{"ok", " "} }},
+		{"tag with space", func(task *example5.Task) { task.Tags = []string{"two words"} }},
+		{"unknown dependency", func(task *example5.Task) { task.BlockedBy = []string{"99"} }},
+		{"empty dependency", func(task *example5.Task) { task.BlockedBy = []string{""} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := newTask(1)
+			tt.modify(task)
+			if err := service.Create(context.Background(), task); !errors.Is(err, example5.ErrInvalidInput) {
+				t.Errorf("Create() error = %v, want %v", err, example5.ErrInvalidInput)
+			}
+		})
+	}
+}
+
+func testListPlanningFilters(t *testing.T, service example5.TaskService) {
+	fields := []struct {
+		priority example5.Priority
+		tags     []string
+		due      int
+	}{
+		{example5.PriorityLow, []string{"go", "docs"}, 10},
+		{example5.PriorityHigh, []string{"go"}, 20},
+		{example5.PriorityUrgent, []string{"docs"}, 0},
+		{example5.PriorityMedium, nil, 30},
+	}
+	for i, f := range fields {
+		task := newTask(i + 1)
+		task.Priority = f.priority
+		task.Tags = f.tags
+		if f.due > 0 {
+			due := at(f.due)
+			task.Due = &due
+		}
+		mustCreate(t, service, task)
+	}
+
+	tests := []struct {
+		name string
+		opts example5.ListOptions
+		want []string
+	}{
+		{"one tag", example5.ListOptions{Tags: []string{"go"}}, []string{"01", "02"}},
+		{"every tag", example5.ListOptions{Tags: []string{"GO", "docs"}}, []string{"01"}},
+		{"min priority", example5.ListOptions{MinPriority: example5.PriorityHigh}, []string{"02", "03"}},
+		{"due from", example5.ListOptions{Due: example5.TimeRange{From: at(20)}}, []string{"02", "04"}},
+		{"due before", example5.ListOptions{Due: example5.TimeRange{To: at(20)}}, []string{"01"}},
+		{"tag and priority", example5.ListOptions{Tags: []string{"docs"}, MinPriority: example5.PriorityMedium}, []string{"03"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := service.List(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if got := ids(page.Tasks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testListOverdue(t *testing.T, service example5.TaskService) {
+	// Creations read the clock at minutes 0 to 3 and the completion at 4
+	for i, due := range []int{2, 100, 1, -1} {
+		task := newTask(i + 1)
+		if due >= 0 {
+			at := at(due)
+			task.Due = &at
+		}
+		mustCreate(t, service, task)
+	}
+	if _, err := service.Transition(context.Background(), "03", example5.StatusCompleted); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+
+	// The list reads the clock at minute 5: task 1 is overdue, task 2 is
+	// not due yet, task 3 is done and task 4 has no due date
+	page, err := service.List(context.Background(), example5.ListOptions{Overdue: true})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if got := ids(page.Tasks); !reflect.DeepEqual(got, []string{"01"}) {
+		t.Errorf("overdue tasks = %v, want [01]", got)
+	}
+	if !page.Tasks[0].Overdue(at(5)) || page.Tasks[0].Overdue(at(1)) {
+		t.Error("Task.Overdue disagrees with the due date")
+	}
+}
+
+func testDependencies(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+	mustCreate(t, service, newTask(2))
+	task := newTask(3)
+	task.BlockedBy = []string{"02", "01", "02"}
+	mustCreate(t, service, task)
+
+	got := mustGet(t, service, "03")
+	if want := []string{"01", "02"}; !reflect.DeepEqual(got.BlockedBy, want) {
+		t.Errorf("BlockedBy = %v, want %v", got.BlockedBy, want)
+	}
+
+	got.BlockedBy = []string{"01"}
+	if err := service.Update(context.Background(), got); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := mustGet(t, service, "03"); !reflect.DeepEqual(got.BlockedBy, []string{"01"}) {
+		t.Errorf("after update BlockedBy = %v, want [01]", got.BlockedBy)
+	}
+}
+
+func testDependencyCycle(t *testing.T, service example5.TaskService) {
+	self := newTask(1)
+	self.BlockedBy = []string{"01"}
+	var cycle *example5.DependencyCycleError
+	if err := service.Create(context.Background(), self); !errors.As(err, &cycle) {
+		t.Fatalf("self dependency error = %v, want a DependencyCycleError", err)
+	}
+
+	// 03 waits on 02, which waits on 01
+	for i := 1; i <= 3; i++ {
+		task := newTask(i)
+		if i > 1 {
+			task.BlockedBy = []string{fmt.Sprintf("%02d", i-1)}
+		}
+		mustCreate(t, service, task)
+	}
+
+	first := mustGet(t, service, "01")
+	first.BlockedBy = []string{"03"}
+	err := service.Update(context.Background(), first)
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Update() error = %v, want a DependencyCycleError", err)
+	}
+	if want := []string{"01", "03", "02", "01"}; !reflect.DeepEqual(cycle.Path, want) {
+		t.Errorf("cycle path = %v, want %v", cycle.Path, want)
+	}
+	if !errors.Is(err, example5.ErrDependencyCycle) || !errors.Is(err, example5.ErrInvalidInput) {
+		t.Errorf("Update() error = %v, want it to match ErrDependencyCycle and ErrInvalidInput", err)
+	}
+	if got := mustGet(t, service, "01"); got.BlockedBy != nil {
+		t.Errorf("rejected update stored BlockedBy = %v", got.BlockedBy)
+	}
+}
+
+// testDeleteDropsDependency checks that deleting a task unblocks the tasks
+// waiting on it, as updates that are logged and delivered to watchers
+func testDeleteDropsDependency(t *testing.T, service example5.TaskService, log *example5.MemoryAuditLog) {
+	mustCreate(t, service, newTask(1))
+	mustCreate(t, service, newTask(2))
+	task := newTask(3)
+	task.BlockedBy = []string{"01", "02"}
+	mustCreate(t, service, task)
+	task = newTask(4)
+	task.BlockedBy = []string{"01"}
+	mustCreate(t, service, task)
+	created := mustGet(t, service, "03")
+
+	events := mustWatch(t, service, example5.WatchFilter{})
+	ctx := example5.WithRequestID(example5.WithActor(context.Background(), "bob"), "req-9")
+	if err := service.Delete(ctx, "01"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	got := mustGet(t, service, "03")
+	if !reflect.DeepEqual(got.BlockedBy, []string{"02"}) {
+		t.Errorf("BlockedBy after delete = %v, want [02]", got.BlockedBy)
+	}
+	if !got.UpdatedAt.After(created.UpdatedAt) || got.UpdatedBy != "bob" || got.RequestID != "req-9" {
+		t.Errorf("unblocked task updated at %v by %q in %q, want after %v by bob in req-9",
+			got.UpdatedAt, got.UpdatedBy, got.RequestID, created.UpdatedAt)
+	}
+	if got := mustGet(t, service, "04"); got.BlockedBy != nil {
+		t.Errorf("BlockedBy after deleting the only blocker = %v, want none", got.BlockedBy)
+	}
+	if got := mustGet(t, service, "02"); got.UpdatedBy != "" {
+		t.Errorf("task 02 was updated by %q, want it untouched", got.UpdatedBy)
+	}
+
+	// The delete comes first, then an update per unblocked task in ID order
+	want := []struct {
+		kind   example5.ChangeKind
+		taskID string
+		after  []string
+	}{
+		{example5.ChangeDeleted, "01", nil},
+		{example5.ChangeUpdated, "03", []string{"02"}},
+		{example5.ChangeUpdated, "04", nil},
+	}
+	logged := log.Events(example5.WatchFilter{})
+	if len(logged) < len(want) {
+		t.Fatalf("audit log has %d events, want at least %d", len(logged), len(want))
+	}
+	logged = logged[len(logged)-len(want):]
+	for i, w := range want {
+		delivered := receive(t, events)
+		for _, e := range []example5.ChangeEvent{delivered, logged[i]} {
+			if e.Kind != w.kind || e.TaskID != w.taskID || e.Actor != "bob" || e.RequestID != "req-9" {
+				t.Errorf("event %d = %s of %s by %q in %q, want %s of %s by bob in req-9", i, e.Kind, e.TaskID, e.Actor, e.RequestID, w.kind, w.taskID)
+				continue
+			}
+			if w.kind != example5.ChangeUpdated {
+				continue
+			}
+			if len(e.Diff) != 1 || e.Diff[0].Field != "blocked_by" || !reflect.DeepEqual(e.After.BlockedBy, w.after) ||
+				!e.At.Equal(e.After.UpdatedAt) || !e.At.Equal(got.UpdatedAt) {
+				t.Errorf("event %d diff %+v at %v, want blocked_by changing to %v at %v", i, e.Diff, e.At, w.after, got.UpdatedAt)
+			}
+		}
+	}
+
+	// A new task may reuse the ID without inheriting the dependency
+	mustCreate(t, service, newTask(1))
+	if got := mustGet(t, service, "03"); !reflect.DeepEqual(got.BlockedBy, []string{"02"}) {
+		t.Errorf("BlockedBy after reuse = %v, want [02]", got.BlockedBy)
+	}
+}
+
+func testNext(t *testing.T, service example5.TaskService) {
+	soon, later := at(60), at(120)
+	tasks := []struct {
+		priority  example5.Priority
+		due       *time.Time
+		blockedBy []string
+	}{
+		{example5.PriorityLow, nil, nil},                     // 01
+		{example5.PriorityUrgent, nil, []string{"01"}},       // 02 waits on 01
+		{example5.PriorityMedium, &later, nil},               // 03
+		{example5.PriorityHigh, nil, nil},                    // 04 is marked blocked
+		{example5.PriorityUrgent, nil, nil},                  // 05 is completed
+		{example5.PriorityMedium, &soon, nil},                // 06 is due before 03
+		{example5.PriorityUrgent, nil, []string{"02", "05"}}, // 07 waits on 02
+	}
+	for i, spec := range tasks {
+		task := newTask(i + 1)
+		task.Priority = spec.priority
+		task.Due = spec.due
+		task.BlockedBy = spec.blockedBy
+		mustCreate(t, service, task)
+	}
+	for id, status := range map[string]example5.Status{"04": example5.StatusBlocked, "05": example5.StatusCompleted} {
+		if _, err := service.Transition(context.Background(), id, status); err != nil {
+			t.Fatalf("Transition(%s) error = %v", id, err)
+		}
+	}
+
+	next, err := service.Next(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if want := []string{"06", "03", "01", "02", "07", "04"}; !reflect.DeepEqual(ids(next), want) {
+		t.Errorf("Next() = %v, want %v", ids(next), want)
+	}
+
+	next, err = service.Next(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Next(2) error = %v", err)
+	}
+	if want := []string{"06", "03"}; !reflect.DeepEqual(ids(next), want) {
+		t.Errorf("Next(2) = %v, want %v", ids(next), want)
+	}
+
+	if _, err := service.Next(context.Background(), -1); !errors.Is(err, example5.ErrInvalidInput) {
+		t.Errorf("Next(-1) error = %v, want %v", err, example5.ErrInvalidInput)
+	}
+}
+
+// createDocs creates one task per title and description pair, with IDs
+// counting from 01
+func createDocs(t *testing.T, service example5.TaskService, docs ...[2]string) {
+	t.Helper()
+	for i, doc := range docs {
+		task := newTask(i + 1)
+		task.Title, task.Description = doc[0], doc[1]
+		mustCreate(t, service, task)
+	}
+}
+
+// searchIDs returns the IDs Search finds, best first
+func searchIDs(t *testing.T, service example5.TaskService, query string, limit int) []string {
+	t.Helper()
+	results, err := service.Search(context.Background(), query, limit)
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", query, err)
+	}
+	found := make([]string, len(results))
+	for i, result := range results {
+		found[i] = result.Task.ID
+		if result.Score <= 0 {
+			t.Errorf("Search(%q) scored %s %v, want a positive score", query, result.Task.ID, result.Score)
+		}
+	}
+	return found
+}
+
+func testSearch(t *testing.T, service example5.TaskService) {
+	createDocs(t, service,
+		[2]string{"Write API docs", "Document the REST endpoints"},    // 01
+		[2]string{"Fix login bug", "Users cannot log in with e-mail"}, // 02
+		[2]string{"Review docs", "Read the API documentation"},        // 03
+		[2]string{"Deploy", "Ship the release to production"},         // 04
+	)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"deploy", []string{"04"}},
+		{"DOCS", []string{"01", "03"}},
+		{"api docs", []string{"01", "03"}},
+		{"docs login", []string{}},
+		{"doc*", []string{"01", "03"}},
+		{"log*", []string{"02"}},
+		{`"api docs"`, []string{"01"}},
+		{`"docs api"`, []string{}},
+		{`"REST endpoints" write`, []string{"01"}},
+		{"e-mail", []string{"02"}},
+		{"mail", []string{"02"}},
+		{`"docs document"`, []string{}},
+		{"missing", []string{}},
+	}
+	for _, tt := range tests {
+		got := searchIDs(t, service, tt.query, 0)
+		slices.Sort(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	results, err := service.Search(context.Background(), "production", 0)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search(production) = %v, %v", results, err)
+	}
+	assertEqual(t, results[0].Task, mustGet(t, service, "04"))
+}
+
+func testSearchRanking(t *testing.T, service example5.TaskService) {
+	createDocs(t, service,
+		[2]string{"Plan sprint", "Talk about the database later"},            // 01
+		[2]string{"Database migration", "Move the database to the new host"}, // 02
+		[2]string{"Tune database", "Indexes"},                                // 03
+		[2]string{"Cache", "Put a cache in front of the database"},           // 04
+		[2]string{"Unrelated", "Nothing here"},                               // 05
+	)
+
+	// Title matches count double and shorter tasks rank higher: 03 has the
+	// word once in a short title, 02 twice but in a longer task,
+	// and 01 and 04 only in their descriptions, 01 being shorter
+	if got, want := searchIDs(t, service, "database", 0), []string{"03", "02", "01", "04"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(database) = %v, want %v", got, want)
+	}
+	if got, want := searchIDs(t, service, "database cache", 0), []string{"04"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(database cache) = %v, want %v", got, want)
+	}
+	if got, want := searchIDs(t, service, "database", 2), []string{"03", "02"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(database, 2) = %v, want %v", got, want)
+	}
+}
+
+func testSearchFollowsChanges(t *testing.T, service example5.TaskService) {
+	createDocs(t, service, [2]string{"Draft proposal", "First version"}, [2]string{"Proposal review", ""})
+
+	update := mustGet(t, service, "01")
+	update.Title = "Final report"
+	if err := service.Update(context.Background(), update); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if got := searchIDs(t, service, "proposal", 0); !reflect.DeepEqual(got, []string{"02"}) {
+		t.Errorf("after retitling, Search(proposal) = %v, want [02]", got)
+	}
+	if got := searchIDs(t, service, "report", 0); !reflect.DeepEqual(got, []string{"01"}) {
+		t.Errorf("after retitling, Search(report) = %v, want [01]", got)
+	}
+
+	// Transitions keep the task findable and return its new status
+	if _, err := service.Transition(context.Background(), "01", example5.StatusCompleted); err != nil {
+		t.Fatalf("Transition() error = %v", err)
+	}
+	results, err := service.Search(context.Background(), "report", 0)
+	if err != nil || len(results) != 1 || results[0].Task.Status != example5.StatusCompleted {
+		t.Errorf("after Transition, Search(report) = %v, %v", results, err)
+	}
+
+	if err := service.Delete(context.Background(), "02"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if got := searchIDs(t, service, "proposal", 0); len(got) != 0 {
+		t.Errorf("after Delete, Search(proposal) = %v, want nothing", got)
+	}
+}
+
+func testSearchInvalid(t *testing.T, service example5.TaskService) {
+	mustCreate(t, service, newTask(1))
+	for _, query := range []string{"", "   ", "*", `"unterminated phrase`, "-- !!"} {
+		if _, err := service.Search(context.Background(), query, 0); !errors.Is(err, example5.ErrInvalidInput) {
+			t.Errorf("Search(%q) error = %v, want %v", query, err, example5.ErrInvalidInput)
+		}
+	}
+	if _, err := service.Search(context.Background(), "task", -1); !errors.Is(err, example5.ErrInvalidInput) {
+		t.Errorf("Search with limit -1 error = %v, want %v", err, example5.ErrInvalidInput)
+	}
+}
+
+func testSearchConcurrentWrites(t *testing.T, service example5.TaskService) {
+	const writers, perWriter = 4, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter*2)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				task := newTask(w*perWriter + i)
+				task.Title = fmt.Sprintf("Needle %d", w)
+				if err := service.Create(context.Background(), task); err != nil {
+					errs <- err
+				}
+				if i%2 == 1 {
+					if err := service.Delete(context.Background(), task.ID); err != nil {
+						errs <- err
+					}
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < perWriter; i++ {
+			results, err := service.Search(context.Background(), "needle", 0)
+			if err != nil {
+				errs <- err
+				continue
+			}
+			for _, result := range results {
+				if result.Task == nil || !strings.HasPrefix(result.Task.Title, "Needle") {
+					errs <- fmt.Errorf("search returned %+v", result)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got := searchIDs(t, service, "needle", 0); len(got) != writers*perWriter/2 {
+		t.Errorf("after the writes Search(needle) found %d tasks, want %d", len(got), writers*perWriter/2)
+	}
+}
@@ -1,12 +1,17 @@
+//go:generate go run practice/examples/mock/mockgen -out mocks/mocks.go
+
 package example6
 
 import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"runtime"
 	"sync"
 	"time"
+
+	"practice/examples/demo"
 )
 
 // Common errors
@@ -188,7 +193,8 @@ func (p *processor) ProcessConcurrent(ctx context.Context, data []string, worker
 }
 
 // Run demonstrates the data processor with various operations
-func Run() error {
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
 	processor := NewProcessor()
 
 	// Test data
@@ -231,11 +237,11 @@ func Run() error {
 
 	// Run test cases
 	for _, tc := range testCases {
-		fmt.Printf("\nTesting: %s\n", tc.name)
+		fmt.Fprintf(env, "\nTesting: %s\n", tc.name)
 
-		start := time.Now()
+		start := env.Now()
 		result, err := tc.process()
-		duration := time.Since(start)
+		duration := env.Since(start)
 
 		if err != nil {
 			return fmt.Errorf("unexpected error: %w", err)
@@ -245,9 +251,9 @@ func Run() error {
 			return fmt.Errorf("expected %d results, got %d", tc.expected, len(result))
 		}
 
-		fmt.Printf("Processed %d items in %v\n", len(result), duration)
+		fmt.Fprintf(env, "Processed %d items in %v\n", len(result), duration)
 		for i, item := range result {
-			fmt.Printf("  %d: %s\n", i+1, item)
+			fmt.Fprintf(env, "  %d: %s\n", i+1, item)
 		}
 	}
 
@@ -255,7 +261,7 @@ func Run() error {
 }
 
 // RunBenchmark demonstrates benchmarking different processing methods
-func RunBenchmark() error {
+func RunBenchmark(w io.Writer) error {
 	processor := NewProcessor()
 
 	// Generate test data
@@ -291,7 +297,7 @@ func RunBenchmark() error {
 
 	// Run benchmarks
 	for _, bc := range benchmarkCases {
-		fmt.Printf("\nBenchmarking: %s\n", bc.name)
+		fmt.Fprintf(w, "\nBenchmarking: %s\n", bc.name)
 
 		// Warm up
 		for i := 0; i < 3; i++ {
@@ -310,9 +316,9 @@ func RunBenchmark() error {
 		}
 		duration := time.Since(start)
 
-		fmt.Printf("Processed %d items %d times in %v\n", len(data), iterations, duration)
-		fmt.Printf("Average time per iteration: %v\n", duration/time.Duration(iterations))
-		fmt.Printf("Average time per item: %v\n", duration/time.Duration(iterations*len(data)))
+		fmt.Fprintf(w, "Processed %d items %d times in %v\n", len(data), iterations, duration)
+		fmt.Fprintf(w, "Average time per iteration: %v\n", duration/time.Duration(iterations))
+		fmt.Fprintf(w, "Average time per item: %v\n", duration/time.Duration(iterations*len(data)))
 	}
 
 	return nil
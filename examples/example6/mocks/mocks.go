@@ -0,0 +1,270 @@
+// Code generated by mockgen from practice/examples/example6. DO NOT EDIT.
+
+// Package mocks holds test doubles for the interfaces of practice/examples/example6.
+package mocks
+
+import (
+	"context"
+
+	"practice/examples/example6"
+	"practice/examples/mock"
+)
+
+// DataProcessor is a test double for example6.DataProcessor
+type DataProcessor struct {
+	recorder mock.Recorder
+
+	// ProcessFunc, if set, handles Process calls that match no expectation
+	ProcessFunc func(ctx context.Context, data []string) ([]string, error)
+
+	// ProcessBatchFunc, if set, handles ProcessBatch calls that match no expectation
+	ProcessBatchFunc func(ctx context.Context, data []string, batchSize int) ([]string, error)
+
+	// ProcessConcurrentFunc, if set, handles ProcessConcurrent calls that match no expectation
+	ProcessConcurrentFunc func(ctx context.Context, data []string, workers int) ([]string, error)
+}
+
+var _ example6.DataProcessor = (*DataProcessor)(nil)
+
+// NewDataProcessor returns a strict double: calls that match no expectation and
+// have no fake fail t, and unmet expectations fail t when the test ends
+func NewDataProcessor(t mock.TB) *DataProcessor {
+	m := &DataProcessor{}
+	m.recorder.T = t
+	t.Cleanup(func() { m.recorder.Verify(t) })
+	return m
+}
+
+// Verify reports expectations that were not met
+func (m *DataProcessor) Verify(t mock.TB) {
+	t.Helper()
+	m.recorder.Verify(t)
+}
+
+// Process implements example6.DataProcessor
+func (m *DataProcessor) Process(ctx context.Context, data []string) ([]string, error) {
+	if results, ok := m.recorder.Called("Process", ctx, data); ok {
+		r0, _ := results[0].([]string)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ProcessFunc != nil {
+		return m.ProcessFunc(ctx, data)
+	}
+	m.recorder.Unexpected("Process", ctx, data)
+	var r0 []string
+	var r1 error
+	return r0, r1
+}
+
+// DataProcessorProcessCall is an expectation on DataProcessor.Process
+type DataProcessorProcessCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcess expects a call to Process whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectProcess(ctx, data interface{}) DataProcessorProcessCall {
+	return DataProcessorProcessCall{m.recorder.Expect("Process", 2, ctx, data)}
+}
+
+// Return sets the values Process returns
+func (c DataProcessorProcessCall) Return(r0 []string, r1 error) DataProcessorProcessCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values Process returns from its arguments
+func (c DataProcessorProcessCall) Do(fn func(ctx context.Context, data []string) ([]string, error)) DataProcessorProcessCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		data, _ := args[1].([]string)
+		r0, r1 := fn(ctx, data)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorProcessCall) Times(n int) DataProcessorProcessCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorProcessCall) AnyTimes() DataProcessorProcessCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorProcessArgs holds the arguments of one Process call
+type DataProcessorProcessArgs struct {
+	Ctx  context.Context
+	Data []string
+}
+
+// ProcessCalls returns the arguments of every Process call in order
+func (m *DataProcessor) ProcessCalls() []DataProcessorProcessArgs {
+	var out []DataProcessorProcessArgs
+	for _, call := range m.recorder.Calls("Process") {
+		var args DataProcessorProcessArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Data, _ = call.Args[1].([]string)
+		out = append(out, args)
+	}
+	return out
+}
+
+// ProcessBatch implements example6.DataProcessor
+func (m *DataProcessor) ProcessBatch(ctx context.Context, data []string, batchSize int) ([]string, error) {
+	if results, ok := m.recorder.Called("ProcessBatch", ctx, data, batchSize); ok {
+		r0, _ := results[0].([]string)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ProcessBatchFunc != nil {
+		return m.ProcessBatchFunc(ctx, data, batchSize)
+	}
+	m.recorder.Unexpected("ProcessBatch", ctx, data, batchSize)
+	var r0 []string
+	var r1 error
+	return r0, r1
+}
+
+// DataProcessorProcessBatchCall is an expectation on DataProcessor.ProcessBatch
+type DataProcessorProcessBatchCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcessBatch expects a call to ProcessBatch whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectProcessBatch(ctx, data, batchSize interface{}) DataProcessorProcessBatchCall {
+	return DataProcessorProcessBatchCall{m.recorder.Expect("ProcessBatch", 2, ctx, data, batchSize)}
+}
+
+// Return sets the values ProcessBatch returns
+func (c DataProcessorProcessBatchCall) Return(r0 []string, r1 error) DataProcessorProcessBatchCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values ProcessBatch returns from its arguments
+func (c DataProcessorProcessBatchCall) Do(fn func(ctx context.Context, data []string, batchSize int) ([]string, error)) DataProcessorProcessBatchCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		data, _ := args[1].([]string)
+		batchSize, _ := args[2].(int)
+		r0, r1 := fn(ctx, data, batchSize)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorProcessBatchCall) Times(n int) DataProcessorProcessBatchCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorProcessBatchCall) AnyTimes() DataProcessorProcessBatchCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorProcessBatchArgs holds the arguments of one ProcessBatch call
+type DataProcessorProcessBatchArgs struct {
+	Ctx       context.Context
+	Data      []string
+	BatchSize int
+}
+
+// ProcessBatchCalls returns the arguments of every ProcessBatch call in order
+func (m *DataProcessor) ProcessBatchCalls() []DataProcessorProcessBatchArgs {
+	var out []DataProcessorProcessBatchArgs
+	for _, call := range m.recorder.Calls("ProcessBatch") {
+		var args DataProcessorProcessBatchArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Data, _ = call.Args[1].([]string)
+		args.BatchSize, _ = call.Args[2].(int)
+		out = append(out, args)
+	}
+	return out
+}
+
+// ProcessConcurrent implements example6.DataProcessor
+func (m *DataProcessor) ProcessConcurrent(ctx context.Context, data []string, workers int) ([]string, error) {
+	if results, ok := m.recorder.Called("ProcessConcurrent", ctx, data, workers); ok {
+		r0, _ := results[0].([]string)
+		r1, _ := results[1].(error)
+		return r0, r1
+	}
+	if m.ProcessConcurrentFunc != nil {
+		return m.ProcessConcurrentFunc(ctx, data, workers)
+	}
+	m.recorder.Unexpected("ProcessConcurrent", ctx, data, workers)
+	var r0 []string
+	var r1 error
+	return r0, r1
+}
+
+// DataProcessorProcessConcurrentCall is an expectation on DataProcessor.ProcessConcurrent
+type DataProcessorProcessConcurrentCall struct {
+	e *mock.Expectation
+}
+
+// ExpectProcessConcurrent expects a call to ProcessConcurrent whose arguments match the given
+// matchers or equal the given values
+func (m *DataProcessor) ExpectProcessConcurrent(ctx, data, workers interface{}) DataProcessorProcessConcurrentCall {
+	return DataProcessorProcessConcurrentCall{m.recorder.Expect("ProcessConcurrent", 2, ctx, data, workers)}
+}
+
+// Return sets the values ProcessConcurrent returns
+func (c DataProcessorProcessConcurrentCall) Return(r0 []string, r1 error) DataProcessorProcessConcurrentCall {
+	c.e.Return(r0, r1)
+	return c
+}
+
+// Do computes the values ProcessConcurrent returns from its arguments
+func (c DataProcessorProcessConcurrentCall) Do(fn func(ctx context.Context, data []string, workers int) ([]string, error)) DataProcessorProcessConcurrentCall {
+	c.e.Do(func(args []interface{}) []interface{} {
+		ctx, _ := args[0].(context.Context)
+		data, _ := args[1].([]string)
+		workers, _ := args[2].(int)
+		r0, r1 := fn(ctx, data, workers)
+		return []interface{}{r0, r1}
+	})
+	return c
+}
+
+// Times requires exactly n matching calls
+func (c DataProcessorProcessConcurrentCall) Times(n int) DataProcessorProcessConcurrentCall {
+	c.e.Times(n)
+	return c
+}
+
+// AnyTimes allows any number of matching calls
+func (c DataProcessorProcessConcurrentCall) AnyTimes() DataProcessorProcessConcurrentCall {
+	c.e.AnyTimes()
+	return c
+}
+
+// DataProcessorProcessConcurrentArgs holds the arguments of one ProcessConcurrent call
+type DataProcessorProcessConcurrentArgs struct {
+	Ctx     context.Context
+	Data    []string
+	Workers int
+}
+
+// ProcessConcurrentCalls returns the arguments of every ProcessConcurrent call in order
+func (m *DataProcessor) ProcessConcurrentCalls() []DataProcessorProcessConcurrentArgs {
+	var out []DataProcessorProcessConcurrentArgs
+	for _, call := range m.recorder.Calls("ProcessConcurrent") {
+		var args DataProcessorProcessConcurrentArgs
+		args.Ctx, _ = call.Args[0].(context.Context)
+		args.Data, _ = call.Args[1].([]string)
+		args.Workers, _ = call.Args[2].(int)
+		out = append(out, args)
+	}
+	return out
+}
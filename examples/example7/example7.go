@@ -2,6 +2,7 @@ package example7
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+
+	"practice/examples/demo"
 )
 
 // DependencyManager demonstrates various dependency management operations
@@ -95,8 +98,23 @@ func (dm *DependencyManager) WhyDependency(dependency string) (string, error) {
 	return string(output), err
 }
 
+// exampleProgram uses the dependencies Run adds
+const exampleProgram = `package main
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	logrus.Info(uuid.NewString())
+}
+`
+
 // Run demonstrates dependency management operations
-func Run() error {
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+
 	// Create a temporary directory for our example
 	tempDir, err := os.MkdirTemp("", "go-deps-example-*")
 	if err != nil {
@@ -106,13 +124,23 @@ func Run() error {
 
 	// Initialize dependency manager
 	dm := NewDependencyManager(tempDir)
+	dm.logger.SetOutput(env)
+	if env.Deterministic {
+		dm.logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	}
 
 	// Initialize a new module
-	moduleName := fmt.Sprintf("example.com/%s", uuid.New().String())
+	moduleName := fmt.Sprintf("example.com/%s", env.NewID())
 	if err := dm.InitializeModule(moduleName); err != nil {
 		return fmt.Errorf("failed to initialize module: %w", err)
 	}
 
+	// Import the dependencies, otherwise tidy removes them again and there
+	// is nothing to vendor
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte(exampleProgram), 0o644); err != nil {
+		return fmt.Errorf("failed to write example program: %w", err)
+	}
+
 	// Add some dependencies
 	dependencies := []struct {
 		name    string
@@ -138,16 +166,16 @@ func Run() error {
 	if err != nil {
 		return fmt.Errorf("failed to list dependencies: %w", err)
 	}
-	fmt.Println("Current dependencies:")
-	fmt.Println(deps)
+	fmt.Fprintln(env, "Current dependencies:")
+	fmt.Fprintln(env, deps)
 
 	// Explain why we need logrus
 	why, err := dm.WhyDependency("github.com/sirupsen/logrus")
 	if err != nil {
 		return fmt.Errorf("failed to explain dependency: %w", err)
 	}
-	fmt.Println("\nWhy we need logrus:")
-	fmt.Println(why)
+	fmt.Fprintln(env, "\nWhy we need logrus:")
+	fmt.Fprintln(env, why)
 
 	// Create vendor directory
 	if err := dm.VendorDependencies(); err != nil {
@@ -160,12 +188,12 @@ func Run() error {
 		return fmt.Errorf("vendor directory not created: %w", err)
 	}
 
-	fmt.Println("\nDependency management operations completed successfully!")
+	fmt.Fprintln(env, "\nDependency management operations completed successfully!")
 	return nil
 }
 
 // RunBenchmark demonstrates dependency management performance
-func RunBenchmark() error {
+func RunBenchmark(w io.Writer) error {
 	// Create a temporary directory for benchmarking
 	tempDir, err := os.MkdirTemp("", "go-deps-benchmark-*")
 	if err != nil {
@@ -174,6 +202,7 @@ func RunBenchmark() error {
 	defer os.RemoveAll(tempDir)
 
 	dm := NewDependencyManager(tempDir)
+	dm.logger.SetOutput(w)
 
 	// Initialize module
 	moduleName := fmt.Sprintf("example.com/%s", uuid.New().String())
@@ -199,7 +228,7 @@ func RunBenchmark() error {
 
 	iterations := 5
 	for _, op := range operations {
-		fmt.Printf("\nBenchmarking: %s\n", op.name)
+		fmt.Fprintf(w, "\nBenchmarking: %s\n", op.name)
 
 		// Warm up
 		for i := 0; i < 2; i++ {
@@ -217,7 +246,7 @@ func RunBenchmark() error {
 		}
 		duration := time.Since(start)
 
-		fmt.Printf("Average time per operation: %v\n", duration/time.Duration(iterations))
+		fmt.Fprintf(w, "Average time per operation: %v\n", duration/time.Duration(iterations))
 	}
 
 	return nil
@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
 	"sync"
 	"time"
+
+	"practice/examples/demo"
 )
 
 // Custom error for demonstration
@@ -41,9 +45,9 @@ func handlerWithContext(w http.ResponseWriter, r *http.Request) {
 }
 
 // middleware demonstrates HTTP middleware pattern
-func middleware(next http.Handler) http.Handler {
+func middleware(logger *log.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Request: %s %s", r.Method, r.URL.Path)
+		logger.Printf("Request: %s %s", r.Method, r.URL.Path)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -68,38 +72,52 @@ func concurrentCounter(n int) int {
 }
 
 // Run demonstrates advanced standard library usage
-func Run() error {
-	fmt.Println("--- Go Standard Library Example ---")
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	fmt.Fprintln(env, "--- Go Standard Library Example ---")
+
+	flags := log.LstdFlags
+	if env.Deterministic {
+		flags = 0
+	}
+	logger := log.New(env, "", flags)
 
 	// 1. Advanced net/http server setup
 	mux := http.NewServeMux()
-	mux.Handle("/user", middleware(http.HandlerFunc(handlerWithContext)))
+	mux.Handle("/user", middleware(logger, http.HandlerFunc(handlerWithContext)))
 
 	srv := &http.Server{
 		Addr:         ":8081",
 		Handler:      mux,
 		ReadTimeout:  2 * time.Second,
 		WriteTimeout: 2 * time.Second,
+		ErrorLog:     logger,
 	}
 
-	// Start server in a goroutine
+	// Listen before serving so the request below cannot race the startup
+	ln, err := env.Listen("tcp", srv.Addr)
+	if err != nil {
+		return fmt.Errorf("listen failed: %w", err)
+	}
+	fmt.Fprintf(env, "Starting HTTP server on %s (GET /user)...\n", srv.Addr)
+
+	// Serve in a goroutine
 	done := make(chan struct{})
 	go func() {
-		fmt.Println("Starting HTTP server on :8081 (GET /user)...")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("Server error: %v", err)
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			logger.Printf("Server error: %v", err)
 		}
 		close(done)
 	}()
 
-	// Give the server a moment to start
-	time.Sleep(100 * time.Millisecond)
-
 	// 2. Make a request with context and handle JSON
 	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", "http://localhost:8081/user", nil)
+	// The listener may not be on srv.Addr's port when Listen is replaced
+	port := ln.Addr().(*net.TCPAddr).Port
+	url := fmt.Sprintf("http://localhost:%d/user", port)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return fmt.Errorf("request creation failed: %w", err)
 	}
@@ -113,32 +131,32 @@ func Run() error {
 	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
 		return fmt.Errorf("json decode failed: %w", err)
 	}
-	fmt.Printf("Received user: %+v\n", user)
+	fmt.Fprintf(env, "Received user: %+v\n", user)
 
 	// 3. Demonstrate sync usage
 	count := concurrentCounter(100)
-	fmt.Printf("Concurrent counter result: %d\n", count)
+	fmt.Fprintf(env, "Concurrent counter result: %d\n", count)
 
 	// 4. Graceful shutdown
 	if err := srv.Shutdown(context.Background()); err != nil {
 		return fmt.Errorf("server shutdown failed: %w", err)
 	}
 	<-done
-	fmt.Println("Server gracefully stopped.")
+	fmt.Fprintln(env, "Server gracefully stopped.")
 
 	return nil
 }
 
 // RunBenchmark demonstrates benchmarking standard library features
-func RunBenchmark() error {
-	fmt.Println("--- Go Standard Library Benchmark ---")
+func RunBenchmark(w io.Writer) error {
+	fmt.Fprintln(w, "--- Go Standard Library Benchmark ---")
 	start := time.Now()
 	iters := 1000
 	for i := 0; i < iters; i++ {
 		_ = concurrentCounter(100)
 	}
 	duration := time.Since(start)
-	fmt.Printf("Ran concurrentCounter 1000 times in %v\n", duration)
-	fmt.Printf("Average per run: %v\n", duration/time.Duration(iters))
+	fmt.Fprintf(w, "Ran concurrentCounter 1000 times in %v\n", duration)
+	fmt.Fprintf(w, "Average per run: %v\n", duration/time.Duration(iters))
 	return nil
 }
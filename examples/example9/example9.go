@@ -2,56 +2,74 @@ package example9
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"time"
+
+	"practice/examples/demo"
 )
 
+// packagePath is built by import path so the examples work from any
+// directory inside the module
+const packagePath = "practice/examples/example9"
+
 // Run demonstrates Go's tooling ecosystem
-func Run() error {
-	fmt.Println("--- Go Tooling Ecosystem Example ---")
+func Run(w io.Writer, opts ...demo.Option) error {
+	env := demo.New(w, opts...)
+	fmt.Fprintln(env, "--- Go Tooling Ecosystem Example ---")
+
+	outDir, err := os.MkdirTemp("", "example9-")
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// Clean up
+	defer os.RemoveAll(outDir)
 
 	// 1. Advanced build options
-	fmt.Println("Building with advanced options...")
-	cmd := exec.Command("go", "build", "-ldflags=-s -w", "-tags=prod", "-o", "example9_binary", ".")
-	cmd.Dir = "examples/example9"
+	fmt.Fprintln(env, "Building with advanced options...")
+	cmd := exec.Command("go", "build", "-ldflags=-s -w", "-tags=prod", "-o", filepath.Join(outDir, "example9_binary"), packagePath)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("build failed: %w", err)
 	}
-	fmt.Println("Build completed successfully.")
+	fmt.Fprintln(env, "Build completed successfully.")
 
 	// 2. Code generation (simulated)
-	fmt.Println("Simulating code generation...")
+	fmt.Fprintln(env, "Simulating code generation...")
 	// In a real scenario, you might run 'go generate' here
-	time.Sleep(500 * time.Millisecond)
-	fmt.Println("Code generation simulated.")
+	env.Sleep(500 * time.Millisecond)
+	fmt.Fprintln(env, "Code generation simulated.")
 
 	// 3. Static analysis (simulated)
-	fmt.Println("Running static analysis...")
+	fmt.Fprintln(env, "Running static analysis...")
 	// In a real scenario, you might run 'go vet' or 'golangci-lint' here
-	time.Sleep(500 * time.Millisecond)
-	fmt.Println("Static analysis completed.")
+	env.Sleep(500 * time.Millisecond)
+	fmt.Fprintln(env, "Static analysis completed.")
 
-	// Clean up
-	os.Remove("examples/example9/example9_binary")
 	return nil
 }
 
 // RunBenchmark demonstrates benchmarking tooling operations
-func RunBenchmark() error {
-	fmt.Println("--- Go Tooling Benchmark ---")
+func RunBenchmark(w io.Writer) error {
+	fmt.Fprintln(w, "--- Go Tooling Benchmark ---")
+
+	outDir, err := os.MkdirTemp("", "example9-")
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+
 	start := time.Now()
 	iters := 5
 	for i := 0; i < iters; i++ {
-		cmd := exec.Command("go", "build", "-o", "example9_binary", ".")
-		cmd.Dir = "examples/example9"
+		cmd := exec.Command("go", "build", "-o", filepath.Join(outDir, "example9_binary"), packagePath)
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("benchmark build failed: %w", err)
 		}
-		os.Remove("examples/example9/example9_binary")
 	}
 	duration := time.Since(start)
-	fmt.Printf("Ran build %d times in %v\n", iters, duration)
-	fmt.Printf("Average build time: %v\n", duration/time.Duration(iters))
+	fmt.Fprintf(w, "Ran build %d times in %v\n", iters, duration)
+	fmt.Fprintf(w, "Average build time: %v\n", duration/time.Duration(iters))
 	return nil
 }
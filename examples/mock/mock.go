@@ -0,0 +1,270 @@
+// Package mock is the runtime behind the test doubles generated by
+// mockgen. A generated double records every call (a spy), can delegate to
+// per-method functions (a fake), and can check calls against expectations
+// built from argument matchers (a mock).
+package mock
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TB is the part of testing.TB a double reports through
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// Matcher decides whether an argument is acceptable
+type Matcher interface {
+	Match(arg interface{}) bool
+	String() string
+}
+
+type anyMatcher struct{}
+
+func (anyMatcher) Match(interface{}) bool { return true }
+func (anyMatcher) String() string         { return "any" }
+
+// Any matches every argument
+func Any() Matcher {
+	return anyMatcher{}
+}
+
+type eqMatcher struct{ want interface{} }
+
+func (m eqMatcher) Match(arg interface{}) bool { return reflect.DeepEqual(arg, m.want) }
+func (m eqMatcher) String() string             { return fmt.Sprintf("%#v", m.want) }
+
+// Eq matches arguments deeply equal to want. Expectations wrap plain
+// values in Eq.
+func Eq(want interface{}) Matcher {
+	return eqMatcher{want}
+}
+
+type notMatcher struct{ m Matcher }
+
+func (n notMatcher) Match(arg interface{}) bool { return !n.m.Match(arg) }
+func (n notMatcher) String() string             { return "not(" + n.m.String() + ")" }
+
+// Not matches arguments m rejects
+func Not(m Matcher) Matcher {
+	return notMatcher{m}
+}
+
+type funcMatcher struct {
+	desc  string
+	match func(interface{}) bool
+}
+
+func (m funcMatcher) Match(arg interface{}) bool { return m.match(arg) }
+func (m funcMatcher) String() string             { return m.desc }
+
+// Match matches arguments of type T for which fn returns true; desc
+// describes the matcher in failure messages
+func Match[T any](desc string, fn func(T) bool) Matcher {
+	return funcMatcher{desc, func(arg interface{}) bool {
+		v, ok := arg.(T)
+		return ok && fn(v)
+	}}
+}
+
+// matcherFor wraps plain values in Eq
+func matcherFor(arg interface{}) Matcher {
+	if m, ok := arg.(Matcher); ok {
+		return m
+	}
+	return Eq(arg)
+}
+
+// Call is one recorded method call
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = fmt.Sprintf("%#v", arg)
+	}
+	return fmt.Sprintf("%s(%s)", c.Method, strings.Join(args, ", "))
+}
+
+// Expectation is an expected call. By default it must be matched exactly
+// once.
+type Expectation struct {
+	method  string
+	args    []Matcher
+	results []interface{}
+	do      func(args []interface{}) []interface{}
+
+	min, max int // max < 0 means unlimited
+	calls    int
+}
+
+// Return sets the values the call returns. It panics unless there is one
+// value per result of the method.
+func (e *Expectation) Return(results ...interface{}) *Expectation {
+	if len(results) != len(e.results) {
+		panic(fmt.Sprintf("mock: %s returns %d value(s), Return got %d", e, len(e.results), len(results)))
+	}
+	copy(e.results, results)
+	return e
+}
+
+// Do computes the returned values from the arguments instead of Return
+func (e *Expectation) Do(fn func(args []interface{}) []interface{}) *Expectation {
+	e.do = fn
+	return e
+}
+
+// Times requires exactly n matching calls
+func (e *Expectation) Times(n int) *Expectation {
+	e.min, e.max = n, n
+	return e
+}
+
+// AnyTimes allows any number of matching calls, including none
+func (e *Expectation) AnyTimes() *Expectation {
+	e.min, e.max = 0, -1
+	return e
+}
+
+func (e *Expectation) matches(method string, args []interface{}) bool {
+	if e.method != method || len(e.args) != len(args) {
+		return false
+	}
+	for i, m := range e.args {
+		if !m.Match(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) exhausted() bool {
+	return e.max >= 0 && e.calls >= e.max
+}
+
+func (e *Expectation) String() string {
+	args := make([]string, len(e.args))
+	for i, m := range e.args {
+		args[i] = m.String()
+	}
+	return fmt.Sprintf("%s(%s)", e.method, strings.Join(args, ", "))
+}
+
+// Recorder keeps the calls and expectations of one double. The zero value
+// is a lenient recorder; with T set, unexpected calls fail the test.
+type Recorder struct {
+	T TB
+
+	mu           sync.Mutex
+	calls        []Call
+	expectations []*Expectation
+}
+
+// Expect adds an expectation on method. results is the number of values
+// the method returns; args are matchers or plain values.
+func (r *Recorder) Expect(method string, results int, args ...interface{}) *Expectation {
+	e := &Expectation{
+		method:  method,
+		results: make([]interface{}, results),
+		min:     1,
+		max:     1,
+	}
+	for _, arg := range args {
+		e.args = append(e.args, matcherFor(arg))
+	}
+
+	r.mu.Lock()
+	r.expectations = append(r.expectations, e)
+	r.mu.Unlock()
+	return e
+}
+
+// Called records a call and returns the results of the first matching
+// expectation that is not used up. ok is false if none matches.
+func (r *Recorder) Called(method string, args ...interface{}) (results []interface{}, ok bool) {
+	r.mu.Lock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+
+	var match *Expectation
+	for _, e := range r.expectations {
+		if !e.exhausted() && e.matches(method, args) {
+			match = e
+			break
+		}
+	}
+	if match == nil {
+		r.mu.Unlock()
+		return nil, false
+	}
+	match.calls++
+	results, do := match.results, match.do
+	r.mu.Unlock()
+
+	// Run Do outside the lock so it may call the double again
+	if do != nil {
+		return do(args), true
+	}
+	return results, true
+}
+
+// Unexpected reports a call that matched no expectation and had no fake
+// to handle it. It only fails the test when T is set.
+func (r *Recorder) Unexpected(method string, args ...interface{}) {
+	if r.T == nil {
+		return
+	}
+	r.T.Helper()
+
+	call := Call{Method: method, Args: args}
+	r.mu.Lock()
+	var candidates []string
+	for _, e := range r.expectations {
+		if e.method == method {
+			candidates = append(candidates, e.String())
+		}
+	}
+	r.mu.Unlock()
+
+	if len(candidates) == 0 {
+		r.T.Errorf("mock: unexpected call %s", call)
+		return
+	}
+	r.T.Errorf("mock: unexpected call %s; expected one of:\n\t%s", call, strings.Join(candidates, "\n\t"))
+}
+
+// Calls returns the recorded calls of method, or of every method if
+// method is empty
+func (r *Recorder) Calls(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []Call
+	for _, c := range r.calls {
+		if method == "" || c.Method == method {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Verify reports every expectation that was called fewer times than
+// required
+func (r *Recorder) Verify(t TB) {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.expectations {
+		if e.calls < e.min {
+			t.Errorf("mock: expected %s to be called %d time(s), got %d", e, e.min, e.calls)
+		}
+	}
+}
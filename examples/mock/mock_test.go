@@ -0,0 +1,113 @@
+package mock
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeTB records failures instead of reporting them
+type fakeTB struct {
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		name    string
+		matcher Matcher
+		arg     interface{}
+		want    bool
+	}{
+		{"any", Any(), nil, true},
+		{"eq equal", Eq([]byte("a")), []byte("a"), true},
+		{"eq different", Eq(1), 2, false},
+		{"not", Not(Eq(1)), 2, true},
+		{"match", Match("even", func(n int) bool { return n%2 == 0 }), 4, true},
+		{"match wrong type", Match("even", func(n int) bool { return true }), "4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.matcher.Match(tt.arg); got != tt.want {
+				t.Errorf("%s.Match(%v) = %v, want %v", tt.matcher, tt.arg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderExpectations(t *testing.T) {
+	tb := &fakeTB{}
+	r := &Recorder{T: tb}
+
+	r.Expect("Get", 2, "a").Return(1, nil)
+	r.Expect("Get", 2, Any()).Return(0, fmt.Errorf("missing")).AnyTimes()
+	r.Expect("Put", 1, "a", Any()).Times(2)
+
+	if results, ok := r.Called("Get", "a"); !ok || results[0] != 1 {
+		t.Errorf("Called(Get, a) = %v, %v; want the first expectation", results, ok)
+	}
+	// The first expectation is used up, so the fallback matches
+	if results, ok := r.Called("Get", "a"); !ok || results[1] == nil {
+		t.Errorf("second Called(Get, a) = %v, %v; want the fallback", results, ok)
+	}
+	if _, ok := r.Called("Put", "a", 1); !ok {
+		t.Error("Called(Put) did not match")
+	}
+	if _, ok := r.Called("Delete", "a"); ok {
+		t.Error("Called(Delete) matched without an expectation")
+	}
+	r.Unexpected("Delete", "a")
+
+	r.Verify(tb)
+	if len(tb.errors) != 2 {
+		t.Fatalf("got %d failures, want 2: %q", len(tb.errors), tb.errors)
+	}
+	if !strings.Contains(tb.errors[0], `unexpected call Delete("a")`) {
+		t.Errorf("unexpected call failure = %q", tb.errors[0])
+	}
+	if !strings.Contains(tb.errors[1], `Put("a", any) to be called 2 time(s), got 1`) {
+		t.Errorf("verify failure = %q", tb.errors[1])
+	}
+
+	if calls := r.Calls("Get"); len(calls) != 2 {
+		t.Errorf("Calls(Get) returned %d calls, want 2", len(calls))
+	}
+	if calls := r.Calls(""); len(calls) != 4 {
+		t.Errorf("Calls(\"\") returned %d calls, want 4", len(calls))
+	}
+}
+
+func TestRecorderDo(t *testing.T) {
+	var r Recorder
+	r.Expect("Double", 1, Any()).AnyTimes().Do(func(args []interface{}) []interface{} {
+		return []interface{}{args[0].(int) * 2}
+	})
+
+	if results, _ := r.Called("Double", 21); results[0] != 42 {
+		t.Errorf("Do result = %v, want 42", results[0])
+	}
+}
+
+func TestReturnChecksResultCount(t *testing.T) {
+	for _, results := range [][]interface{}{{1}, {1, nil, "extra"}} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "Get(any) returns 2 value(s)") {
+					t.Errorf("Return(%v) panic = %v, want a result count mismatch", results, r)
+				}
+			}()
+			var r Recorder
+			r.Expect("Get", 2, Any()).Return(results...)
+		}()
+	}
+}
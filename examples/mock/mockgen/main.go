@@ -0,0 +1,528 @@
+// Command mockgen generates test doubles for the interfaces of a package.
+// Run it through go generate from the package's directory:
+//
+//	//go:generate go run practice/examples/mock/mockgen -out mocks/mocks.go
+//
+// Each exported interface I becomes a struct I in the output package with
+// a method per interface method M, plus:
+//
+//	NewI(t)        a strict double that fails t on unexpected calls and
+//	               verifies expectations when the test ends
+//	MFunc          an optional fake handling calls that match no expectation
+//	ExpectM(args)  an expectation with Return, Do, Times and AnyTimes
+//	MCalls()       the typed arguments of every recorded call
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const mockPath = "practice/examples/mock"
+
+func main() {
+	out := flag.String("out", "mocks/mocks.go", "output file")
+	pkgName := flag.String("pkg", "", "output package name (default: the output directory's name)")
+	names := flag.String("types", "", "comma-separated interfaces to mock (default: every exported interface)")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("mockgen: ")
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if *pkgName == "" {
+		abs, err := filepath.Abs(filepath.Dir(*out))
+		if err != nil {
+			log.Fatal(err)
+		}
+		*pkgName = filepath.Base(abs)
+	}
+
+	pkg, err := load(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ifaces, err := selectInterfaces(pkg, *names)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(pkg, *pkgName, ifaces)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// load type-checks the package in dir
+func load(dir string) (*types.Package, error) {
+	cmd := exec.Command("go", "list", "-json", ".")
+	cmd.Dir = dir
+	cmd.Stderr = os.Stderr
+	listing, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+	var info struct {
+		ImportPath string
+		Dir        string
+		GoFiles    []string
+	}
+	if err := json.Unmarshal(listing, &info); err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range info.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(info.Dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(info.ImportPath, fset, files, nil)
+}
+
+type namedInterface struct {
+	name  string
+	iface *types.Interface
+}
+
+// selectInterfaces returns the named interfaces, or every exported
+// non-generic interface, sorted by name
+func selectInterfaces(pkg *types.Package, names string) ([]namedInterface, error) {
+	var wanted []string
+	if names != "" {
+		wanted = strings.Split(names, ",")
+	} else {
+		for _, name := range pkg.Scope().Names() {
+			if token.IsExported(name) {
+				wanted = append(wanted, name)
+			}
+		}
+	}
+
+	var out []namedInterface
+	for _, name := range wanted {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			if names != "" {
+				return nil, fmt.Errorf("%s.%s is not a type", pkg.Path(), name)
+			}
+			continue
+		}
+		named, _ := obj.Type().(*types.Named)
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		if !ok || named == nil || named.TypeParams().Len() > 0 || !iface.IsMethodSet() {
+			if names != "" {
+				return nil, fmt.Errorf("%s.%s is not a non-generic method-set interface", pkg.Path(), name)
+			}
+			continue
+		}
+		out = append(out, namedInterface{name, iface})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no interfaces to mock in %s", pkg.Path())
+	}
+	return out, nil
+}
+
+// imports assigns package names to the packages the output refers to
+type imports struct {
+	names map[string]string // path -> name
+	used  map[string]bool
+}
+
+func newImports() *imports {
+	return &imports{names: make(map[string]string), used: make(map[string]bool)}
+}
+
+func (im *imports) add(path, name string) string {
+	if existing, ok := im.names[path]; ok {
+		return existing
+	}
+	unique := name
+	for i := 2; im.used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	im.names[path] = unique
+	im.used[unique] = true
+	return unique
+}
+
+func (im *imports) qualifier(p *types.Package) string {
+	return im.add(p.Path(), p.Name())
+}
+
+// write renders the import block, standard library first
+func (im *imports) write(buf *bytes.Buffer, local string) {
+	var std, own []string
+	for path := range im.names {
+		if strings.SplitN(path, "/", 2)[0] == local {
+			own = append(own, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(own)
+
+	buf.WriteString("import (\n")
+	for i, group := range [][]string{std, own} {
+		if i > 0 && len(std) > 0 && len(own) > 0 {
+			buf.WriteString("\n")
+		}
+		for _, path := range group {
+			name := im.names[path]
+			if name == filepath.Base(path) {
+				fmt.Fprintf(buf, "\t%q\n", path)
+			} else {
+				fmt.Fprintf(buf, "\t%s %q\n", name, path)
+			}
+		}
+	}
+	buf.WriteString(")\n\n")
+}
+
+// param is a parameter of a mocked method under the name the generated
+// code uses for it
+type param struct {
+	name, field, typ string
+	variadic         bool
+}
+
+// method is a mocked method with its types rendered for the output
+type method struct {
+	name    string
+	params  []param
+	results []string
+}
+
+// reserved are identifiers the generated method bodies declare
+var reserved = map[string]bool{
+	"m": true, "c": true, "fn": true, "args": true, "call": true,
+	"out": true, "results": true, "ok": true, "t": true,
+}
+
+func newMethod(fn *types.Func, im *imports) method {
+	sig := fn.Type().(*types.Signature)
+	m := method{name: fn.Name()}
+
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		m.params = append(m.params, param{
+			name: v.Name(),
+			// A variadic parameter is recorded as the slice itself
+			typ:      types.TypeString(v.Type(), im.qualifier),
+			variadic: sig.Variadic() && i == sig.Params().Len()-1,
+		})
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		m.results = append(m.results, types.TypeString(sig.Results().At(i).Type(), im.qualifier))
+	}
+	return m
+}
+
+// rename gives parameters names that cannot collide with the generated
+// code's locals or the imported packages. It runs once every type has
+// been rendered so the import names are final.
+func (m *method) rename(im *imports) {
+	for i := range m.params {
+		p := &m.params[i]
+		name := p.name
+		if name == "" || name == "_" || reserved[name] || im.used[name] || strings.HasPrefix(name, "r") && isDigits(name[1:]) {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		p.name, p.field = name, exported(name)
+	}
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// exported turns a parameter name into a field name
+func exported(name string) string {
+	if name == "id" {
+		return "ID"
+	}
+	if strings.HasSuffix(name, "Id") {
+		name = strings.TrimSuffix(name, "Id") + "ID"
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// signature renders the parameter list with declared names
+func (m method) signature() string {
+	parts := make([]string, len(m.params))
+	for i, p := range m.params {
+		if p.variadic {
+			parts[i] = p.name + " ..." + strings.TrimPrefix(p.typ, "[]")
+		} else {
+			parts[i] = p.name + " " + p.typ
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")" + m.resultList()
+}
+
+func (m method) resultList() string {
+	switch len(m.results) {
+	case 0:
+		return ""
+	case 1:
+		return " " + m.results[0]
+	}
+	return " (" + strings.Join(m.results, ", ") + ")"
+}
+
+// callArgs renders the arguments for forwarding a call
+func (m method) callArgs() string {
+	parts := make([]string, len(m.params))
+	for i, p := range m.params {
+		parts[i] = p.name
+		if p.variadic {
+			parts[i] += "..."
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// recordArgs renders the arguments as recorded, variadic ones as a slice
+func (m method) recordArgs() string {
+	var b strings.Builder
+	for _, p := range m.params {
+		b.WriteString(", ")
+		b.WriteString(p.name)
+	}
+	return b.String()
+}
+
+func (m method) resultVars() string {
+	vars := make([]string, len(m.results))
+	for i := range m.results {
+		vars[i] = fmt.Sprintf("r%d", i)
+	}
+	return strings.Join(vars, ", ")
+}
+
+// generate renders the output file
+func generate(pkg *types.Package, pkgName string, ifaces []namedInterface) ([]byte, error) {
+	im := newImports()
+	mockName := im.add(mockPath, "mock")
+	srcName := im.qualifier(pkg)
+
+	all := make([][]method, len(ifaces))
+	for n, ni := range ifaces {
+		generated := map[string]bool{"Verify": true}
+		for i := 0; i < ni.iface.NumMethods(); i++ {
+			fn := ni.iface.Method(i)
+			if !fn.Exported() {
+				return nil, fmt.Errorf("%s.%s has unexported method %s", pkg.Path(), ni.name, fn.Name())
+			}
+			all[n] = append(all[n], newMethod(fn, im))
+			generated["Expect"+fn.Name()] = true
+			generated[fn.Name()+"Calls"] = true
+		}
+		for _, m := range all[n] {
+			if generated[m.name] {
+				return nil, fmt.Errorf("%s.%s: method %s clashes with a generated helper", pkg.Path(), ni.name, m.name)
+			}
+		}
+	}
+
+	var body bytes.Buffer
+	for n, ni := range ifaces {
+		for i := range all[n] {
+			all[n][i].rename(im)
+		}
+		writeMock(&body, mockName, srcName, ni.name, all[n])
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by mockgen from %s. DO NOT EDIT.\n\n", pkg.Path())
+	fmt.Fprintf(&buf, "// Package %s holds test doubles for the interfaces of %s.\n", pkgName, pkg.Path())
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	im.write(&buf, strings.SplitN(pkg.Path(), "/", 2)[0])
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+func writeMock(buf *bytes.Buffer, mockPkg, srcPkg, name string, methods []method) {
+	w := func(format string, args ...interface{}) { fmt.Fprintf(buf, format, args...) }
+	qualified := srcPkg + "." + name
+
+	w("// %s is a test double for %s\n", name, qualified)
+	w("type %s struct {\n", name)
+	w("recorder %s.Recorder\n", mockPkg)
+	for _, m := range methods {
+		w("\n// %sFunc, if set, handles %s calls that match no expectation\n", m.name, m.name)
+		w("%sFunc func%s\n", m.name, m.signature())
+	}
+	w("}\n\n")
+	w("var _ %s = (*%s)(nil)\n\n", qualified, name)
+
+	w("// New%s returns a strict double: calls that match no expectation and\n", name)
+	w("// have no fake fail t, and unmet expectations fail t when the test ends\n")
+	w("func New%s(t %s.TB) *%s {\n", name, mockPkg, name)
+	w("m := &%s{}\n", name)
+	w("m.recorder.T = t\n")
+	w("t.Cleanup(func() { m.recorder.Verify(t) })\n")
+	w("return m\n}\n\n")
+
+	w("// Verify reports expectations that were not met\n")
+	w("func (m *%s) Verify(t %s.TB) {\n", name, mockPkg)
+	w("t.Helper()\n")
+	w("m.recorder.Verify(t)\n}\n\n")
+
+	for _, m := range methods {
+		writeMethod(w, mockPkg, srcPkg, name, m)
+	}
+}
+
+func writeMethod(w func(string, ...interface{}), mockPkg, srcPkg, name string, m method) {
+	callType := name + m.name + "Call"
+	argsType := name + m.name + "Args"
+	results := m.resultVars()
+
+	// The interface method
+	w("// %s implements %s.%s\n", m.name, srcPkg, name)
+	w("func (m *%s) %s%s {\n", name, m.name, m.signature())
+	w("if results, ok := m.recorder.Called(%q%s); ok {\n", m.name, m.recordArgs())
+	if len(m.results) == 0 {
+		w("_ = results\nreturn\n")
+	} else {
+		for i, typ := range m.results {
+			w("r%d, _ := results[%d].(%s)\n", i, i, typ)
+		}
+		w("return %s\n", results)
+	}
+	w("}\n")
+	w("if m.%sFunc != nil {\n", m.name)
+	if len(m.results) == 0 {
+		w("m.%sFunc(%s)\nreturn\n", m.name, m.callArgs())
+	} else {
+		w("return m.%sFunc(%s)\n", m.name, m.callArgs())
+	}
+	w("}\n")
+	w("m.recorder.Unexpected(%q%s)\n", m.name, m.recordArgs())
+	for i, typ := range m.results {
+		w("var r%d %s\n", i, typ)
+	}
+	if len(m.results) > 0 {
+		w("return %s\n", results)
+	}
+	w("}\n\n")
+
+	// The expectation
+	w("// %s is an expectation on %s.%s\n", callType, name, m.name)
+	w("type %s struct {\n", callType)
+	w("e *%s.Expectation\n}\n\n", mockPkg)
+
+	matchParams := make([]string, len(m.params))
+	for i, p := range m.params {
+		matchParams[i] = p.name
+	}
+	w("// Expect%s expects a call to %s whose arguments match the given\n", m.name, m.name)
+	w("// matchers or equal the given values\n")
+	if len(matchParams) == 0 {
+		w("func (m *%s) Expect%s() %s {\n", name, m.name, callType)
+	} else {
+		w("func (m *%s) Expect%s(%s interface{}) %s {\n", name, m.name, strings.Join(matchParams, ", "), callType)
+	}
+	w("return %s{m.recorder.Expect(%q, %d%s)}\n}\n\n", callType, m.name, len(m.results), m.recordArgs())
+
+	if len(m.results) > 0 {
+		resultParams := make([]string, len(m.results))
+		for i, typ := range m.results {
+			resultParams[i] = fmt.Sprintf("r%d %s", i, typ)
+		}
+		w("// Return sets the values %s returns\n", m.name)
+		w("func (c %s) Return(%s) %s {\n", callType, strings.Join(resultParams, ", "), callType)
+		w("c.e.Return(%s)\nreturn c\n}\n\n", results)
+	}
+
+	w("// Do computes the values %s returns from its arguments\n", m.name)
+	w("func (c %s) Do(fn func%s) %s {\n", callType, m.signature(), callType)
+	w("c.e.Do(func(args []interface{}) []interface{} {\n")
+	for i, p := range m.params {
+		w("%s, _ := args[%d].(%s)\n", p.name, i, p.typ)
+	}
+	if len(m.results) == 0 {
+		w("fn(%s)\nreturn nil\n", m.callArgs())
+	} else {
+		w("%s := fn(%s)\n", results, m.callArgs())
+		w("return []interface{}{%s}\n", results)
+	}
+	w("})\nreturn c\n}\n\n")
+
+	w("// Times requires exactly n matching calls\n")
+	w("func (c %s) Times(n int) %s {\n", callType, callType)
+	w("c.e.Times(n)\nreturn c\n}\n\n")
+	w("// AnyTimes allows any number of matching calls\n")
+	w("func (c %s) AnyTimes() %s {\n", callType, callType)
+	w("c.e.AnyTimes()\nreturn c\n}\n\n")
+
+	// The spy
+	w("// %s holds the arguments of one %s call\n", argsType, m.name)
+	w("type %s struct {\n", argsType)
+	for _, p := range m.params {
+		w("%s %s\n", p.field, p.typ)
+	}
+	w("}\n\n")
+	w("// %sCalls returns the arguments of every %s call in order\n", m.name, m.name)
+	w("func (m *%s) %sCalls() []%s {\n", name, m.name, argsType)
+	w("var out []%s\n", argsType)
+	w("for _, call := range m.recorder.Calls(%q) {\n", m.name)
+	if len(m.params) == 0 {
+		w("_ = call\n")
+	}
+	w("var args %s\n", argsType)
+	for i, p := range m.params {
+		w("args.%s, _ = call.Args[%d].(%s)\n", p.field, i, p.typ)
+	}
+	w("out = append(out, args)\n}\n")
+	w("return out\n}\n\n")
+}
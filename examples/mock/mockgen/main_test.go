@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGeneratedUpToDate fails when a committed mocks file no longer
+// matches its interfaces; run go generate ./... to refresh them.
+func TestGeneratedUpToDate(t *testing.T) {
+	for _, dir := range []string{"example1", "example4", "example5", "example6"} {
+		t.Run(dir, func(t *testing.T) {
+			src := filepath.Join("..", "..", dir)
+			pkg, err := load(src)
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			ifaces, err := selectInterfaces(pkg, "")
+			if err != nil {
+				t.Fatalf("selectInterfaces() error = %v", err)
+			}
+			got, err := generate(pkg, "mocks", ifaces)
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+
+			want, err := os.ReadFile(filepath.Join(src, "mocks", "mocks.go"))
+			if err != nil {
+				t.Fatalf("reading committed mocks: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s/mocks/mocks.go is stale; run go generate ./examples/%s", dir, dir)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Package proptest is a small property-based testing helper: generators
+// produce random inputs, Check runs a property against many of them, and a
+// failing input is shrunk to a minimal counterexample before it is reported.
+package proptest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// Gen generates random values of T and proposes simpler variants of a
+// value for shrinking. Shrink may be nil for values that cannot shrink.
+type Gen[T any] struct {
+	Generate func(r *rand.Rand) T
+	Shrink   func(v T) []T
+}
+
+// Config controls Check. Zero fields take the defaults noted.
+type Config struct {
+	Runs       int   // inputs to try, default 200
+	Seed       int64 // default derived from the clock; reported on failure
+	MaxShrinks int   // shrink steps before giving up, default 1000
+}
+
+// Check runs prop against generated inputs and fails t with a shrunk
+// counterexample if prop returns an error for any of them.
+func Check[T any](t testing.TB, gen Gen[T], prop func(T) error, cfg ...Config) {
+	t.Helper()
+
+	var c Config
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	if c.Runs <= 0 {
+		c.Runs = 200
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	if c.MaxShrinks <= 0 {
+		c.MaxShrinks = 1000
+	}
+
+	r := rand.New(rand.NewSource(c.Seed))
+	for run := 1; run <= c.Runs; run++ {
+		input := gen.Generate(r)
+		err := prop(input)
+		if err == nil {
+			continue
+		}
+
+		shrunk, shrunkErr, steps := shrink(gen, prop, input, err, c.MaxShrinks)
+		t.Fatalf("property failed on run %d (seed %d)\noriginal input: %+v\noriginal error: %v\nshrunk input (%d steps): %+v\nshrunk error: %v",
+			run, c.Seed, input, err, steps, shrunk, shrunkErr)
+		return
+	}
+}
+
+// shrink greedily replaces the failing input with the first simpler
+// candidate that still fails, until no candidate fails.
+func shrink[T any](gen Gen[T], prop func(T) error, input T, err error, maxSteps int) (T, error, int) {
+	if gen.Shrink == nil {
+		return input, err, 0
+	}
+
+	steps := 0
+	for steps < maxSteps {
+		improved := false
+		for _, candidate := range gen.Shrink(input) {
+			if candidateErr := prop(candidate); candidateErr != nil {
+				input, err = candidate, candidateErr
+				steps++
+				improved = true
+				break
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return input, err, steps
+}
+
+// Int generates integers in [min, max], shrinking toward the value in the
+// range closest to zero.
+func Int(min, max int) Gen[int] {
+	if min > max {
+		panic(fmt.Sprintf("proptest: Int(%d, %d): min > max", min, max))
+	}
+
+	target := 0
+	switch {
+	case min > 0:
+		target = min
+	case max < 0:
+		target = max
+	}
+
+	return Gen[int]{
+		Generate: func(r *rand.Rand) int {
+			// Bias toward the edges, where bugs tend to live
+			switch r.Intn(10) {
+			case 0:
+				return min
+			case 1:
+				return max
+			case 2:
+				return target
+			}
+			return min + int(uniform(r, uint64(max)-uint64(min)))
+		},
+		Shrink: func(v int) []int {
+			if v == target {
+				return nil
+			}
+			candidates := []int{target}
+			if half := target + (v-target)/2; half != target && half != v {
+				candidates = append(candidates, half)
+			}
+			if v > target {
+				candidates = append(candidates, v-1)
+			} else {
+				candidates = append(candidates, v+1)
+			}
+			return candidates
+		},
+	}
+}
+
+// uniform returns a uniformly random value in [0, span]. span+1 may not
+// fit in an int64, as for Int(math.MinInt, math.MaxInt), so large spans
+// draw 64 random bits and reject values past span.
+func uniform(r *rand.Rand, span uint64) uint64 {
+	if span < math.MaxInt64 {
+		return uint64(r.Int63n(int64(span) + 1))
+	}
+	for {
+		if v := r.Uint64(); v <= span {
+			return v
+		}
+	}
+}
+
+// Alphabets for String
+const (
+	Lower    = "abcdefghijklmnopqrstuvwxyz"
+	Printing = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+)
+
+// String generates strings of up to maxLen runes drawn from alphabet,
+// shrinking toward the empty string and toward the alphabet's first rune.
+func String(maxLen int, alphabet string) Gen[string] {
+	runes := []rune(alphabet)
+	if len(runes) == 0 {
+		panic("proptest: String: empty alphabet")
+	}
+
+	return Gen[string]{
+		Generate: func(r *rand.Rand) string {
+			n := r.Intn(maxLen + 1)
+			if r.Intn(10) == 0 {
+				n = 0
+			}
+			out := make([]rune, n)
+			for i := range out {
+				out[i] = runes[r.Intn(len(runes))]
+			}
+			return string(out)
+		},
+		Shrink: func(v string) []string {
+			s := []rune(v)
+			if len(s) == 0 {
+				return nil
+			}
+
+			candidates := []string{""}
+			if len(s) > 1 {
+				candidates = append(candidates, string(s[:len(s)/2]), string(s[len(s)/2:]))
+			}
+			for i := range s {
+				candidates = append(candidates, string(s[:i])+string(s[i+1:]))
+			}
+			for i, c := range s {
+				if c != runes[0] {
+					simpler := append([]rune(nil), s...)
+					simpler[i] = runes[0]
+					candidates = append(candidates, string(simpler))
+				}
+			}
+			return candidates
+		},
+	}
+}
+
+// Bool generates booleans, shrinking toward false
+func Bool() Gen[bool] {
+	return Gen[bool]{
+		Generate: func(r *rand.Rand) bool { return r.Intn(2) == 1 },
+		Shrink: func(v bool) []bool {
+			if v {
+				return []bool{false}
+			}
+			return nil
+		},
+	}
+}
+
+// Map derives a generator by transforming values of another. Shrinking
+// needs the inverse, so unmap returns the source value for a mapped one.
+func Map[A, B any](gen Gen[A], fn func(A) B, unmap func(B) A) Gen[B] {
+	return Gen[B]{
+		Generate: func(r *rand.Rand) B { return fn(gen.Generate(r)) },
+		Shrink: func(v B) []B {
+			if gen.Shrink == nil || unmap == nil {
+				return nil
+			}
+			var out []B
+			for _, a := range gen.Shrink(unmap(v)) {
+				out = append(out, fn(a))
+			}
+			return out
+		},
+	}
+}
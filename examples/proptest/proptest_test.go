@@ -0,0 +1,222 @@
+package proptest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fatalRecorder records Check's failure instead of failing the test
+type fatalRecorder struct {
+	testing.TB
+	failure string
+}
+
+func (f *fatalRecorder) Helper() {}
+
+func (f *fatalRecorder) Fatalf(format string, args ...interface{}) {
+	f.failure = fmt.Sprintf(format, args...)
+}
+
+func TestIntStaysInRange(t *testing.T) {
+	ranges := []struct{ min, max int }{
+		{0, 0},
+		{-5, 5},
+		{3, 9},
+		{-9, -3},
+		{math.MinInt, math.MaxInt},
+		{math.MinInt, 0},
+		{-1, math.MaxInt},
+		{math.MaxInt - 1, math.MaxInt},
+	}
+	r := rand.New(rand.NewSource(1))
+	for _, rg := range ranges {
+		gen := Int(rg.min, rg.max)
+		for i := 0; i < 1000; i++ {
+			if v := gen.Generate(r); v < rg.min || v > rg.max {
+				t.Fatalf("Int(%d, %d) generated %d", rg.min, rg.max, v)
+			}
+		}
+	}
+}
+
+func TestIntFullRangeSpreads(t *testing.T) {
+	gen := Int(math.MinInt, math.MaxInt)
+	r := rand.New(rand.NewSource(1))
+	var negative, positive int
+	for i := 0; i < 1000; i++ {
+		switch v := gen.Generate(r); {
+		case v < math.MinInt/2:
+			negative++
+		case v > math.MaxInt/2:
+			positive++
+		}
+	}
+	if negative < 100 || positive < 100 {
+		t.Errorf("Int(MinInt, MaxInt) gave %d values below MinInt/2 and %d above MaxInt/2 in 1000, want both around a quarter", negative, positive)
+	}
+}
+
+func TestIntPanicsOnEmptyRange(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "min > max") {
+			t.Errorf("Int(2, 1) panic = %v, want min > max", r)
+		}
+	}()
+	Int(2, 1)
+}
+
+func TestIntShrink(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		v        int
+		want     []int
+	}{
+		{"toward zero", -10, 10, 8, []int{0, 4, 7}},
+		{"negative toward zero", -10, 10, -8, []int{0, -4, -7}},
+		{"toward min when positive", 5, 20, 9, []int{5, 7, 8}},
+		{"toward max when negative", -20, -5, -9, []int{-5, -7, -8}},
+		{"no half next to the target", -10, 10, 1, []int{0, 0}},
+		{"target does not shrink", -10, 10, 0, nil},
+		{"extremes do not overflow", math.MinInt, math.MaxInt, math.MaxInt, []int{0, math.MaxInt / 2, math.MaxInt - 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Int(tt.min, tt.max).Shrink(tt.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Shrink(%d) = %v, want %v", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	gen := String(5, "ab")
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		v := gen.Generate(r)
+		if len(v) > 5 || strings.Trim(v, "ab") != "" {
+			t.Fatalf("String(5, ab) generated %q", v)
+		}
+	}
+
+	if got, want := gen.Shrink("bab"), []string{"", "b", "ab", "ab", "bb", "ba", "aab", "baa"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Shrink(bab) = %q, want %q", got, want)
+	}
+	if got := gen.Shrink(""); got != nil {
+		t.Errorf("Shrink(\"\") = %q, want nil", got)
+	}
+	if got, want := String(3, "é").Shrink("é"), []string{"", ""}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Shrink(é) = %q, want %q: shrinking works on runes", got, want)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("String with an empty alphabet did not panic")
+		}
+	}()
+	String(3, "")
+}
+
+func TestBool(t *testing.T) {
+	gen := Bool()
+	r := rand.New(rand.NewSource(1))
+	seen := map[bool]int{}
+	for i := 0; i < 100; i++ {
+		seen[gen.Generate(r)]++
+	}
+	if seen[true] == 0 || seen[false] == 0 {
+		t.Errorf("Bool generated %v in 100 runs, want both values", seen)
+	}
+	if got := gen.Shrink(true); !reflect.DeepEqual(got, []bool{false}) {
+		t.Errorf("Shrink(true) = %v, want [false]", got)
+	}
+	if got := gen.Shrink(false); got != nil {
+		t.Errorf("Shrink(false) = %v, want nil", got)
+	}
+}
+
+func TestMap(t *testing.T) {
+	double := func(n int) int { return 2 * n }
+	halve := func(n int) int { return n / 2 }
+
+	gen := Map(Int(0, 50), double, halve)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		if v := gen.Generate(r); v%2 != 0 || v < 0 || v > 100 {
+			t.Fatalf("Map(Int(0, 50), double) generated %d", v)
+		}
+	}
+	if got, want := gen.Shrink(20), []int{0, 10, 18}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Shrink(20) = %v, want %v", got, want)
+	}
+
+	if got := Map(Int(0, 50), double, nil).Shrink(20); got != nil {
+		t.Errorf("Shrink without unmap = %v, want nil", got)
+	}
+	if got := Map(Gen[int]{Generate: Int(0, 50).Generate}, double, halve).Shrink(20); got != nil {
+		t.Errorf("Shrink of an unshrinkable source = %v, want nil", got)
+	}
+}
+
+func TestCheckPasses(t *testing.T) {
+	runs := 0
+	Check(t, Int(0, 100), func(n int) error {
+		runs++
+		if n < 0 || n > 100 {
+			return fmt.Errorf("%d out of range", n)
+		}
+		return nil
+	}, Config{Runs: 50})
+	if runs != 50 {
+		t.Errorf("Check ran the property %d times, want 50", runs)
+	}
+}
+
+func TestCheckShrinksCounterexample(t *testing.T) {
+	rec := &fatalRecorder{TB: t}
+	Check(rec, Int(0, 1000), func(n int) error {
+		if n >= 17 {
+			return errors.New("too big")
+		}
+		return nil
+	}, Config{Seed: 42})
+
+	if !strings.Contains(rec.failure, "(seed 42)") || !strings.Contains(rec.failure, "steps): 17\n") {
+		t.Errorf("Check failure =\n%s\nwant the seed and 17 as the shrunk input", rec.failure)
+	}
+}
+
+func TestCheckIsReproducibleFromSeed(t *testing.T) {
+	inputs := func() []string {
+		var seen []string
+		Check(t, String(8, Lower), func(s string) error {
+			seen = append(seen, s)
+			return nil
+		}, Config{Runs: 20, Seed: 7})
+		return seen
+	}
+	if first, second := inputs(), inputs(); !reflect.DeepEqual(first, second) {
+		t.Errorf("the same seed generated %q, then %q", first, second)
+	}
+}
+
+func TestShrinkStopsAtMaxSteps(t *testing.T) {
+	// Only stepping down by one still fails, so each step removes one
+	countdown := Gen[int]{Shrink: func(v int) []int { return []int{v - 1} }}
+	alwaysFails := func(int) error { return errors.New("fails") }
+
+	got, _, steps := shrink(countdown, alwaysFails, 100, errors.New("fails"), 10)
+	if got != 90 || steps != 10 {
+		t.Errorf("shrink() = %d after %d steps, want 90 after 10", got, steps)
+	}
+
+	got, _, steps = shrink(Gen[int]{}, alwaysFails, 100, errors.New("fails"), 10)
+	if got != 100 || steps != 0 {
+		t.Errorf("shrink() without Shrink = %d after %d steps, want the input unchanged", got, steps)
+	}
+}
@@ -0,0 +1,232 @@
+// Package tabletest runs table-driven test cases either under go test or as
+// a printed walkthrough, so the examples' demo output and their tests share
+// one table. Package tabletesttest runs a table under go test; it is kept
+// apart so the demos do not link the testing package.
+package tabletest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+// Case is a single table entry
+type Case[In, Out any] struct {
+	Name  string
+	Input In
+
+	// Want is the expected output. In a table with a GoldenDir, a zero
+	// Want leaves the golden file as the only expectation.
+	Want Out
+
+	// WantErr expects an error of any kind; ErrIs expects one matching
+	// errors.Is and implies WantErr.
+	WantErr bool
+	ErrIs   error
+
+	// Setup and Teardown run around this case only
+	Setup    func() error
+	Teardown func()
+}
+
+func (c Case[In, Out]) wantsErr() bool {
+	return c.WantErr || c.ErrIs != nil
+}
+
+// Table is a set of cases run against one function
+type Table[In, Out any] struct {
+	Name  string
+	Cases []Case[In, Out]
+
+	// Run is the code under test
+	Run func(in In) (Out, error)
+
+	// Equal compares outputs of successful cases; default reflect.DeepEqual
+	Equal func(got, want Out) bool
+
+	// BeforeEach and AfterEach run around every case
+	BeforeEach func() error
+	AfterEach  func()
+
+	// Parallel runs cases concurrently. Print still reports them in order.
+	Parallel bool
+
+	// GoldenDir, if set, stores each case's output in
+	// GoldenDir/<case>.golden and fails when the output differs.
+	// UpdateGolden rewrites the files instead.
+	GoldenDir    string
+	UpdateGolden bool
+
+	// Golden renders a case's outcome for golden comparison; default is
+	// indented JSON of the output or the error message.
+	Golden func(got Out, err error) []byte
+
+	// Describe prints a case's outcome in the walkthrough; default prints
+	// the error or the output as JSON.
+	Describe func(w io.Writer, got Out, err error)
+}
+
+// Result is the outcome of one case
+type Result[Out any] struct {
+	Name    string
+	Got     Out
+	Err     error // returned by Run
+	Failure error // why the case failed, nil if it passed
+}
+
+// Print runs every case and writes a walkthrough to w in the style of
+// the examples' demos. It returns an error for the first failing case.
+func (tb Table[In, Out]) Print(w io.Writer) error {
+	results := tb.RunAll()
+
+	for _, r := range results {
+		fmt.Fprintf(w, "\nTesting: %s\n", r.Name)
+		if r.Failure != nil {
+			return fmt.Errorf("%s: %w", r.Name, r.Failure)
+		}
+		tb.describe(w, r.Got, r.Err)
+	}
+	return nil
+}
+
+// RunAll runs every case, concurrently when Parallel is set, and returns
+// the results in table order.
+func (tb Table[In, Out]) RunAll() []Result[Out] {
+	results := make([]Result[Out], len(tb.Cases))
+	if !tb.Parallel {
+		for i, c := range tb.Cases {
+			results[i] = tb.RunCase(c)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range tb.Cases {
+		wg.Add(1)
+		go func(i int, c Case[In, Out]) {
+			defer wg.Done()
+			results[i] = tb.RunCase(c)
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// RunCase runs one case with its hooks and checks the outcome
+func (tb Table[In, Out]) RunCase(c Case[In, Out]) (r Result[Out]) {
+	r.Name = c.Name
+
+	if tb.BeforeEach != nil {
+		if err := tb.BeforeEach(); err != nil {
+			r.Failure = fmt.Errorf("before each: %w", err)
+			return r
+		}
+	}
+	if tb.AfterEach != nil {
+		defer tb.AfterEach()
+	}
+	if c.Setup != nil {
+		if err := c.Setup(); err != nil {
+			r.Failure = fmt.Errorf("setup: %w", err)
+			return r
+		}
+	}
+	if c.Teardown != nil {
+		defer c.Teardown()
+	}
+
+	r.Got, r.Err = tb.Run(c.Input)
+	r.Failure = tb.check(c, r.Got, r.Err)
+	if r.Failure == nil && tb.GoldenDir != "" {
+		r.Failure = tb.checkGolden(c.Name, r.Got, r.Err)
+	}
+	return r
+}
+
+// check compares an outcome with the case's expectations
+func (tb Table[In, Out]) check(c Case[In, Out], got Out, err error) error {
+	switch {
+	case c.wantsErr() && err == nil:
+		return fmt.Errorf("expected an error, got %+v", got)
+	case c.ErrIs != nil && !errors.Is(err, c.ErrIs):
+		return fmt.Errorf("error %q does not match %q", err, c.ErrIs)
+	case !c.wantsErr() && err != nil:
+		return fmt.Errorf("unexpected error: %w", err)
+	case !c.wantsErr() && tb.comparesWant(c) && !tb.equal(got, c.Want):
+		return fmt.Errorf("got %+v, want %+v", got, c.Want)
+	}
+	return nil
+}
+
+func (tb Table[In, Out]) comparesWant(c Case[In, Out]) bool {
+	return tb.GoldenDir == "" || !reflect.ValueOf(&c.Want).Elem().IsZero()
+}
+
+func (tb Table[In, Out]) equal(got, want Out) bool {
+	if tb.Equal != nil {
+		return tb.Equal(got, want)
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func (tb Table[In, Out]) describe(w io.Writer, got Out, err error) {
+	if tb.Describe != nil {
+		tb.Describe(w, got, err)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(w, "Expected error: %v\n", err)
+		return
+	}
+	data, _ := json.MarshalIndent(got, "", "  ")
+	fmt.Fprintf(w, "Result: %s\n", data)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// GoldenPath returns the golden file used for a case
+func (tb Table[In, Out]) GoldenPath(caseName string) string {
+	return filepath.Join(tb.GoldenDir, unsafeFileChars.ReplaceAllString(caseName, "_")+".golden")
+}
+
+func (tb Table[In, Out]) checkGolden(name string, got Out, err error) error {
+	var actual []byte
+	if tb.Golden != nil {
+		actual = tb.Golden(got, err)
+	} else if err != nil {
+		actual = []byte("error: " + err.Error() + "\n")
+	} else {
+		data, marshalErr := json.MarshalIndent(got, "", "  ")
+		if marshalErr != nil {
+			return fmt.Errorf("failed to render golden output: %w", marshalErr)
+		}
+		actual = append(data, '\n')
+	}
+
+	path := tb.GoldenPath(name)
+	if tb.UpdateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create golden directory: %w", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			return fmt.Errorf("failed to update golden file: %w", err)
+		}
+		return nil
+	}
+
+	expected, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return fmt.Errorf("failed to read golden file (run with -update to create it): %w", readErr)
+	}
+	if !bytes.Equal(actual, expected) {
+		return fmt.Errorf("output differs from %s:\n--- got\n%s\n--- want\n%s", path, actual, expected)
+	}
+	return nil
+}
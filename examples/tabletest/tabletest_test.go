@@ -0,0 +1,255 @@
+package tabletest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errOdd = errors.New("odd input")
+
+// half returns n/2 and errOdd for odd n
+func half(n int) (int, error) {
+	if n%2 != 0 {
+		return 0, fmt.Errorf("half(%d): %w", n, errOdd)
+	}
+	return n / 2, nil
+}
+
+func TestRunCaseChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		c           Case[int, int]
+		wantFailure string // "" means the case passes
+	}{
+		{"match", Case[int, int]{Input: 4, Want: 2}, ""},
+		{"mismatch", Case[int, int]{Input: 4, Want: 3}, "got 2, want 3"},
+		{"unexpected error", Case[int, int]{Input: 3, Want: 1}, "unexpected error: half(3): odd input"},
+		{"wanted error", Case[int, int]{Input: 3, WantErr: true}, ""},
+		{"wanted error missing", Case[int, int]{Input: 4, WantErr: true}, "expected an error, got 2"},
+		{"ErrIs matches", Case[int, int]{Input: 3, ErrIs: errOdd}, ""},
+		{"ErrIs implies WantErr", Case[int, int]{Input: 4, ErrIs: errOdd}, "expected an error, got 2"},
+		{"ErrIs mismatch", Case[int, int]{Input: 3, ErrIs: io.EOF}, `error "half(3): odd input" does not match "EOF"`},
+	}
+
+	table := Table[int, int]{Run: half}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.Name = tt.name
+			r := table.RunCase(tt.c)
+			if r.Name != tt.name {
+				t.Errorf("Result.Name = %q, want %q", r.Name, tt.name)
+			}
+			switch {
+			case tt.wantFailure == "" && r.Failure != nil:
+				t.Errorf("Failure = %v, want nil", r.Failure)
+			case tt.wantFailure != "" && (r.Failure == nil || r.Failure.Error() != tt.wantFailure):
+				t.Errorf("Failure = %v, want %q", r.Failure, tt.wantFailure)
+			}
+		})
+	}
+}
+
+func TestRunCaseCustomEqual(t *testing.T) {
+	table := Table[int, int]{
+		Run:   half,
+		Equal: func(got, want int) bool { return got%2 == want%2 },
+	}
+	if r := table.RunCase(Case[int, int]{Input: 8, Want: 6}); r.Failure != nil {
+		t.Errorf("Failure = %v, want the custom Equal to accept 4 for 6", r.Failure)
+	}
+	if r := table.RunCase(Case[int, int]{Input: 8, Want: 3}); r.Failure == nil {
+		t.Error("Failure = nil, want the custom Equal to reject 4 for 3")
+	}
+}
+
+func TestRunCaseHooks(t *testing.T) {
+	var calls []string
+	record := func(name string) func() { return func() { calls = append(calls, name) } }
+
+	table := Table[int, int]{
+		Run: func(n int) (int, error) {
+			calls = append(calls, "run")
+			return half(n)
+		},
+		BeforeEach: func() error { record("before")(); return nil },
+		AfterEach:  record("after"),
+	}
+	c := Case[int, int]{
+		Input:    2,
+		Want:     1,
+		Setup:    func() error { record("setup")(); return nil },
+		Teardown: record("teardown"),
+	}
+
+	if r := table.RunCase(c); r.Failure != nil {
+		t.Fatalf("Failure = %v", r.Failure)
+	}
+	if got, want := strings.Join(calls, ","), "before,setup,run,teardown,after"; got != want {
+		t.Errorf("hooks ran as %s, want %s", got, want)
+	}
+
+	// Hook failures stop the case before Run, but earlier hooks still unwind
+	calls = nil
+	c.Setup = func() error { return errors.New("no fixture") }
+	if r := table.RunCase(c); r.Failure == nil || r.Failure.Error() != "setup: no fixture" {
+		t.Errorf("Failure = %v, want the setup error", r.Failure)
+	}
+	if got, want := strings.Join(calls, ","), "before,after"; got != want {
+		t.Errorf("hooks ran as %s after a failed setup, want %s", got, want)
+	}
+
+	calls = nil
+	table.BeforeEach = func() error { return errors.New("no database") }
+	if r := table.RunCase(c); r.Failure == nil || r.Failure.Error() != "before each: no database" {
+		t.Errorf("Failure = %v, want the before each error", r.Failure)
+	}
+	if len(calls) != 0 {
+		t.Errorf("hooks ran as %v after a failed before each, want none", calls)
+	}
+}
+
+func TestRunAllParallel(t *testing.T) {
+	const n = 8
+
+	// Every case waits until all of them have started, so a sequential
+	// run would never finish
+	var started atomic.Int32
+	allStarted := make(chan struct{})
+	table := Table[int, string]{
+		Parallel: true,
+		Run: func(i int) (string, error) {
+			if started.Add(1) == n {
+				close(allStarted)
+			}
+			select {
+			case <-allStarted:
+			case <-time.After(5 * time.Second):
+				return "", errors.New("cases did not run concurrently")
+			}
+			return strconv.Itoa(i), nil
+		},
+	}
+	for i := 0; i < n; i++ {
+		table.Cases = append(table.Cases, Case[int, string]{Name: strconv.Itoa(i), Input: i, Want: strconv.Itoa(i)})
+	}
+
+	for i, r := range table.RunAll() {
+		if r.Failure != nil {
+			t.Errorf("case %s failed: %v", r.Name, r.Failure)
+		}
+		if r.Name != strconv.Itoa(i) || r.Got != strconv.Itoa(i) {
+			t.Errorf("results[%d] = %+v, want the results in table order", i, r)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	table := Table[int, int]{
+		Run: half,
+		Cases: []Case[int, int]{
+			{Name: "even", Input: 4, Want: 2},
+			{Name: "odd", Input: 3, ErrIs: errOdd},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := table.Print(&buf); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	want := "\nTesting: even\nResult: 2\n\nTesting: odd\nExpected error: half(3): odd input\n"
+	if buf.String() != want {
+		t.Errorf("Print() wrote %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	table.Describe = func(w io.Writer, got int, err error) { fmt.Fprintf(w, "%d %v\n", got, err) }
+	table.Cases = append(table.Cases, Case[int, int]{Name: "wrong", Input: 6, Want: 4}, Case[int, int]{Name: "never", Input: 8, Want: 4})
+	err := table.Print(&buf)
+	if err == nil || err.Error() != "wrong: got 3, want 4" {
+		t.Errorf("Print() error = %v, want the first failing case", err)
+	}
+	want = "\nTesting: even\n2 <nil>\n\nTesting: odd\n0 half(3): odd input\n\nTesting: wrong\n"
+	if buf.String() != want {
+		t.Errorf("Print() wrote %q, want it to stop at the failing case", buf.String())
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "golden")
+	table := Table[int, int]{
+		Run:       half,
+		GoldenDir: dir,
+		Cases: []Case[int, int]{
+			{Name: "even input", Input: 4, Want: 2},
+			{Name: "odd/input", Input: 3, WantErr: true},
+		},
+	}
+
+	if got, want := table.GoldenPath("odd/input"), filepath.Join(dir, "odd_input.golden"); got != want {
+		t.Errorf("GoldenPath() = %s, want %s", got, want)
+	}
+	for _, r := range table.RunAll() {
+		if r.Failure == nil || !errors.Is(r.Failure, os.ErrNotExist) || !strings.Contains(r.Failure.Error(), "-update") {
+			t.Errorf("case %s without a golden file: Failure = %v, want a hint to run -update", r.Name, r.Failure)
+		}
+	}
+
+	table.UpdateGolden = true
+	for _, r := range table.RunAll() {
+		if r.Failure != nil {
+			t.Fatalf("case %s with UpdateGolden: Failure = %v", r.Name, r.Failure)
+		}
+	}
+	for name, want := range map[string]string{"even input": "2\n", "odd/input": "error: half(3): odd input\n"} {
+		if data, err := os.ReadFile(table.GoldenPath(name)); err != nil || string(data) != want {
+			t.Errorf("golden file for %s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+
+	table.UpdateGolden = false
+	for _, r := range table.RunAll() {
+		if r.Failure != nil {
+			t.Errorf("case %s against its golden file: Failure = %v", r.Name, r.Failure)
+		}
+	}
+
+	// Without a Want the golden file is the only expectation
+	if r := table.RunCase(Case[int, int]{Name: "even input", Input: 4}); r.Failure != nil {
+		t.Errorf("case without a Want: Failure = %v", r.Failure)
+	}
+
+	// A custom renderer that no longer matches the stored output fails
+	table.Golden = func(got int, err error) []byte { return []byte(fmt.Sprintf("%d %v\n", got, err)) }
+	r := table.RunCase(table.Cases[0])
+	if r.Failure == nil || !strings.Contains(r.Failure.Error(), "--- got\n2 <nil>\n") {
+		t.Errorf("Failure = %v, want a diff against the golden file", r.Failure)
+	}
+
+	// Golden output is only checked once the case's own expectations pass
+	r = table.RunCase(Case[int, int]{Name: "even input", Input: 4, Want: 5})
+	if r.Failure == nil || r.Failure.Error() != "got 2, want 5" {
+		t.Errorf("Failure = %v, want the expectation mismatch", r.Failure)
+	}
+}
+
+func TestGoldenUnencodableOutput(t *testing.T) {
+	table := Table[int, chan int]{
+		Run:          func(int) (chan int, error) { return nil, nil },
+		Equal:        func(got, want chan int) bool { return true },
+		GoldenDir:    t.TempDir(),
+		UpdateGolden: true,
+	}
+	r := table.RunCase(Case[int, chan int]{Name: "chan"})
+	if r.Failure == nil || !strings.Contains(r.Failure.Error(), "failed to render golden output") {
+		t.Errorf("Failure = %v, want a render error", r.Failure)
+	}
+}
@@ -0,0 +1,26 @@
+// Package tabletesttest runs tabletest tables under go test. It is separate
+// from tabletest so that the demos, which only print tables, do not import
+// the testing package.
+package tabletesttest
+
+import (
+	"testing"
+
+	"practice/examples/tabletest"
+)
+
+// Run runs every case of tb as a subtest of t
+func Run[In, Out any](t *testing.T, tb tabletest.Table[In, Out]) {
+	t.Helper()
+
+	for _, c := range tb.Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if tb.Parallel {
+				t.Parallel()
+			}
+			if r := tb.RunCase(c); r.Failure != nil {
+				t.Error(r.Failure)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"practice/examples/demo"
 	"practice/examples/example1"
 	"practice/examples/example10"
 	"practice/examples/example2"
@@ -18,16 +19,34 @@ import (
 )
 
 func main() {
+	// Subcommands come before the example flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "coverage":
+			os.Exit(runCoverage(os.Args[2:]))
+		case "tasks":
+			os.Exit(runTasks(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		}
+	}
+
 	// Define command line flags
 	example1Flag := flag.Bool("example1", false, "Run example 1 (Interface Design)")
 	example2Flag := flag.Bool("example2", false, "Run example 2 (Concurrency Patterns)")
 	example2PipelineFlag := flag.Bool("example2-pipeline", false, "Run example 2 pipeline pattern")
 	example3Flag := flag.Bool("example3", false, "Run example 3 (Error Handling)")
+	example3ConcurrencyFlag := flag.Bool("example3-concurrency", false, "Run example 3 concurrency and optimistic locking")
+	example3ResilienceFlag := flag.Bool("example3-resilience", false, "Run example 3 retries and circuit breaker")
+	example3PanicFlag := flag.Bool("example3-panic", false, "Run example 3 panic recovery")
 	example4Flag := flag.Bool("example4", false, "Run example 4 (Testing Strategies)")
 	example4BenchmarkFlag := flag.Bool("example4-benchmark", false, "Run example 4 benchmarks")
+	example4BenchmarkSave := flag.String("example4-benchmark-save", "", "Save example 4 benchmark results as a JSON baseline to this file")
+	example4BenchmarkCompare := flag.String("example4-benchmark-compare", "", "Compare example 4 benchmark results against this JSON baseline")
 	example4IntegrationFlag := flag.Bool("example4-integration", false, "Run example 4 integration tests")
 	example5Flag := flag.Bool("example5", false, "Run example 5 (Package Design)")
 	example5IntegrationFlag := flag.Bool("example5-integration", false, "Run example 5 integration tests")
+	example5SQLiteFlag := flag.Bool("example5-sqlite", false, "Run example 5 with SQLite storage")
 	example6Flag := flag.Bool("example6", false, "Run example 6 (Performance Optimization)")
 	example6BenchmarkFlag := flag.Bool("example6-benchmark", false, "Run example 6 benchmarks")
 	example7Flag := flag.Bool("example7", false, "Run example 7 (Dependency Management)")
@@ -38,20 +57,27 @@ func main() {
 	example9BenchmarkFlag := flag.Bool("example9-benchmark", false, "Run example 9 benchmarks")
 	example10Flag := flag.Bool("example10", false, "Run example 10 (Idiomatic Go)")
 	example10BenchmarkFlag := flag.Bool("example10-benchmark", false, "Run example 10 benchmarks")
+	deterministicFlag := flag.Bool("deterministic", false, "Use a fixed clock and sequential IDs so examples print the same output every run")
 	flag.Parse()
 
 	// Check if any example flag is set
-	if !*example1Flag && !*example2Flag && !*example2PipelineFlag && !*example3Flag && !*example4Flag && !*example4BenchmarkFlag && !*example4IntegrationFlag && !*example5Flag && !*example5IntegrationFlag && !*example6Flag && !*example6BenchmarkFlag && !*example7Flag && !*example7BenchmarkFlag && !*example8Flag && !*example8BenchmarkFlag && !*example9Flag && !*example9BenchmarkFlag && !*example10Flag && !*example10BenchmarkFlag {
+	if !*example1Flag && !*example2Flag && !*example2PipelineFlag && !*example3Flag && !*example3ConcurrencyFlag && !*example3ResilienceFlag && !*example3PanicFlag && !*example4Flag && !*example4BenchmarkFlag && !*example4IntegrationFlag && !*example5Flag && !*example5IntegrationFlag && !*example5SQLiteFlag && !*example6Flag && !*example6BenchmarkFlag && !*example7Flag && !*example7BenchmarkFlag && !*example8Flag && !*example8BenchmarkFlag && !*example9Flag && !*example9BenchmarkFlag && !*example10Flag && !*example10BenchmarkFlag {
 		fmt.Println("Please specify an example to run:")
 		fmt.Println("  --example1              Run example 1 (Interface Design)")
 		fmt.Println("  --example2              Run example 2 (Worker Pool)")
 		fmt.Println("  --example2-pipeline     Run example 2 (Pipeline Pattern)")
 		fmt.Println("  --example3              Run example 3 (Error Handling)")
+		fmt.Println("  --example3-concurrency  Run example 3 (Optimistic Concurrency)")
+		fmt.Println("  --example3-resilience   Run example 3 (Retry and Circuit Breaker)")
+		fmt.Println("  --example3-panic        Run example 3 (Panic Recovery)")
 		fmt.Println("  --example4              Run example 4 (Table-Driven Tests)")
 		fmt.Println("  --example4-benchmark    Run example 4 (Benchmarks)")
+		fmt.Println("      --example4-benchmark-save=FILE     Save results as a JSON baseline")
+		fmt.Println("      --example4-benchmark-compare=FILE  Compare results against a baseline")
 		fmt.Println("  --example4-integration  Run example 4 (Integration Tests)")
 		fmt.Println("  --example5              Run example 5 (Package Design)")
 		fmt.Println("  --example5-integration  Run example 5 (Integration Tests)")
+		fmt.Println("  --example5-sqlite       Run example 5 (SQLite Storage)")
 		fmt.Println("  --example6              Run example 6 (Performance Optimization)")
 		fmt.Println("  --example6-benchmark    Run example 6 (Benchmarks)")
 		fmt.Println("  --example7              Run example 7 (Dependency Management)")
@@ -62,140 +88,182 @@ func main() {
 		fmt.Println("  --example9-benchmark    Run example 9 (Benchmarks)")
 		fmt.Println("  --example10             Run example 10 (Idiomatic Go)")
 		fmt.Println("  --example10-benchmark   Run example 10 (Benchmarks)")
+		fmt.Println("  --deterministic         Use a fixed clock and sequential IDs (not for benchmarks)")
+		fmt.Println("\nOr run a command:")
+		fmt.Println("  coverage [flags] [packages]  Report test coverage and check thresholds")
+		fmt.Println("  tasks COMMAND [flags]        Manage tasks in a local data file (add, list, search, show, update, done, rm)")
+		fmt.Println("  serve [flags]                Serve the tasks as a REST API under /v1")
 		os.Exit(1)
 	}
 
+	var demoOpts []demo.Option
+	if *deterministicFlag {
+		demoOpts = append(demoOpts, demo.Deterministic())
+	}
+
 	// Run the selected example
 	if *example1Flag {
-		if err := example1.Run(); err != nil {
+		if err := example1.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running example 1: %v\n", err)
 		}
 	}
 
 	if *example2Flag {
-		if err := example2.Run(); err != nil {
+		if err := example2.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running example 2: %v\n", err)
 		}
 	}
 
 	if *example2PipelineFlag {
-		if err := example2.RunPipeline(); err != nil {
+		if err := example2.RunPipeline(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running example 2 pipeline: %v\n", err)
 		}
 	}
 
 	if *example3Flag {
 		fmt.Println("Running error handling example:")
-		if err := example3.Run(); err != nil {
+		if err := example3.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running example 3: %v\n", err)
 		}
 		fmt.Println("\nRunning error wrapping example:")
-		if err := example3.RunErrorWrapping(); err != nil {
+		if err := example3.RunErrorWrapping(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running error wrapping example: %v\n", err)
 		}
 	}
 
+	if *example3ConcurrencyFlag {
+		fmt.Println("Running optimistic concurrency example:")
+		if err := example3.RunConcurrency(os.Stdout, demoOpts...); err != nil {
+			fmt.Printf("Error running optimistic concurrency example: %v\n", err)
+		}
+	}
+
+	if *example3ResilienceFlag {
+		fmt.Println("Running retry and circuit breaker example:")
+		if err := example3.RunResilience(os.Stdout, demoOpts...); err != nil {
+			fmt.Printf("Error running retry and circuit breaker example: %v\n", err)
+		}
+	}
+
+	if *example3PanicFlag {
+		fmt.Println("Running panic recovery example:")
+		if err := example3.RunPanicRecovery(os.Stdout, demoOpts...); err != nil {
+			fmt.Printf("Error running panic recovery example: %v\n", err)
+		}
+	}
+
 	if *example4Flag {
 		fmt.Println("Running table-driven tests example:")
-		if err := example4.Run(); err != nil {
+		if err := example4.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running table-driven tests: %v\n", err)
 		}
 	}
 
 	if *example4BenchmarkFlag {
 		fmt.Println("Running benchmarks example:")
-		if err := example4.RunBenchmark(); err != nil {
+		opts := example4.BenchmarkOptions{
+			SavePath:    *example4BenchmarkSave,
+			ComparePath: *example4BenchmarkCompare,
+		}
+		if err := example4.RunBenchmarkWithOptions(os.Stdout, opts); err != nil {
 			fmt.Printf("Error running benchmarks: %v\n", err)
 		}
 	}
 
 	if *example4IntegrationFlag {
 		fmt.Println("Running integration tests example:")
-		if err := example4.RunIntegration(); err != nil {
+		if err := example4.RunIntegration(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running integration tests: %v\n", err)
 		}
 	}
 
 	if *example5Flag {
 		fmt.Println("Running package design example:")
-		if err := example5.Run(); err != nil {
+		if err := example5.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running package design example: %v\n", err)
 		}
 	}
 
 	if *example5IntegrationFlag {
 		fmt.Println("Running package design integration tests:")
-		if err := example5.RunIntegration(); err != nil {
+		if err := example5.RunIntegration(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running package design integration tests: %v\n", err)
 		}
 	}
 
+	if *example5SQLiteFlag {
+		fmt.Println("Running package design SQLite storage example:")
+		if err := example5.RunPersistence(os.Stdout, demoOpts...); err != nil {
+			fmt.Printf("Error running package design SQLite storage example: %v\n", err)
+		}
+	}
+
 	if *example6Flag {
 		fmt.Println("Running performance optimization example:")
-		if err := example6.Run(); err != nil {
+		if err := example6.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running performance optimization example: %v\n", err)
 		}
 	}
 
 	if *example6BenchmarkFlag {
 		fmt.Println("Running performance benchmarks:")
-		if err := example6.RunBenchmark(); err != nil {
+		if err := example6.RunBenchmark(os.Stdout); err != nil {
 			fmt.Printf("Error running performance benchmarks: %v\n", err)
 		}
 	}
 
 	if *example7Flag {
 		fmt.Println("Running dependency management example:")
-		if err := example7.Run(); err != nil {
+		if err := example7.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running dependency management example: %v\n", err)
 		}
 	}
 
 	if *example7BenchmarkFlag {
 		fmt.Println("Running dependency management benchmarks:")
-		if err := example7.RunBenchmark(); err != nil {
+		if err := example7.RunBenchmark(os.Stdout); err != nil {
 			fmt.Printf("Error running dependency management benchmarks: %v\n", err)
 		}
 	}
 
 	if *example8Flag {
 		fmt.Println("Running standard library example:")
-		if err := example8.Run(); err != nil {
+		if err := example8.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running standard library example: %v\n", err)
 		}
 	}
 
 	if *example8BenchmarkFlag {
 		fmt.Println("Running standard library benchmarks:")
-		if err := example8.RunBenchmark(); err != nil {
+		if err := example8.RunBenchmark(os.Stdout); err != nil {
 			fmt.Printf("Error running standard library benchmarks: %v\n", err)
 		}
 	}
 
 	if *example9Flag {
 		fmt.Println("Running tooling ecosystem example:")
-		if err := example9.Run(); err != nil {
+		if err := example9.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running tooling ecosystem example: %v\n", err)
 		}
 	}
 
 	if *example9BenchmarkFlag {
 		fmt.Println("Running tooling ecosystem benchmarks:")
-		if err := example9.RunBenchmark(); err != nil {
+		if err := example9.RunBenchmark(os.Stdout); err != nil {
 			fmt.Printf("Error running tooling ecosystem benchmarks: %v\n", err)
 		}
 	}
 
 	if *example10Flag {
 		fmt.Println("Running idiomatic Go example:")
-		if err := example10.Run(); err != nil {
+		if err := example10.Run(os.Stdout, demoOpts...); err != nil {
 			fmt.Printf("Error running idiomatic Go example: %v\n", err)
 		}
 	}
 
 	if *example10BenchmarkFlag {
 		fmt.Println("Running idiomatic Go benchmarks:")
-		if err := example10.RunBenchmark(); err != nil {
+		if err := example10.RunBenchmark(os.Stdout); err != nil {
 			fmt.Printf("Error running idiomatic Go benchmarks: %v\n", err)
 		}
 	}
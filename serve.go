@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"practice/examples/example5"
+	"practice/examples/example5/taskapi"
+	"practice/examples/example5/taskcli"
+)
+
+// runServe implements `practice serve [flags]`, serving the tasks data
+// file over the REST API until interrupted, and returns the process exit
+// code
+func runServe(args []string) int {
+	dataFile, _ := taskcli.DefaultDataFile()
+
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:8080", "Address to listen on")
+	fs.StringVar(&dataFile, "file", dataFile, "SQLite file holding the tasks")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 || dataFile == "" {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := serveTasks(ctx, *addr, dataFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serveTasks serves the API until ctx is done, then waits for requests in
+// flight to finish
+func serveTasks(ctx context.Context, addr, dataFile string) error {
+	if err := os.MkdirAll(filepath.Dir(dataFile), 0o755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	db, err := example5.OpenSQLite(dataFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	service, err := example5.NewSQLTaskService(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           taskapi.NewHandler(service),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	fmt.Printf("Serving %s at http://%s/%s/tasks (API description at /%s/openapi.json)\n", dataFile, addr, taskapi.Version, taskapi.Version)
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
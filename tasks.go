@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+
+	"practice/examples/example5/taskcli"
+)
+
+// runTasks implements `practice tasks COMMAND [flags] [args]` and returns
+// the process exit code
+func runTasks(args []string) int {
+	dataFile, err := taskcli.DefaultDataFile()
+	if err != nil {
+		// A -file flag can still say where the data lives
+		dataFile = ""
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = taskcli.Run(ctx, taskcli.Config{
+		Stdout:   os.Stdout,
+		Stderr:   os.Stderr,
+		DataFile: dataFile,
+		Actor:    currentUser(),
+	}, args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, taskcli.ErrUsage):
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+}
+
+// currentUser names the person running the command, for the tasks' audit
+// fields
+func currentUser() string {
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}